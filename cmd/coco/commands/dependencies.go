@@ -60,6 +60,7 @@ func newDependencies() *cobra.Command {
 				viper.GetString(componentCfg),
 				sourceBranch,
 				targetBranch,
+				ignoreFiles(),
 				graphDepth,
 				overWriteGitDepth, // viper.GetInt(gitDepth),
 				logLvl,
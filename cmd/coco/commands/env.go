@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/env"
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/generate"
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/values"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	envFrom      string
+	envDir       string
+	envLabels    map[string]string
+	envOverrides string
+	envGenerate  bool
+	envDryRun    bool
+)
+
+func newEnv() *cobra.Command {
+	var c = &cobra.Command{
+		Use:   "env",
+		Short: "env allows to manage the environments of the gitops repository",
+		Long: `
+The env command creates, renames and removes environments together with their
+generated files.
+`,
+	}
+
+	c.PersistentFlags().StringSliceVarP(
+		&valuesFolders, "values", "v", []string{"values"},
+		"folder that contains all value files of the environments",
+	)
+	c.AddCommand(newEnvCreate())
+	c.AddCommand(newEnvRename())
+	c.AddCommand(newEnvRemove())
+	return c
+}
+
+func newEnvCreate() *cobra.Command {
+	var c = &cobra.Command{
+		Use:   "create NAME",
+		Short: "create scaffolds a new environment",
+		Long: `
+The create command writes the folder and the config file of a new environment.
+With --from the environment is cloned from an existing one: value files inside
+the folder of the existing environment are copied, all others are referenced
+with paths relative to the new folder.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			name := args[0]
+			basepath := viper.GetString(gitPathKey)
+			configFileName := viper.GetString(componentCfg)
+			folders := cleanValuePaths(valuesFolders, basepath)
+			if len(folders) == 0 {
+				failOnError(fmt.Errorf("no values folder specified"), "create")
+			}
+
+			_, err := values.ReadEnvironment(basepath, configFileName, folders, ignoreFiles(), name)
+			if err == nil {
+				failOnError(fmt.Errorf("environment %q already exists", name), "create")
+			}
+			if !errors.Is(err, values.ErrEnvironmentNotFound) {
+				failOnError(err, "create")
+			}
+			opts := env.CreateOptions{
+				Name:           name,
+				Dir:            filepath.Join(folders[0], name),
+				ConfigFileName: configFileName,
+				Labels:         envLabels,
+				Overrides:      envOverrides,
+			}
+			if envDir != "" {
+				opts.Dir = envDir
+				if !filepath.IsAbs(envDir) {
+					opts.Dir = filepath.Join(basepath, envDir)
+				}
+			}
+			if envFrom != "" {
+				from, err := values.ReadEnvironment(basepath, configFileName, folders, ignoreFiles(), envFrom)
+				failOnError(err, "create")
+				opts.From = &from
+			}
+			created, err := env.Create(files.OS(), opts)
+			for _, f := range created {
+				log.Sugar.Infof("created %q", f)
+			}
+			failOnError(err, "create")
+
+			if envGenerate {
+				failOnError(generateEnvironment(basepath, configFileName, folders, opts.Dir), "create")
+			}
+		},
+	}
+
+	c.Flags().StringVar(&envFrom, "from", "", "name of an existing environment that is cloned")
+	c.Flags().StringVar(
+		&envDir, "dir", "",
+		"folder of the new environment relative to the git path (default: <first values folder>/NAME)",
+	)
+	c.Flags().StringToStringVar(
+		&envLabels, "labels", map[string]string{},
+		"labels of the new environment, e.g. --labels region=eu,tier=1",
+	)
+	c.Flags().StringVar(
+		&envOverrides, "overrides", "",
+		"name of an empty values file that is created and appended to the values, e.g. overrides.yaml",
+	)
+	c.Flags().BoolVar(
+		&envGenerate, "generate", false,
+		"run the file generation for the new environment",
+	)
+	return c
+}
+
+func newEnvRename() *cobra.Command {
+	var c = &cobra.Command{
+		Use:   "rename OLD NEW",
+		Short: "rename renames an environment and moves its generated files",
+		Long: `
+The rename command sets the new name in the config file of the environment and
+moves all files that have been generated for it to the paths of the new name.
+Files without the header of a compatible coco version (or raw copies that differ
+from their template) are not moved. The content of the generated files
+(including lines marked as HumanInput) is kept; run "coco generate" (or use
+--generate) afterwards to update it.
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			r := envRepository()
+			actions, err := env.Rename(files.OS(), r, args[0], args[1])
+			failOnError(err, "rename")
+			applyEnvActions(r.Basepath, actions, "rename")
+			if envGenerate && !envDryRun {
+				renamed, err := values.ReadEnvironment(r.Basepath, r.ConfigFileName, r.ValueFolders, r.IgnoreFiles, args[1])
+				failOnError(err, "rename")
+				failOnError(
+					generateEnvironment(r.Basepath, r.ConfigFileName, r.ValueFolders, filepath.Dir(renamed.Path)),
+					"rename",
+				)
+			}
+		},
+	}
+
+	c.Flags().BoolVar(&envDryRun, "dry-run", false, "only list the planned changes")
+	c.Flags().BoolVar(
+		&envGenerate, "generate", false,
+		"run the file generation for the renamed environment",
+	)
+	return c
+}
+
+func newEnvRemove() *cobra.Command {
+	var c = &cobra.Command{
+		Use:   "remove NAME",
+		Short: "remove deletes an environment and its generated files",
+		Long: `
+The remove command deletes all files that have been generated for the
+environment, its config file and the value files in its folder that no other
+environment uses. Files without the header of a compatible coco version (or raw
+copies that differ from their template) are kept, also in generated folders.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			r := envRepository()
+			actions, err := env.Remove(files.OS(), r, args[0])
+			failOnError(err, "remove")
+			applyEnvActions(r.Basepath, actions, "remove")
+		},
+	}
+
+	c.Flags().BoolVar(&envDryRun, "dry-run", false, "only list the planned changes")
+	return c
+}
+
+func envRepository() env.Repository {
+	basepath := viper.GetString(gitPathKey)
+	return env.Repository{
+		Basepath:           basepath,
+		ConfigFileName:     viper.GetString(componentCfg),
+		TemplateIdentifier: tmplIdentifier,
+		ValueFolders:       cleanValuePaths(valuesFolders, basepath),
+		IgnoreFiles:        ignoreFiles(),
+		Version:            version.ReadAll(),
+	}
+}
+
+// applyEnvActions lists the actions on a dry run and executes them otherwise.
+func applyEnvActions(basepath string, actions []env.Action, command string) {
+	if envDryRun {
+		failOnError(env.PrintActions(os.Stdout, actions, basepath), command)
+		return
+	}
+	var b strings.Builder
+	failOnError(env.PrintActions(&b, actions, basepath), command)
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		log.Sugar.Info(line)
+	}
+	failOnError(env.Apply(files.OS(), actions), command)
+}
+
+// generateEnvironment runs the file generation for the environment in dir.
+func generateEnvironment(basepath, configFileName string, folders []string, dir string) error {
+	return generate.Generate(
+		basepath,
+		tmplIdentifier,
+		persistenceFlag,
+		configFileName,
+		version.ReadAll(),
+		folders,
+		[]string{dir + string(filepath.Separator)},
+		[]string{},
+		[]string{},
+		ignoreFiles(),
+		logLvl,
+		false,
+		false,
+		generate.HookConfig{Hooks: []inputfile.Hook{}, Workers: 1},
+		nil,
+		files.OS(),
+		generate.Destination{},
+	)
+}
@@ -4,8 +4,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 
 	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/generate"
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/exec"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/k8sschema"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
 	"github.com/spf13/cobra"
@@ -19,6 +24,16 @@ var (
 	tmplIdentifier    string
 	persistenceFlag   string
 	takeControl       bool
+	failFast          bool
+	fileHooks         []string
+	dirHooks          []string
+	hookWorkers       int
+	runHooks          bool
+	validateK8s       bool
+	k8sSchemas        []string
+	outputDir         string
+	outputTar         string
+	atomic            bool
 )
 
 func newGenerate() *cobra.Command {
@@ -30,6 +45,10 @@ func newGenerate() *cobra.Command {
 		Long: `
 The generate command governs all aspects of non-sensitive file-generation
 in the gitops repository.
+
+Folder arguments, --env-filter and --exclude match substrings of the path by
+default. With the prefix "glob:" or "regex:" they match the path relative to
+the git path as a whole, e.g. --exclude "glob:**/test" or -e "regex:values/prod-.*".
 `,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
@@ -41,6 +60,10 @@ in the gitops repository.
 		Run: func(cmd *cobra.Command, args []string) {
 			basepath := viper.GetString(gitPathKey)
 			configFileName := viper.GetString(componentCfg)
+			validator, err := k8sValidator(validateK8s, k8sSchemas, basepath)
+			failOnError(err, "generate")
+			hooks, err := hooksFromFlags(fileHooks, dirHooks)
+			failOnError(err, "generate")
 			failOnError(
 				generate.Generate(
 					basepath,
@@ -52,8 +75,14 @@ in the gitops repository.
 					environmentFilter,
 					args,
 					excludeFolders,
+					ignoreFiles(),
 					logLvl,
 					takeControl,
+					failFast,
+					generate.HookConfig{Hooks: hooks, Workers: hookWorkers, TemplateHooks: runHooks},
+					validator,
+					files.OS(),
+					generate.Destination{Dir: outputDir, Tar: outputTar, Atomic: atomic},
 				),
 				"generate",
 			)
@@ -61,10 +90,11 @@ in the gitops repository.
 	}
 
 	c.AddCommand(newGenerateCustom())
+	c.AddCommand(newGenerateLint())
 
 	c.PersistentFlags().StringSliceVarP(
 		&environmentFilter, "env-filter", "e", []string{},
-		"restrict the command to one or more environments",
+		"restrict the command to one or more environments (substring, glob: or regex: filter)",
 	)
 
 	c.Flags().StringSliceVarP(
@@ -73,7 +103,7 @@ in the gitops repository.
 	)
 	c.Flags().StringSliceVarP(
 		&excludeFolders, "exclude", "x", []string{},
-		"folders that will be excluded from file generation",
+		"folders that will be excluded from file generation (substring, glob: or regex: filter)",
 	)
 	c.Flags().StringVarP(
 		&tmplIdentifier, "templates", "t", ".tmpl",
@@ -98,10 +128,97 @@ of the version in the generated files`,
 		&takeControl, "force", false,
 		`if this flag is set, coco forcefully regenerats all files regardless of
 the version in the generated files`,
+	)
+	c.Flags().BoolVar(
+		&failFast, "fail-fast", false,
+		`stop rendering the templates of a folder at the first error instead of
+reporting all errors`,
+	)
+	c.Flags().StringArrayVar(
+		&fileHooks, "hook", []string{},
+		`command that runs on each generated file (repeatable), e.g. "yamllint -s".
+The file path is appended unless the command contains "{{ .Path }}". Arguments
+are separated by whitespace and can be quoted like in a shell.`,
+	)
+	c.Flags().StringArrayVar(
+		&dirHooks, "dir-hook", []string{},
+		`command that runs once on each generated directory (repeatable), e.g.
+"kustomize build". The path is appended unless the command contains "{{ .Path }}".`,
+	)
+	c.Flags().IntVar(
+		&hookWorkers, "hook-workers", runtime.NumCPU(),
+		"maximal number of hooks that run concurrently",
+	)
+	c.Flags().BoolVar(
+		&runHooks, "run-hooks", false,
+		`run the hooks of the template configurations. They are skipped by default
+since every configuration file of the repository can declare them.`,
+	)
+	c.Flags().BoolVar(
+		&validateK8s, "validate-k8s", false,
+		`validate all generated Kubernetes objects offline against the schemas of
+their kind and apiVersion`,
+	)
+	c.Flags().StringSliceVar(
+		&k8sSchemas, "k8s-schemas", []string{},
+		`folders with additional JSON schemas or CustomResourceDefinitions for
+"--validate-k8s" (relative to the git path)`,
+	)
+	c.Flags().StringVar(
+		&outputDir, "output-dir", "",
+		`write the generated files into this folder (keeping their path relative to
+the git path) instead of in place. The repository is not modified.`,
+	)
+	c.Flags().StringVar(
+		&outputTar, "output-tar", "",
+		`write the generated files into this tar archive (gzip compressed for ".tgz"
+and ".gz") instead of in place. The repository is not modified.`,
+	)
+	c.Flags().BoolVar(
+		&atomic, "atomic", false,
+		`write the generated files only if all templates have been rendered without
+error (all or nothing). An interrupt leaves the repository unchanged.`,
 	)
 	return c
 }
 
+// k8sValidator returns the validator for the generated Kubernetes objects or nil
+// if the validation is disabled.
+func k8sValidator(enabled bool, schemaDirs []string, basepath string) (*k8sschema.Validator, error) {
+	if !enabled {
+		return nil, nil
+	}
+	dirs := make([]string, 0, len(schemaDirs))
+	for _, d := range schemaDirs {
+		if !filepath.IsAbs(d) {
+			d = filepath.Join(basepath, d)
+		}
+		dirs = append(dirs, d)
+	}
+	return k8sschema.New(dirs...)
+}
+
+// hooksFromFlags turns the hook flags into repo-wide hooks. The arguments of the
+// commands are split like in a shell (see exec.SplitArgs).
+func hooksFromFlags(fileHooks, dirHooks []string) ([]inputfile.Hook, error) {
+	res := make([]inputfile.Hook, 0, len(fileHooks)+len(dirHooks))
+	for _, h := range fileHooks {
+		args, err := exec.SplitArgs(h)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hook: %w", err)
+		}
+		res = append(res, inputfile.Hook{Command: args, Scope: inputfile.HookScopeFile})
+	}
+	for _, h := range dirHooks {
+		args, err := exec.SplitArgs(h)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hook: %w", err)
+		}
+		res = append(res, inputfile.Hook{Command: args, Scope: inputfile.HookScopeDirectory})
+	}
+	return res, nil
+}
+
 func cleanValuePaths(valuesFolders []string, basepath string) []string {
 	res := make([]string, 0, len(valuesFolders))
 	for _, f := range valuesFolders {
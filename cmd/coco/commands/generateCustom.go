@@ -4,12 +4,15 @@ import (
 	"fmt"
 
 	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/generate"
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 	"github.com/spf13/cobra"
 )
 
 var (
 	customTarget string
 	customValues []string
+	customDelims []string
 )
 
 func newGenerateCustom() *cobra.Command {
@@ -28,8 +31,10 @@ func newGenerateCustom() *cobra.Command {
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
+			delims, err := parseDelims(customDelims)
+			failOnError(err, "custom")
 			failOnError(
-				generate.ParseTemplate(args[0], customValues, customTarget),
+				generate.ParseTemplate(files.OS(), args[0], customValues, customTarget, delims),
 				"custom",
 			)
 		},
@@ -43,7 +48,25 @@ func newGenerateCustom() *cobra.Command {
 		&customValues, "value", []string{},
 		"value files for rendering a custom template",
 	)
+	c.Flags().StringSliceVar(
+		&customDelims, "delims", []string{},
+		`left and right delimiter of template actions instead of "{{" and "}}",
+e.g. --delims "[[,]]"`,
+	)
 	c.MarkFlagsRequiredTogether("value", "target")
 
 	return c
 }
+
+// parseDelims turns the delimiters flag into template delimiters (nil for the
+// default delimiters).
+func parseDelims(delims []string) (*inputfile.Delimiters, error) {
+	if len(delims) == 0 {
+		return nil, nil
+	}
+	if len(delims) != 2 {
+		return nil, fmt.Errorf("exactly two delimiters are required, got %d", len(delims))
+	}
+	d := &inputfile.Delimiters{Left: delims[0], Right: delims[1]}
+	return d, d.Validate()
+}
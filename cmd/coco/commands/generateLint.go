@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/generate"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newGenerateLint() *cobra.Command {
+	var c = &cobra.Command{
+		Use:   "lint",
+		Short: "lint checks all templates and value files without generating files",
+		Long: `
+The lint command renders all templates for all environments without writing any
+file and reports template syntax errors, keys that are referenced by templates
+but missing in some environments, value keys that no template references and
+values that are overridden without effect.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			basepath := viper.GetString(gitPathKey)
+			failOnError(
+				generate.Lint(
+					basepath,
+					tmplIdentifier,
+					viper.GetString(componentCfg),
+					cleanValuePaths(valuesFolders, basepath),
+					environmentFilter,
+					args,
+					excludeFolders,
+					ignoreFiles(),
+					files.OS(),
+				),
+				"lint",
+			)
+		},
+	}
+
+	c.Flags().StringSliceVarP(
+		&valuesFolders, "values", "v", []string{"values"},
+		"folder that contains all value files used for rendering templates",
+	)
+	c.Flags().StringSliceVarP(
+		&excludeFolders, "exclude", "x", []string{},
+		"folders that will be excluded from linting (substring, glob: or regex: filter)",
+	)
+	c.Flags().StringVarP(
+		&tmplIdentifier, "templates", "t", ".tmpl",
+		"pattern in folder or file names that identifies templates",
+	)
+	return c
+}
@@ -34,6 +34,7 @@ func newGraph() *cobra.Command {
 			deps, _, err := dependencies.Graph(
 				viper.GetString(gitPathKey),
 				viper.GetString(componentCfg),
+				ignoreFiles(),
 			)
 			failOnError(err, "graph")
 			deps.Print(os.Stdout, format)
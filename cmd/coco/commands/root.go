@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
 	"github.com/spf13/cobra"
@@ -18,6 +19,7 @@ var (
 	remote, defaultBranch             string
 	mD                                int
 	logLvl                            log.Level
+	useGitignore                      bool
 )
 
 const (
@@ -26,6 +28,7 @@ const (
 	gitRemoteKey = "git.remote"
 	gitDepthKey  = "git.depth"
 	componentCfg = "component.cfg"
+	gitignoreKey = "ignore.gitignore"
 	// cannot restrict checkout depth due to upstream bug
 	// (see https://github.com/go-git/go-git/issues/328 for issue tracking)
 	overWriteGitDepth = 0
@@ -51,9 +54,11 @@ func newRoot() *cobra.Command {
 
 	c.AddCommand(newVersion())
 	c.AddCommand(newDependencies())
+	c.AddCommand(newEnv())
 	c.AddCommand(newGenerate())
 	c.AddCommand(newInspect())
 	c.AddCommand(newReconcile())
+	c.AddCommand(newValues())
 
 	c.PersistentFlags().StringVar(
 		&cfgFile, "config", "", "config file (default $HOME/.coco)",
@@ -100,6 +105,13 @@ func newRoot() *cobra.Command {
 	)
 	bindFlag(c.PersistentFlags(), gitDepthKey, "git-depth", "GIT_DEPTH")
 
+	c.PersistentFlags().BoolVar(
+		&useGitignore, "gitignore", false,
+		`honour .gitignore files in addition to .cocoignore files when searching the
+repository (folders of version control systems are always skipped)`,
+	)
+	bindFlag(c.PersistentFlags(), gitignoreKey, "gitignore", "COCO_GITIGNORE")
+
 	failOnError(viper.BindEnv("git-token", "GITHUB_TOKEN"), "root")
 
 	return c
@@ -170,6 +182,15 @@ func initConfig() {
 	}
 }
 
+// ignoreFiles returns the names of the ignore files that are honoured when
+// searching the repository (.gitignore files only with --gitignore).
+func ignoreFiles() []string {
+	if viper.GetBool(gitignoreKey) {
+		return []string{files.CocoIgnore, ".gitignore"}
+	}
+	return files.DefaultIgnoreFiles
+}
+
 func consistentGitSetup(url, remote string) bool {
 	if remote == "" {
 		return true
@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/values"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	valuesEnv    string
+	valuesFormat string
+	diffEnvs     []string
+	diffFrom     string
+	diffTo       string
+)
+
+func newValues() *cobra.Command {
+	var c = &cobra.Command{
+		Use:   "values",
+		Short: "values allows to inspect the merged values of environments",
+		Long: `
+The values command shows how the values of environments are merged from their
+chains of value files.
+`,
+	}
+
+	c.PersistentFlags().StringSliceVarP(
+		&valuesFolders, "values", "v", []string{"values"},
+		"folder that contains all value files of the environments",
+	)
+	c.AddCommand(newValuesExplain())
+	c.AddCommand(newValuesShow())
+	c.AddCommand(newValuesDiff())
+	return c
+}
+
+func newValuesExplain() *cobra.Command {
+	var c = &cobra.Command{
+		Use:   "explain KEY",
+		Short: "explain shows which value file sets the value of a key",
+		Long: `
+The explain command prints the merged value of the dot separated key (e.g.
+"ingress.className") for an environment, the value file (and position) that sets
+it and the values of other files that it overrides.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			basepath := viper.GetString(gitPathKey)
+			env, err := values.ReadEnvironment(
+				basepath,
+				viper.GetString(componentCfg),
+				cleanValuePaths(valuesFolders, basepath),
+				ignoreFiles(),
+				valuesEnv,
+			)
+			failOnError(err, "explain")
+			e, err := values.Explain(files.OS(), env, args[0])
+			failOnError(err, "explain")
+			failOnError(e.Print(os.Stdout, basepath), "explain")
+		},
+	}
+
+	c.Flags().StringVarP(&valuesEnv, "env", "e", "", "name of the environment")
+	failOnError(c.MarkFlagRequired("env"), "explain")
+	return c
+}
+
+func newValuesShow() *cobra.Command {
+	var c = &cobra.Command{
+		Use:   "show",
+		Short: "show prints the merged values of an environment",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			basepath := viper.GetString(gitPathKey)
+			env, err := values.ReadEnvironment(
+				basepath,
+				viper.GetString(componentCfg),
+				cleanValuePaths(valuesFolders, basepath),
+				ignoreFiles(),
+				valuesEnv,
+			)
+			failOnError(err, "show")
+			failOnError(values.Show(os.Stdout, env, valuesFormat), "show")
+		},
+	}
+
+	c.Flags().StringVarP(&valuesEnv, "env", "e", "", "name of the environment")
+	failOnError(c.MarkFlagRequired("env"), "show")
+	c.Flags().StringVarP(
+		&valuesFormat, "format", "f", values.FormatYaml,
+		fmt.Sprintf("output format (%s or %s)", values.FormatYaml, values.FormatJSON),
+	)
+	return c
+}
+
+func newValuesDiff() *cobra.Command {
+	var c = &cobra.Command{
+		Use:   "diff",
+		Short: "diff prints the differences between the merged values of two environments",
+		Long: `
+The diff command compares the merged values of two environments and prints one
+line per added (+), removed (-) or changed (~) path, e.g.
+"~ ingress.className: traefik -> nginx".
+
+With --from and/or --to the values of a single environment are compared between
+two git revisions (e.g. "--from origin/main --to HEAD"). The config and value
+files are read from the git trees without checking them out; an omitted revision
+refers to the working tree.
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			basepath := viper.GetString(gitPathKey)
+			if diffFrom != "" || diffTo != "" {
+				if len(diffEnvs) != 1 {
+					failOnError(fmt.Errorf(
+						"exactly one environment is required to compare git revisions, got %d", len(diffEnvs),
+					), "diff")
+				}
+				from := environmentValuesAt(basepath, diffFrom, diffEnvs[0], ignoreFiles())
+				to := environmentValuesAt(basepath, diffTo, diffEnvs[0], ignoreFiles())
+				failOnError(values.PrintDiff(os.Stdout, values.Diff(from, to)), "diff")
+				return
+			}
+			if len(diffEnvs) != 2 {
+				failOnError(fmt.Errorf("exactly two environments are required, got %d", len(diffEnvs)), "diff")
+			}
+			envs := make([]interface{}, 0, len(diffEnvs))
+			for _, name := range diffEnvs {
+				envs = append(envs, environmentValuesAt(basepath, "", name, ignoreFiles()))
+			}
+			failOnError(values.PrintDiff(os.Stdout, values.Diff(envs[0], envs[1])), "diff")
+		},
+	}
+
+	c.Flags().StringSliceVarP(
+		&diffEnvs, "env", "e", []string{},
+		"names of the two environments to compare (the first one is the base)",
+	)
+	c.Flags().StringVar(
+		&diffFrom, "from", "", "git revision of the base values (default: working tree)",
+	)
+	c.Flags().StringVar(
+		&diffTo, "to", "", "git revision of the compared values (default: working tree)",
+	)
+	return c
+}
+
+// environmentValuesAt returns the merged values of the environment at the git
+// revision or in the working tree if revision is empty. Both read the ignoreFiles
+// of their own tree. An environment that does not exist at a revision has no
+// values.
+func environmentValuesAt(basepath, revision, name string, ignoreFiles []string) interface{} {
+	if revision == "" {
+		env, err := values.ReadEnvironment(
+			basepath,
+			viper.GetString(componentCfg),
+			cleanValuePaths(valuesFolders, basepath),
+			ignoreFiles,
+			name,
+		)
+		failOnError(err, "diff")
+		return env.Values
+	}
+	env, err := values.ReadEnvironmentAt(
+		basepath, revision, viper.GetString(componentCfg), valuesFolders, ignoreFiles, name,
+	)
+	if errors.Is(err, values.ErrEnvironmentNotFound) {
+		log.Sugar.Warnf("environment %q does not exist at %q", name, revision)
+		return nil
+	}
+	failOnError(err, "diff")
+	return env.Values
+}
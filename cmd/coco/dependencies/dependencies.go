@@ -19,14 +19,14 @@ var (
 	) (gitRepo, error) = newRepo
 	mergeBase func(*git.Tree, *git.Tree) (*git.Tree, error) = mb
 	diffPaths func(*git.Tree, *git.Tree) ([]string, error)  = diff
-	graphh    func(path, depFileName string) (
+	graphh    func(path, depFileName string, ignoreFiles []string) (
 		g.ComponentDependencies, map[string]string, error,
 	) = Graph
 )
 
 func ChangeAffectedComponents(
 	giturl, remote, gitToken, path, depFileName, sourceBranch, targetBranch string,
-	graphDepth, gitDepth int, logLvl log.Level,
+	ignoreFiles []string, graphDepth, gitDepth int, logLvl log.Level,
 ) (g.ComponentDependencies, error) {
 	c := log.Context{
 		"git.URL":         giturl,
@@ -48,7 +48,7 @@ func ChangeAffectedComponents(
 		return g.ComponentDependencies{}, err
 	}
 
-	allDependencies, components, err := graphh(path, depFileName)
+	allDependencies, components, err := graphh(path, depFileName, ignoreFiles)
 	if logErr(c, err) {
 		return g.ComponentDependencies{}, err
 	}
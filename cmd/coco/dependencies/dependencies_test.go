@@ -267,7 +267,7 @@ func TestChangeAffectedComponents(t *testing.T) {
 		graphh = s.input.graph
 
 		got, err := ChangeAffectedComponents(
-			notUsed, notUsed, notUsed, notUsed, notUsed, notUsed, notUsed, s.input.graphDepth, 0,
+			notUsed, notUsed, notUsed, notUsed, notUsed, notUsed, notUsed, nil, s.input.graphDepth, 0,
 			log.Debug(),
 		)
 		testfuncs.CheckErrs(t, s.want.err, err)
@@ -292,6 +292,6 @@ func (m *inputDeps) mergeBase(*git.Tree, *git.Tree) (*git.Tree, error) {
 func (m *inputDeps) diffPaths(*git.Tree, *git.Tree) ([]string, error) {
 	return m.diffFiles, m.errors.diffPaths
 }
-func (m *inputDeps) graph(string, string) (graph.ComponentDependencies, map[string]string, error) {
+func (m *inputDeps) graph(string, string, []string) (graph.ComponentDependencies, map[string]string, error) {
 	return m.componentDependencies, m.componentPaths, m.errors.graph
 }
@@ -12,14 +12,16 @@ import (
 )
 
 var (
-	unmarshal    func([]byte, interface{}) error                                                   = yaml.Unmarshal
-	dependencies func(string, string, []string, []string, []string) (map[string]files.File, error) = inputfile.FindAll
+	unmarshal    func([]byte, interface{}) error = yaml.Unmarshal
+	dependencies func(
+		files.FS, string, string, []string, []string, []string, []string,
+	) (map[string]files.File, error) = inputfile.FindAll
 )
 
-func Graph(path, depFileName string) (
+func Graph(path, depFileName string, ignoreFiles []string) (
 	graph g.ComponentDependencies, components map[string]string, err error) {
 	c := log.Context{"path": path, "dependency-file": depFileName}
-	allDeps, components, err := constructGraph(path, depFileName)
+	allDeps, components, err := constructGraph(path, depFileName, ignoreFiles)
 	if logErr(c, err) {
 		return g.ComponentDependencies{}, nil, err
 	}
@@ -34,10 +36,10 @@ func logErr(c log.Context, err error) bool {
 	return false
 }
 
-func constructGraph(path, depFileName string) (
+func constructGraph(path, depFileName string, ignoreFiles []string) (
 	downToUp g.DownToUp, componentPaths map[string]string, err error,
 ) {
-	fs, err := dependencies(path, depFileName, []string{}, []string{}, []string{})
+	fs, err := dependencies(files.OS(), path, depFileName, []string{}, []string{}, []string{}, ignoreFiles)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -190,7 +190,7 @@ func (g *graphTest) Test(t *testing.T) {
 	}
 	defer testDir.Cleanup(t)
 
-	got, _, err := Graph(testDir.Path(), g.input.depFileName)
+	got, _, err := Graph(testDir.Path(), g.input.depFileName, nil)
 	testfuncs.CheckErrs(t, g.want.err, err)
 	checkRes(t, g.want.res, got)
 }
@@ -226,14 +226,16 @@ func (m mockGraph) unmarshal(in []byte, out interface{}) error {
 }
 
 func (m mockGraph) readDeps(
+	fsys files.FS,
 	path,
 	depFileName string,
 	includeOr,
 	includeAnd,
-	exclude []string,
+	exclude,
+	ignoreFiles []string,
 ) (map[string]files.File, error) {
 	if m.rd != nil {
 		return nil, m.rd
 	}
-	return inputfile.FindAll(path, depFileName, includeOr, includeAnd, exclude)
+	return inputfile.FindAll(fsys, path, depFileName, includeOr, includeAnd, exclude, ignoreFiles)
 }
@@ -0,0 +1,237 @@
+// Package env manages the environments of the repository (see ./readme.md).
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
+	"gopkg.in/yaml.v3"
+)
+
+// CreateOptions configures a new environment.
+type CreateOptions struct {
+	Name string
+	// Dir is the folder of the new environment. It must not contain a config
+	// file yet.
+	Dir            string
+	ConfigFileName string
+	// From is the environment that is cloned (optional). Its value files inside
+	// its own folder are copied to Dir, all others are referenced.
+	From *inputfile.Environment
+	// Labels are added to (or replace) the labels of the cloned environment.
+	Labels map[string]string
+	// Overrides is the name of an empty values file that is created in Dir and
+	// appended to the values (optional).
+	Overrides string
+}
+
+// Create writes the folder, the config file and the value files of a new
+// environment to fsys and returns the paths of the created files.
+func Create(fsys files.FS, o CreateOptions) ([]string, error) {
+	if err := validateName(o.Name); err != nil {
+		return nil, err
+	}
+	cfgPath := filepath.Join(o.Dir, o.ConfigFileName)
+	if _, err := fsys.Stat(cfgPath); !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("config file %q already exists", cfgPath)
+	}
+
+	doc := newConfig(o.Name)
+	copies := map[string]string{}
+	if o.From != nil {
+		var err error
+		if doc, copies, err = cloneConfig(fsys, *o.From, o.Name, o.Dir); err != nil {
+			return nil, err
+		}
+	}
+	cfg := doc.Content[0]
+	if len(o.Labels) > 0 {
+		setLabels(cfg, o.Labels)
+	}
+	if o.Overrides != "" {
+		if _, exists := copies[o.Overrides]; exists {
+			return nil, fmt.Errorf("overrides file %q is already a value file of %q", o.Overrides, o.From.Config.Name)
+		}
+		values := mappingValue(cfg, "values")
+		values.Content = append(values.Content, str(filepath.ToSlash(o.Overrides)))
+	}
+
+	content, err := encode(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := fsys.MkdirAll(o.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	created := []string{cfgPath}
+	if err := fsys.WriteFile(cfgPath, content, 0o644); err != nil {
+		return nil, err
+	}
+	for _, rel := range maputils.KeysSorted(copies) {
+		dst := filepath.Join(o.Dir, rel)
+		if err := copyFile(fsys, copies[rel], dst); err != nil {
+			return created, err
+		}
+		created = append(created, dst)
+	}
+	if o.Overrides != "" {
+		p := filepath.Join(o.Dir, o.Overrides)
+		if err := fsys.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return created, err
+		}
+		if err := fsys.WriteFile(p, []byte{}, 0o644); err != nil {
+			return created, err
+		}
+		created = append(created, p)
+	}
+	return created, nil
+}
+
+func validateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("environment name must not be empty")
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("invalid environment name %q", name)
+	}
+	return nil
+}
+
+func newConfig(name string) *yaml.Node {
+	cfg := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	cfg.Content = []*yaml.Node{
+		str("type"), str(string(inputfile.ENVIRONMENT)),
+		str("name"), str(name),
+		str("values"), {Kind: yaml.SequenceNode, Tag: "!!seq"},
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{cfg}}
+}
+
+func str(v string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+}
+
+// cloneConfig returns the config file of the environment from with the new
+// name and the values paths rewritten for dir. Value files that are located in
+// the folder of from are returned as copies (path relative to dir -> source).
+func cloneConfig(
+	fsys files.FS, from inputfile.Environment, name, dir string,
+) (*yaml.Node, map[string]string, error) {
+	content, err := fsys.ReadFile(from.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %q: %w", from.Path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("config file %q is no yaml map", from.Path)
+	}
+	cfg := doc.Content[0]
+	mappingValue(cfg, "name").Value = name
+
+	srcDir := filepath.Dir(from.Path)
+	copies := map[string]string{}
+	for _, n := range mappingValue(cfg, "values").Content {
+		pathNode := n
+		if n.Kind == yaml.MappingNode {
+			pathNode = mappingValue(n, "path")
+		}
+		p := pathNode.Value
+		suffix := ""
+		if n.Kind == yaml.ScalarNode && strings.HasSuffix(p, "?") {
+			p, suffix = strings.TrimSuffix(p, "?"), "?"
+		}
+		abs := filepath.Join(srcDir, p)
+		if rel, ok := within(srcDir, abs); ok {
+			matches, err := fs.Glob(fsys, abs)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to resolve values path %q: %w", p, err)
+			}
+			for _, m := range matches {
+				if info, err := fsys.Stat(m); err == nil && !info.IsDir() {
+					r, _ := within(srcDir, m)
+					copies[r] = m
+				}
+			}
+			pathNode.Value = filepath.ToSlash(rel) + suffix
+			continue
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil {
+			return nil, nil, err
+		}
+		pathNode.Value = filepath.ToSlash(rel) + suffix
+	}
+	return &doc, copies, nil
+}
+
+// within returns the path p relative to dir if p is located in dir.
+func within(dir, p string) (string, bool) {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+func setLabels(cfg *yaml.Node, labels map[string]string) {
+	l := mappingValue(cfg, "labels")
+	if l.Kind != yaml.MappingNode {
+		*l = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	for _, k := range maputils.KeysSorted(labels) {
+		*mappingValue(l, k) = *str(labels[k])
+	}
+}
+
+// mappingValue returns the value node of the key in the mapping node m. Missing
+// keys are appended with an empty sequence (for "values") or null value.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	v := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	if key == "values" {
+		v = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+	m.Content = append(m.Content, str(key), v)
+	return v
+}
+
+func encode(doc *yaml.Node) ([]byte, error) {
+	var b strings.Builder
+	e := yaml.NewEncoder(&b)
+	e.SetIndent(2)
+	if err := e.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+func copyFile(fsys files.FS, src, dst string) error {
+	content, err := fsys.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	info, err := fsys.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := fsys.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return fsys.WriteFile(dst, content, info.Mode().Perm())
+}
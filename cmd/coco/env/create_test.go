@@ -0,0 +1,120 @@
+package env
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	tree := map[string]string{
+		"values/common.yaml": "replicas: 1\n",
+		"values/eu/coco.yaml": "# cluster in eu\ntype: environment\nname: eu-1\nlabels:\n  region: eu\n" +
+			"values:\n  - ../common.yaml\n  - cluster.yaml\n  - path: secrets/*.yaml\n    optional: true\n",
+		"values/eu/cluster.yaml":   "replicas: 3\n",
+		"values/eu/secrets/a.yaml": "a: 1\n",
+	}
+	for name, content := range tree {
+		p := filepath.Join(dir, name)
+		testfuncs.MustBeNil(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		testfuncs.MustBeNil(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	envs, err := inputfile.ReadEnvironments(files.OS(), dir, "coco.yaml", []string{}, []string{}, []string{}, nil)
+	testfuncs.MustBeNil(t, err)
+	from := envs["eu-1"]
+
+	for _, s := range []struct {
+		title       string
+		opts        CreateOptions
+		wantCreated []string
+		wantConfig  string
+		wantValues  interface{}
+		wantErr     error
+	}{
+		{
+			title: "new environment",
+			opts: CreateOptions{
+				Name: "us-1", Dir: filepath.Join(dir, "values/us"), ConfigFileName: "coco.yaml",
+				Labels: map[string]string{"region": "us", "tier": "1"}, Overrides: "overrides.yaml",
+			},
+			wantCreated: []string{"values/us/coco.yaml", "values/us/overrides.yaml"},
+			wantConfig: `type: environment
+name: us-1
+values:
+  - overrides.yaml
+labels:
+  region: us
+  tier: "1"
+`,
+		},
+		{
+			title: "clone environment",
+			opts: CreateOptions{
+				Name: "eu-2", Dir: filepath.Join(dir, "values/eu/second"), ConfigFileName: "coco.yaml",
+				From: &from, Labels: map[string]string{"tier": "2"},
+			},
+			wantCreated: []string{
+				"values/eu/second/coco.yaml", "values/eu/second/cluster.yaml", "values/eu/second/secrets/a.yaml",
+			},
+			wantConfig: `# cluster in eu
+type: environment
+name: eu-2
+labels:
+  region: eu
+  tier: "2"
+values:
+  - ../../common.yaml
+  - cluster.yaml
+  - path: secrets/*.yaml
+    optional: true
+`,
+			wantValues: map[string]interface{}{"replicas": 3, "a": 1},
+		},
+		{
+			title:   "existing config file",
+			opts:    CreateOptions{Name: "eu-3", Dir: filepath.Join(dir, "values/eu"), ConfigFileName: "coco.yaml"},
+			wantErr: errors.New(`config file "` + filepath.Join(dir, "values/eu/coco.yaml") + `" already exists`),
+		},
+		{
+			title: "overrides file that is cloned",
+			opts: CreateOptions{
+				Name: "eu-4", Dir: filepath.Join(dir, "values/eu4"), ConfigFileName: "coco.yaml",
+				From: &from, Overrides: "cluster.yaml",
+			},
+			wantErr: errors.New(`overrides file "cluster.yaml" is already a value file of "eu-1"`),
+		},
+		{
+			title:   "invalid name",
+			opts:    CreateOptions{Name: "a/b", Dir: filepath.Join(dir, "values/ab"), ConfigFileName: "coco.yaml"},
+			wantErr: errors.New(`invalid environment name "a/b"`),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		created, err := Create(files.OS(), s.opts)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		if s.wantErr != nil {
+			continue
+		}
+		want := make([]string, 0, len(s.wantCreated))
+		for _, c := range s.wantCreated {
+			want = append(want, filepath.Join(dir, c))
+		}
+		testfuncs.CheckEqualityInterface(t, want, created)
+		content, err := os.ReadFile(created[0])
+		testfuncs.MustBeNil(t, err)
+		testfuncs.CheckEqualityInterface(t, s.wantConfig, string(content))
+
+		if s.wantValues == nil {
+			continue
+		}
+		envs, err := inputfile.ReadEnvironments(files.OS(), dir, "coco.yaml", []string{}, []string{}, []string{}, nil)
+		testfuncs.MustBeNil(t, err)
+		testfuncs.CheckEqualityInterface(t, s.wantValues, envs[s.opts.Name].Values)
+	}
+}
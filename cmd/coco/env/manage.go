@@ -0,0 +1,281 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/generate"
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
+	"gopkg.in/yaml.v3"
+)
+
+// Repository holds the settings to find the environments and the templates of
+// the repository.
+type Repository struct {
+	Basepath           string
+	ConfigFileName     string
+	TemplateIdentifier string
+	// ValueFolders are the folders with the environments
+	ValueFolders []string
+	// IgnoreFiles are the names of the ignore files that are honoured when
+	// searching environments and templates (see files.FileRunner.IgnoreFiles)
+	IgnoreFiles []string
+	// Version is the coco version; only files generated by a compatible version
+	// are moved or removed
+	Version *version.Version
+}
+
+// Operation of an Action.
+type Operation string
+
+const (
+	Move   Operation = "move"
+	Update Operation = "update"
+	Delete Operation = "remove"
+)
+
+// Action is a planned change of a file or folder (see Rename and Remove).
+type Action struct {
+	Op   Operation
+	Path string
+	// To is the target of Move actions
+	To string
+	// Root is the folder up to which empty parent folders are removed after the
+	// path has been moved or removed
+	Root    string
+	content []byte
+}
+
+// Rename plans the renaming of the environment oldName: its config file gets
+// the new name and all generated outputs are moved to the paths of the new
+// name. The content of the outputs (including lines marked as HumanInput) is
+// kept as is. Files that have not been generated (see generate.Output.Generated)
+// are not moved, also if they are located in generated folders.
+func Rename(fsys files.FS, r Repository, oldName, newName string) ([]Action, error) {
+	if err := validateName(newName); err != nil {
+		return nil, err
+	}
+	envs, err := r.environments(fsys)
+	if err != nil {
+		return nil, err
+	}
+	env, ok := envs[oldName]
+	if !ok {
+		return nil, fmt.Errorf("environment %q not found", oldName)
+	}
+	if _, exists := envs[newName]; exists {
+		return nil, fmt.Errorf("environment %q already exists", newName)
+	}
+
+	content, err := renamedConfig(fsys, env.Path, newName)
+	if err != nil {
+		return nil, err
+	}
+	res := []Action{{Op: Update, Path: env.Path, content: content}}
+
+	from, err := r.outputs(fsys, oldName, env.Values)
+	if err != nil {
+		return nil, err
+	}
+	to, err := r.outputs(fsys, newName, env.Values)
+	if err != nil {
+		return nil, err
+	}
+	if len(from) != len(to) {
+		return nil, fmt.Errorf("outputs of %q and %q do not match", oldName, newName)
+	}
+	moved := map[string]bool{}
+	for i, o := range from {
+		src, dst := o.File, to[i].File
+		if src == dst || moved[src] {
+			continue
+		}
+		generated, err := o.Generated(fsys, r.Version)
+		if err != nil {
+			return nil, err
+		}
+		if !generated {
+			continue
+		}
+		if exists(fsys, dst) {
+			return nil, fmt.Errorf("cannot move %q: %q already exists", src, dst)
+		}
+		moved[src] = true
+		res = append(res, Action{Op: Move, Path: src, To: dst, Root: o.Location})
+	}
+	return res, nil
+}
+
+// Remove plans the removal of the environment name: its generated outputs, its
+// config file and the value files in its folder that no other environment uses.
+// Files that have not been generated (see generate.Output.Generated) are kept,
+// also if they are located in generated folders.
+func Remove(fsys files.FS, r Repository, name string) ([]Action, error) {
+	envs, err := r.environments(fsys)
+	if err != nil {
+		return nil, err
+	}
+	env, ok := envs[name]
+	if !ok {
+		return nil, fmt.Errorf("environment %q not found", name)
+	}
+	outputs, err := r.outputs(fsys, name, env.Values)
+	if err != nil {
+		return nil, err
+	}
+	res := []Action{}
+	removed := map[string]bool{}
+	for _, o := range outputs {
+		if removed[o.File] {
+			continue
+		}
+		generated, err := o.Generated(fsys, r.Version)
+		if err != nil {
+			return nil, err
+		}
+		if !generated {
+			continue
+		}
+		removed[o.File] = true
+		res = append(res, Action{Op: Delete, Path: o.File, Root: o.Location})
+	}
+
+	used := map[string]bool{}
+	for n, e := range envs {
+		if n == name {
+			continue
+		}
+		for _, f := range e.ValueFiles {
+			used[filepath.Clean(f)] = true
+		}
+	}
+	dir := filepath.Dir(env.Path)
+	root := filepath.Dir(dir)
+	res = append(res, Action{Op: Delete, Path: env.Path, Root: root})
+	for _, f := range env.ValueFiles {
+		f = filepath.Clean(f)
+		if _, ok := within(dir, f); !ok || used[f] || removed[f] {
+			continue
+		}
+		removed[f] = true
+		res = append(res, Action{Op: Delete, Path: f, Root: root})
+	}
+	return res, nil
+}
+
+// Apply executes the actions in order on fsys. Only files are moved and removed,
+// folders are removed once they are empty (up to the root of the action).
+func Apply(fsys files.FS, actions []Action) error {
+	for _, a := range actions {
+		var err error
+		switch a.Op {
+		case Update:
+			err = fsys.WriteFile(a.Path, a.content, 0o644)
+		case Move:
+			if err = copyFile(fsys, a.Path, a.To); err == nil {
+				err = fsys.Remove(a.Path)
+			}
+		case Delete:
+			err = fsys.Remove(a.Path)
+		default:
+			err = fmt.Errorf("unknown operation %q", a.Op)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s %q: %w", a.Op, a.Path, err)
+		}
+		if a.Op != Update {
+			if err := removeEmptyParents(fsys, a.Path, a.Root); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PrintActions writes one line per action with paths relative to basepath, e.g.
+//
+//	move services/app/app-eu.yaml -> services/app/app-us.yaml
+func PrintActions(w io.Writer, actions []Action, basepath string) error {
+	var b strings.Builder
+	for _, a := range actions {
+		fmt.Fprintf(&b, "%s %s", a.Op, relativePath(basepath, a.Path))
+		if a.Op == Move {
+			fmt.Fprintf(&b, " -> %s", relativePath(basepath, a.To))
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (r Repository) environments(fsys files.FS) (map[string]inputfile.Environment, error) {
+	return inputfile.ReadEnvironments(
+		fsys, r.Basepath, r.ConfigFileName, r.ValueFolders, []string{}, []string{r.TemplateIdentifier},
+		r.IgnoreFiles,
+	)
+}
+
+func (r Repository) outputs(fsys files.FS, env string, values interface{}) ([]generate.Output, error) {
+	return generate.EnvironmentOutputs(
+		fsys, r.Basepath, r.TemplateIdentifier, r.ConfigFileName, env, values, r.IgnoreFiles,
+	)
+}
+
+// renamedConfig returns the content of the config file with the new name. Other
+// content and comments are kept.
+func renamedConfig(fsys files.FS, path, name string) ([]byte, error) {
+	content, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file %q is no yaml map", path)
+	}
+	*mappingValue(doc.Content[0], "name") = *str(name)
+	return encode(&doc)
+}
+
+func exists(fsys files.FS, p string) bool {
+	_, err := fsys.Stat(p)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// removeEmptyParents removes the parent folders of path that are empty, up to
+// (but excluding) root.
+func removeEmptyParents(fsys files.FS, path, root string) error {
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, ok := within(root, dir); !ok || dir == root {
+			return nil
+		}
+		entries, err := fsys.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(entries) != 0 {
+			return nil
+		}
+		if err := fsys.Remove(dir); err != nil {
+			return err
+		}
+	}
+}
+
+func relativePath(basepath, p string) string {
+	if rel, ok := within(basepath, p); ok {
+		return filepath.ToSlash(rel)
+	}
+	return p
+}
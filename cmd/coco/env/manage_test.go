@@ -0,0 +1,120 @@
+package env
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
+)
+
+// header is the header of files generated by version 0.0.
+const header = "# Code generated by CLI 'coco generate ...' (version: 0.0); DO NOT EDIT.\n\n"
+
+func prepareRepository(t *testing.T) (Repository, func(string) string) {
+	dir := t.TempDir()
+	tree := map[string]string{
+		"app/app.tmpl":          "value: {{ .value }}\n",
+		"app/app-eu.yaml":       header + "value: 1\nkeep: me # HumanInput\n",
+		"svc/svc.tmpl/a.yaml":   "a: 1\n",
+		"svc/svc.tmpl/logo.bin": "\xff",
+		"svc/svc.tmpl/b.yaml":   "b: {{ .value }}\n",
+		"svc/svc-eu/a.yaml":     header + "a: 1\n",
+		"svc/svc-eu/logo.bin":   "\xff",
+		"svc/svc-eu/b.yaml":     "b: edited by hand\n",
+		"svc/svc-eu/notes.md":   "hand-written\n",
+		"values/common.yaml":    "value: 1\n",
+		"values/eu/coco.yaml":   "# eu cluster\ntype: environment\nname: eu\nvalues:\n  - ../common.yaml\n  - eu.yaml\n",
+		"values/eu/eu.yaml":     "value: 1\n",
+		"values/eu/shared.yaml": "value: 2\n",
+		"values/us/coco.yaml":   "type: environment\nname: us\nvalues:\n  - ../common.yaml\n  - ../eu/shared.yaml\n",
+	}
+	for name, content := range tree {
+		p := filepath.Join(dir, name)
+		testfuncs.MustBeNil(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		testfuncs.MustBeNil(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	r := Repository{
+		Basepath:           dir,
+		ConfigFileName:     "coco.yaml",
+		TemplateIdentifier: ".tmpl",
+		ValueFolders:       []string{filepath.Join(dir, "values") + string(os.PathSeparator)},
+		Version:            &version.Version{},
+	}
+	return r, func(f string) string { return filepath.Join(dir, f) }
+}
+
+func TestRename(t *testing.T) {
+	r, p := prepareRepository(t)
+
+	_, err := Rename(files.OS(), r, "eu", "us")
+	testfuncs.CheckErrs(t, errors.New(`environment "us" already exists`), err)
+	_, err = Rename(files.OS(), r, "ap", "ap-1")
+	testfuncs.CheckErrs(t, errors.New(`environment "ap" not found`), err)
+
+	actions, err := Rename(files.OS(), r, "eu", "eu-1")
+	testfuncs.MustBeNil(t, err)
+	var b bytes.Buffer
+	testfuncs.MustBeNil(t, PrintActions(&b, actions, r.Basepath))
+	testfuncs.CheckEqualityInterface(t, `update values/eu/coco.yaml
+move app/app-eu.yaml -> app/app-eu-1.yaml
+move svc/svc-eu/a.yaml -> svc/svc-eu-1/a.yaml
+move svc/svc-eu/logo.bin -> svc/svc-eu-1/logo.bin
+`, b.String())
+
+	testfuncs.MustBeNil(t, Apply(files.OS(), actions))
+	for f, want := range map[string]string{
+		"values/eu/coco.yaml":   "# eu cluster\ntype: environment\nname: eu-1\nvalues:\n  - ../common.yaml\n  - eu.yaml\n",
+		"app/app-eu-1.yaml":     header + "value: 1\nkeep: me # HumanInput\n",
+		"svc/svc-eu-1/a.yaml":   header + "a: 1\n",
+		"svc/svc-eu/b.yaml":     "b: edited by hand\n",
+		"svc/svc-eu/notes.md":   "hand-written\n",
+		"app/app.tmpl":          "value: {{ .value }}\n",
+		"values/eu/shared.yaml": "value: 2\n",
+	} {
+		content, err := os.ReadFile(p(f))
+		testfuncs.MustBeNil(t, err)
+		testfuncs.CheckEqualityInterface(t, want, string(content))
+	}
+	for _, f := range []string{"app/app-eu.yaml", "svc/svc-eu/a.yaml", "svc/svc-eu/logo.bin"} {
+		if _, err := os.Stat(p(f)); !os.IsNotExist(err) {
+			t.Errorf("%q must be moved", f)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	r, p := prepareRepository(t)
+
+	actions, err := Remove(files.OS(), r, "eu")
+	testfuncs.MustBeNil(t, err)
+	var b bytes.Buffer
+	testfuncs.MustBeNil(t, PrintActions(&b, actions, r.Basepath))
+	testfuncs.CheckEqualityInterface(t, `remove app/app-eu.yaml
+remove svc/svc-eu/a.yaml
+remove svc/svc-eu/logo.bin
+remove values/eu/coco.yaml
+remove values/eu/eu.yaml
+`, b.String())
+
+	testfuncs.MustBeNil(t, Apply(files.OS(), actions))
+	for _, f := range []string{
+		"app/app-eu.yaml", "svc/svc-eu/a.yaml", "svc/svc-eu/logo.bin", "values/eu/coco.yaml", "values/eu/eu.yaml",
+	} {
+		if _, err := os.Stat(p(f)); !os.IsNotExist(err) {
+			t.Errorf("%q must be removed", f)
+		}
+	}
+	for _, f := range []string{
+		"app/app.tmpl", "svc/svc.tmpl/a.yaml", "svc/svc-eu/b.yaml", "svc/svc-eu/notes.md",
+		"values/common.yaml", "values/eu/shared.yaml",
+	} {
+		if _, err := os.Stat(p(f)); err != nil {
+			t.Errorf("%q must be kept: %v", f, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package generate
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+)
+
+// Destination redirects the generated files into the folder Dir or the tar
+// archive Tar (gzip compressed for the extensions .tgz and .gz) instead of
+// writing them in place. The files keep their path relative to the git path.
+// Templates and existing files are still read from the repository, which stays
+// untouched.
+//
+// With Atomic, files that are generated in place are staged in memory and only
+// written if all templates have been rendered and validated without error. An
+// interrupt (SIGINT) before or while writing leaves the repository unchanged.
+type Destination struct {
+	Dir    string
+	Tar    string
+	Atomic bool
+}
+
+// Validate returns an error if both the folder and the archive are set.
+func (d Destination) Validate() error {
+	if d.Dir != "" && d.Tar != "" {
+		return errors.New("an output folder and an output archive are mutually exclusive")
+	}
+	return nil
+}
+
+func (d Destination) enabled() bool {
+	return d.Dir != "" || d.Tar != ""
+}
+
+// atomic reports whether files are generated in place all at once.
+func (d Destination) atomic() bool {
+	return d.Atomic && !d.enabled()
+}
+
+// stage returns the file system that the files are generated in: a layer in
+// memory on top of fsys if the files are redirected or written atomically and
+// fsys otherwise.
+func (d Destination) stage(fsys files.FS) files.FS {
+	if !d.enabled() && !d.Atomic {
+		return fsys
+	}
+	return files.NewOverlay(files.NewMemFS(), fsys)
+}
+
+// interruptible returns a context that is cancelled by an interrupt if the files
+// are written atomically.
+func (d Destination) interruptible() (context.Context, context.CancelFunc) {
+	if !d.atomic() {
+		return context.WithCancel(context.Background())
+	}
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// commit writes the files that have been staged for an atomic generation.
+func (d Destination) commit(ctx context.Context, staging files.FS) error {
+	o, ok := staging.(*files.Overlay)
+	if !d.atomic() || !ok {
+		return nil
+	}
+	return o.Commit(ctx)
+}
+
+// folder returns the folder that the generated files are exported to. For an
+// archive, this is a temporary folder that cleanup removes.
+func (d Destination) folder() (dir string, cleanup func(), err error) {
+	if d.Dir != "" {
+		return d.Dir, func() {}, nil
+	}
+	dir, err = os.MkdirTemp("", "coco-output-")
+	if err != nil {
+		return "", nil, err
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// export copies the generated files from the staging file system to dir and
+// returns the outputs with their paths in dir.
+func export(
+	staging files.FS, basepath, dir string, outputs []generatedOutput, copies []string,
+) ([]generatedOutput, error) {
+	target := func(path string) (string, error) {
+		rel, err := filepath.Rel(basepath, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("generated file %q is outside of the git path %q", path, basepath)
+		}
+		return filepath.Join(dir, rel), nil
+	}
+
+	paths := map[string]bool{}
+	for _, c := range copies {
+		paths[c] = true
+	}
+	res := make([]generatedOutput, 0, len(outputs))
+	for _, o := range outputs {
+		paths[o.file] = true
+		file, err := target(o.file)
+		if err != nil {
+			return nil, err
+		}
+		o.file = file
+		if o.dir != "" {
+			if o.dir, err = target(o.dir); err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, o)
+	}
+
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+	for _, p := range sorted {
+		fp, err := target(p)
+		if err != nil {
+			return nil, err
+		}
+		if err := exportFile(staging, p, fp); err != nil {
+			return nil, fmt.Errorf("failed to export %q: %w", p, err)
+		}
+	}
+	return res, nil
+}
+
+func exportFile(staging files.FS, path, target string) error {
+	info, err := staging.Stat(path)
+	if err != nil {
+		return err
+	}
+	content, err := staging.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), allAllowed); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, content, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chmod(target, info.Mode().Perm())
+}
+
+// archive writes the files of dir to the tar archive of the destination.
+func (d Destination) archive(dir string) (err error) {
+	f, err := os.Create(d.Tar)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := f.Close(); err == nil {
+			err = e
+		}
+	}()
+	var w io.Writer = f
+	if ext := filepath.Ext(d.Tar); ext == ".tgz" || ext == ".gz" {
+		gz := gzip.NewWriter(f)
+		defer func() {
+			if e := gz.Close(); err == nil {
+				err = e
+			}
+		}()
+		w = gz
+	}
+	return files.WriteTar(w, files.OS(), dir)
+}
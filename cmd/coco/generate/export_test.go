@@ -0,0 +1,220 @@
+package generate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
+	"go.uber.org/zap"
+)
+
+func TestGenerateDestination(t *testing.T) {
+	if err := log.Init(log.Warn(), "", true); err != nil {
+		zap.S().Fatal(err)
+	}
+	repo := t.TempDir()
+	for name, content := range map[string]string{
+		"values/c1/coco.yaml":   "type: environment\nname: c1\nvalues:\n  - values.yaml\n",
+		"values/c1/values.yaml": "replicas: 2\nname: app\n",
+		"svc/.tmpl/deploy.yaml": "replicas: {{ .replicas }}\n",
+		"svc/.tmpl/logo.bin":    "\xff\xfe",
+		"svc/name.tmpl":         "name: {{ .name }}\n",
+		// up to date files are part of the destination as well
+		"svc/name-c1.yaml": fmt.Sprintf(genFileHeader, 0, 0) + "name: app\n",
+	} {
+		p := filepath.Join(repo, name)
+		testfuncs.MustBeNil(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		testfuncs.MustBeNil(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	header := fmt.Sprintf(genFileHeader, 0, 0)
+	want := map[string]string{
+		"svc/c1/deploy.yaml": header + "replicas: 2\n",
+		"svc/c1/logo.bin":    "\xff\xfe",
+		"svc/c1/hooked":      "",
+		"svc/hooked":         "",
+		"svc/name-c1.yaml":   header + "name: app\n",
+	}
+	hooks := HookConfig{Hooks: []inputfile.Hook{{
+		Command: []string{"touch", "{{ .Path }}/hooked"}, Scope: inputfile.HookScopeDirectory,
+	}}}
+	out := t.TempDir()
+
+	for _, s := range []struct {
+		title   string
+		dest    Destination
+		read    func(t *testing.T) map[string]string
+		wantErr error
+	}{
+		{
+			title: "folder",
+			dest:  Destination{Dir: filepath.Join(out, "render")},
+			read:  func(t *testing.T) map[string]string { return readTree(t, filepath.Join(out, "render")) },
+		},
+		{
+			title: "archive",
+			dest:  Destination{Tar: filepath.Join(out, "render.tar")},
+			read:  func(t *testing.T) map[string]string { return readTar(t, filepath.Join(out, "render.tar"), false) },
+		},
+		{
+			title: "compressed archive",
+			dest:  Destination{Tar: filepath.Join(out, "render.tgz")},
+			read:  func(t *testing.T) map[string]string { return readTar(t, filepath.Join(out, "render.tgz"), true) },
+		},
+		{
+			title:   "folder and archive",
+			dest:    Destination{Dir: filepath.Join(out, "both"), Tar: filepath.Join(out, "both.tar")},
+			wantErr: errors.New("an output folder and an output archive are mutually exclusive"),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		err := Generate(
+			repo, ".tmpl", "HumanInput", "coco.yaml", &version.Version{},
+			[]string{filepath.Join(repo, "values") + "/"}, []string{}, []string{}, []string{}, nil,
+			log.Warn(), false, false, hooks, nil, files.OS(), s.dest,
+		)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		if s.wantErr != nil {
+			continue
+		}
+		testfuncs.CheckEqualityInterface(t, want, s.read(t))
+		if _, err := os.Stat(filepath.Join(repo, "svc/c1")); !os.IsNotExist(err) {
+			t.Errorf("generated files have been written to the repository: %v", err)
+		}
+	}
+}
+
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	res := map[string]string{}
+	testfuncs.MustBeNil(t, filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		res[filepath.ToSlash(rel)] = string(content)
+		return err
+	}))
+	return res
+}
+
+func readTar(t *testing.T, path string, compressed bool) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	testfuncs.MustBeNil(t, err)
+	defer f.Close()
+	var r io.Reader = f
+	if compressed {
+		gz, err := gzip.NewReader(f)
+		testfuncs.MustBeNil(t, err)
+		r = gz
+	}
+	res := map[string]string{}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return res
+		}
+		testfuncs.MustBeNil(t, err)
+		content, err := io.ReadAll(tr)
+		testfuncs.MustBeNil(t, err)
+		res[h.Name] = string(content)
+	}
+}
+
+func TestGenerateAtomic(t *testing.T) {
+	if err := log.Init(log.Warn(), "", true); err != nil {
+		zap.S().Fatal(err)
+	}
+	header := fmt.Sprintf(genFileHeader, 0, 0)
+	for _, s := range []struct {
+		title     string
+		broken    bool
+		interrupt bool
+		want      map[string]string
+		wantErr   error
+	}{
+		{
+			title: "all templates render",
+			want: map[string]string{
+				"a/name-c1.yaml": header + "name: app\n",
+				"b/name-c1.yaml": header + "name: app\n",
+			},
+		},
+		{
+			title:   "one template fails",
+			broken:  true,
+			want:    map[string]string{"b/name-c1.yaml": header + "name: old\n"},
+			wantErr: errors.New("1 rendering errors encountered, no files have been changed"),
+		},
+		{
+			title:     "interrupt",
+			interrupt: true,
+			want:      map[string]string{"b/name-c1.yaml": header + "name: old\n"},
+			wantErr:   errors.New("generation interrupted, no files have been changed"),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		repo := t.TempDir()
+		tree := map[string]string{
+			"values/c1/coco.yaml":   "type: environment\nname: c1\nvalues:\n  - values.yaml\n",
+			"values/c1/values.yaml": "name: app\n",
+			"a/name.tmpl":           "name: {{ .name }}\n",
+			"b/name.tmpl":           "name: {{ .name }}\n",
+			"b/name-c1.yaml":        header + "name: old\n",
+		}
+		if s.broken {
+			tree["a/name.tmpl"] = "name: {{ .name \n"
+		}
+		for name, content := range tree {
+			p := filepath.Join(repo, name)
+			testfuncs.MustBeNil(t, os.MkdirAll(filepath.Dir(p), 0o755))
+			testfuncs.MustBeNil(t, os.WriteFile(p, []byte(content), 0o644))
+		}
+		done := make(chan struct{})
+		if s.interrupt {
+			renderer = func(
+				name string, tmpls []template, vals map[string]interface{}, claims outputClaims,
+				reports chan<- renderReport,
+				logLvl log.Level, persistenceComment string, v *version.Version, takeControl, failFast bool,
+				fsys files.FS,
+			) {
+				testfuncs.MustBeNil(t, syscall.Kill(os.Getpid(), syscall.SIGINT))
+				<-done
+				render(name, tmpls, vals, claims, reports, logLvl, persistenceComment, v, takeControl, failFast, fsys)
+			}
+		}
+
+		err := Generate(
+			repo, ".tmpl", "HumanInput", "coco.yaml", &version.Version{},
+			[]string{filepath.Join(repo, "values") + "/"}, []string{}, []string{}, []string{}, nil,
+			log.Warn(), false, false, HookConfig{}, nil, files.OS(), Destination{Atomic: true},
+		)
+		close(done)
+		renderer = render
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		got := readTree(t, repo)
+		for name, want := range s.want {
+			testfuncs.CheckEqualityInterface(t, want, got[name])
+		}
+		if _, ok := got["a/name-c1.yaml"]; ok && s.wantErr != nil {
+			t.Errorf("files have been written despite of the error")
+		}
+		testfuncs.CheckEqualityInterface(t, len(tree)+len(s.want)-1, len(got))
+	}
+}
@@ -1,23 +1,36 @@
 package generate
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 )
 
 func findTemplates(
-	basepath, tmplIdentifier string, includeFilters, excludeFilters []string,
+	fsys files.FS, basepath, tmplIdentifier string, includeFilters, excludeFilters, ignoreFiles []string,
 ) (map[string][]template, error) {
-	include := []string{tmplIdentifier}
-	include = append(include, includeFilters...)
+	include, err := files.ParseFilters(includeFilters)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := files.ParseFilters(excludeFilters)
+	if err != nil {
+		return nil, err
+	}
 
 	list, err := files.New(basepath).
-		Include(files.AND, include).
-		Exclude(files.OR, excludeFilters).
+		FileSystem(fsys).
+		IgnoreFiles(ignoreFiles...).
+		Include(files.AND, []string{tmplIdentifier}).
+		IncludeFilters(files.AND, include...).
+		ExcludeFilters(files.OR, exclude...).
 		Execute()
 	if err != nil {
 		return nil, err
@@ -67,3 +80,35 @@ func addTemplate(res map[string][]template, path, tmplIdentifier string) {
 		res[b] = []template{t}
 	}
 }
+
+// readTemplateConfigs attaches the template configuration to all templates of
+// a template location. The configuration is read from the config file in the
+// folder of the template location (if present). Config files of type
+// environment are ignored since they configure value files and not templates.
+func readTemplateConfigs(fsys files.FS, tmpls map[string][]template, configFileName string) error {
+	for location, templates := range tmpls {
+		path := filepath.Join(location, configFileName)
+		if _, err := fsys.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		cfg, err := inputfile.LoadFrom(fsys, path)
+		if err != nil {
+			return fmt.Errorf("failed to load template configuration %q: %w", path, err)
+		}
+		if cfg.IsEnvironment() {
+			continue
+		}
+		for _, h := range cfg.Hooks {
+			if err := h.Validate(); err != nil {
+				return fmt.Errorf("invalid template configuration %q: %w", path, err)
+			}
+		}
+		if err := cfg.Delimiters.Validate(); err != nil {
+			return fmt.Errorf("invalid template configuration %q: %w", path, err)
+		}
+		for i := range templates {
+			templates[i].config = &cfg
+		}
+	}
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
 )
 
@@ -156,7 +158,7 @@ func (s *scenario) Test(t *testing.T) {
 
 	t.Logf("temporary directory for test: %s", tmpDir)
 
-	got, err := findTemplates(tmpDir, tmplIdentifier, s.inclFilters, s.exclFilters)
+	got, err := findTemplates(files.OS(), tmpDir, tmplIdentifier, s.inclFilters, s.exclFilters, nil)
 	testfuncs.CheckErrs(t, s.wantErr, err)
 
 	s.CheckRes(t, tmpDir, got)
@@ -198,3 +200,40 @@ func (s *scenario) CheckRes(t *testing.T, basedir string, got map[string][]templ
 		t.Fail()
 	}
 }
+
+func TestReadTemplateConfigs(t *testing.T) {
+	td, err := testfuncs.PrepareTestDirTree(map[string][]byte{
+		"A/.tmpl": nil,
+		"A/coco.yaml": []byte(`
+type: template
+arrayMerge:
+  policy: append
+`),
+		"B/.tmpl":     nil,
+		"C/.tmpl":     nil,
+		"C/coco.yaml": []byte(`type: environment`),
+	})
+	if err != nil {
+		t.Logf("unable to create test dir tree: %v\n", err)
+		t.FailNow()
+	}
+	defer td.Cleanup(t)
+	tmpDir := td.Path()
+
+	tmpls, err := findTemplates(files.OS(), tmpDir, tmplIdentifier, nil, nil, nil)
+	testfuncs.CheckErrs(t, nil, err)
+	testfuncs.CheckErrs(t, nil, readTemplateConfigs(files.OS(), tmpls, "coco.yaml"))
+
+	want := map[string]*inputfile.Coco{
+		"A": {Type: inputfile.TEMPLATE, ArrayMerge: &inputfile.ArrayMerge{Policy: "append"}},
+		"B": nil,
+		"C": nil,
+	}
+	for location, cfg := range want {
+		got := tmpls[filepath.Join(tmpDir, location)]
+		if len(got) != 1 {
+			t.Fatalf("expected 1 template in %q, got %+v", location, got)
+		}
+		testfuncs.CheckEqualityInterface(t, cfg, got[0].config)
+	}
+}
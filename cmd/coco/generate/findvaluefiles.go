@@ -1,53 +1,21 @@
 package generate
 
 import (
-	"path/filepath"
-
 	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 )
 
 func readValueFiles(
-	basepath, configFileName string,
-	includeOr, includeAnd, exclude []string,
+	fsys files.FS, basepath, configFileName string,
+	includeOr, includeAnd, exclude, ignoreFiles []string,
 ) (map[string]interface{}, error) {
-	valueFiles, err := inputfile.FindAll(basepath, configFileName, includeOr, includeAnd, exclude)
+	envs, err := inputfile.ReadEnvironments(fsys, basepath, configFileName, includeOr, includeAnd, exclude, ignoreFiles)
 	if err != nil {
 		return nil, err
 	}
-	res := make(map[string]interface{}, len(valueFiles))
-	for path, file := range valueFiles {
-		if file.IsDir {
-			continue
-		}
-
-		coco, err := inputfile.Load(path)
-		if err != nil {
-			return nil, err
-		}
-
-		if !coco.IsEnvironment() {
-			continue
-		}
-
-		dir := filepath.Dir(path)
-		valueFilesForEnv := make([]string, 0, len(coco.Values))
-		for _, v := range coco.Values {
-			valueFilesForEnv = append(valueFilesForEnv, filepath.Join(dir, v))
-		}
-
-		merged, err := mergeValues(valueFilesForEnv)
-		if err != nil {
-			return nil, err
-		}
-
-		var finalValues interface{}
-		err = merged.Decode(&finalValues)
-		if err != nil {
-			return nil, err
-		}
-		res[coco.Name] = finalValues
-
-		res[coco.Name] = finalValues
+	res := make(map[string]interface{}, len(envs))
+	for name, env := range envs {
+		res[name] = env.Values
 	}
 	return res, nil
 }
@@ -2,11 +2,14 @@ package generate
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
 
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
@@ -171,6 +174,139 @@ k33: v33
 		},
 		wantErr: nil,
 	},
+	{
+		title:          "glob and optional value files",
+		includeFilters: []string{"${BASEPATH}/values/"},
+		excludeFilters: []string{".tmpl"},
+		files: map[string][]byte{
+			"values/common/b.yaml": []byte(`
+k1: b
+k2: b
+`),
+			"values/common/a.yaml": []byte(`
+k1: a
+k3: a
+`),
+			"values/env1/file1.yaml": []byte(`k2: v1`),
+			"values/env1/coco.yaml": []byte(`
+type: environment
+name: name1
+values:
+  - ../common/*.yaml
+  - file1.yaml
+  - overrides.yaml?
+`),
+		},
+		wantFiles: map[string][]byte{
+			"name1": []byte(`
+k1: b
+k2: v1
+k3: a
+`),
+		},
+		wantErr: nil,
+	},
+	{
+		title:          "array merge configuration",
+		includeFilters: []string{"${BASEPATH}/values/"},
+		excludeFilters: []string{".tmpl"},
+		files: map[string][]byte{
+			"values/env1/file1.yaml": []byte(`
+cidrs: [a]
+containers: [a]
+`),
+			"values/env1/file2.yaml": []byte(`
+cidrs: [b]
+containers: [b]
+`),
+			"values/env1/coco.yaml": []byte(`
+type: environment
+name: name1
+values:
+  - file1.yaml
+  - file2.yaml
+arrayMerge:
+  exceptions:
+    - path: cidrs
+      policy: append
+`),
+		},
+		wantFiles: map[string][]byte{
+			"name1": []byte(`
+cidrs: [a, b]
+containers: [b]
+`),
+		},
+		wantErr: nil,
+	},
+	{
+		title:          "anchors in value files",
+		includeFilters: []string{"${BASEPATH}/values/"},
+		excludeFilters: []string{".tmpl"},
+		files: map[string][]byte{
+			"values/env1/file1.yaml": []byte(`
+presets:
+  small: &small
+    cpu: 1
+    memory: 1Gi
+svc1:
+  resources: *small
+svc2:
+  resources:
+    <<: *small
+    cpu: 2
+`),
+			"values/env1/file2.yaml": []byte(`
+svc1:
+  resources:
+    memory: 2Gi
+`),
+			"values/env1/coco.yaml": []byte(`
+type: environment
+name: name1
+values:
+  - file1.yaml
+  - file2.yaml
+`),
+		},
+		wantFiles: map[string][]byte{
+			"name1": []byte(`
+presets:
+  small:
+    cpu: 1
+    memory: 1Gi
+svc1:
+  resources:
+    cpu: 1
+    memory: 2Gi
+svc2:
+  resources:
+    cpu: 2
+    memory: 1Gi
+`),
+		},
+		wantErr: nil,
+	},
+	{
+		title:          "missing value file",
+		includeFilters: []string{"${BASEPATH}/values/"},
+		excludeFilters: []string{".tmpl"},
+		files: map[string][]byte{
+			"values/env1/file1.yaml": []byte(`k1: v1`),
+			"values/env1/coco.yaml": []byte(`
+type: environment
+name: name1
+values:
+  - file1.yaml
+  - typo.yaml
+`),
+		},
+		wantFiles: nil,
+		wantErr: fmt.Errorf(
+			"values file %q of %q does not exist: file does not exist",
+			"${BASEPATH}/values/env1/typo.yaml", "name1",
+		),
+	},
 	{
 		title:          "Unsupported coco type",
 		includeFilters: []string{"${BASEPATH}/values/", "${BASEPATH}/values2/"},
@@ -205,8 +341,12 @@ func (s *scenarioValueFiles) Test(t *testing.T) {
 	defer td.Cleanup(t)
 	tmpDir := td.Path()
 
-	got, err := readValueFiles(tmpDir, configFileName, s.includeFilters, []string{}, s.excludeFilters)
-	testfuncs.CheckErrs(t, s.wantErr, err)
+	got, err := readValueFiles(files.OS(), tmpDir, configFileName, s.includeFilters, []string{}, s.excludeFilters, nil)
+	wantErr := s.wantErr
+	if wantErr != nil {
+		wantErr = errors.New(strings.ReplaceAll(wantErr.Error(), "${BASEPATH}", tmpDir))
+	}
+	testfuncs.CheckErrs(t, wantErr, err)
 
 	s.CheckRes(t, tmpDir, got)
 }
@@ -0,0 +1,366 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+	gotemplate "text/template"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
+)
+
+const (
+	// defaultForEachKey is the key of the list elements that names the generated
+	// output if the template configuration does not specify one.
+	defaultForEachKey = "name"
+	// itemPlaceholder replaces the item values when output paths are rendered for
+	// the search of outputs of vanished items.
+	itemPlaceholder = "\x00"
+)
+
+// forEachItem is an element of the list that a template is rendered for (see the
+// forEach option of the template configuration).
+type forEachItem struct {
+	// key names the generated output
+	key string
+	// value holds the list element
+	value interface{}
+}
+
+func (t template) forEach() bool {
+	return t.config != nil && t.config.ForEach != ""
+}
+
+func (t template) forEachKey() string {
+	if t.config == nil || t.config.ForEachKey == "" {
+		return defaultForEachKey
+	}
+	return t.config.ForEachKey
+}
+
+// forEachItems returns the items the template must be rendered for with the
+// values of an environment. Templates without forEach option are rendered once
+// (represented by a nil item). A missing list results in no items.
+func (t template) forEachItems(values interface{}) ([]*forEachItem, error) {
+	if !t.forEach() {
+		return []*forEachItem{nil}, nil
+	}
+	list, err := lookupList(values, t.config.ForEach)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*forEachItem, 0, len(list))
+	keys := make(map[string]bool, len(list))
+	for i, el := range list {
+		key, err := itemKey(el, t.forEachKey())
+		if err != nil {
+			return nil, fmt.Errorf("element %d of %q: %w", i, t.config.ForEach, err)
+		}
+		if keys[key] {
+			return nil, fmt.Errorf("element %d of %q: duplicate key %q", i, t.config.ForEach, key)
+		}
+		keys[key] = true
+		res = append(res, &forEachItem{key: key, value: el})
+	}
+	return res, nil
+}
+
+// lookupList returns the list at the dot separated path (e.g. ".tenants" or
+// "platform.tenants") in the values.
+func lookupList(values interface{}, path string) ([]interface{}, error) {
+	current := values
+	for _, k := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("forEach path %q: %q is not located in a map", path, k)
+		}
+		if current, ok = m[k]; !ok {
+			return []interface{}{}, nil
+		}
+	}
+	if current == nil {
+		return []interface{}{}, nil
+	}
+	list, ok := current.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("forEach path %q does not hold a list", path)
+	}
+	return list, nil
+}
+
+// itemKey returns the value of key for map elements and the element itself for
+// scalar elements.
+func itemKey(el interface{}, key string) (string, error) {
+	switch v := el.(type) {
+	case map[string]interface{}:
+		k, ok := v[key]
+		if !ok || k == nil {
+			return "", fmt.Errorf("key %q is missing", key)
+		}
+		return validItemKey(fmt.Sprint(k))
+	case []interface{}, nil:
+		return "", fmt.Errorf("elements must be maps or scalars")
+	default:
+		return validItemKey(fmt.Sprint(v))
+	}
+}
+
+func validItemKey(key string) (string, error) {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`+itemPlaceholder) {
+		return "", fmt.Errorf("%q cannot be used in output paths", key)
+	}
+	return key, nil
+}
+
+// renderData returns the data the template is rendered with: the values of the
+// environment for templates without forEach option, and otherwise the values
+// extended by the key "Coco" that holds the environment name
+// (.Coco.Environment.Name) and the list element (.Coco.Item).
+func renderData(env string, values interface{}, item *forEachItem) interface{} {
+	if item == nil {
+		return values
+	}
+	res := map[string]interface{}{}
+	if m, ok := values.(map[string]interface{}); ok {
+		for k, v := range m {
+			res[k] = v
+		}
+	}
+	res["Coco"] = map[string]interface{}{
+		"Environment": map[string]interface{}{"Name": env},
+		"Item":        item.value,
+	}
+	return res
+}
+
+// staleOutputs returns the generated outputs of the template for the environment
+// that belong to list elements which are not part of the items anymore. These are
+// all files that match the output path of the template with arbitrary item values
+// but are not part of the current outputs. Files that other templates or
+// environments generate or may have generated (see outputClaims) are skipped.
+func (t template) staleOutputs(
+	fsys files.FS, env string, values interface{}, items []*forEachItem, outputs map[string]bool,
+	claims outputClaims,
+) ([]string, error) {
+	glob, re, err := t.stalePattern(env, values, items)
+	if err != nil || re == nil {
+		return []string{}, err
+	}
+	matches, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return nil, err
+	}
+	res := []string{}
+	for _, m := range matches {
+		if !outputs[m] && re.MatchString(m) && !claims.claimed(m, t.source, env) {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+// stalePattern returns a glob and a regular expression that match the output
+// paths of the template for the environment with arbitrary item values. The
+// regular expression is nil if the output path does not depend on the items.
+func (t template) stalePattern(
+	env string, values interface{}, items []*forEachItem,
+) (string, *regexp.Regexp, error) {
+	pattern, err := filePath(env, t, values, &forEachItem{itemPlaceholder, t.itemPlaceholders(items)})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to determine outputs of removed elements: %w", err)
+	}
+	parts := strings.Split(pattern, itemPlaceholder)
+	if len(parts) < 2 {
+		return "", nil, nil
+	}
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	re, err := regexp.Compile(fmt.Sprintf("^%s$", strings.Join(parts, "[^/]+")))
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.ReplaceAll(pattern, itemPlaceholder, "*"), re, nil
+}
+
+// outputClaims holds the outputs of all templates for all environments and the
+// patterns of the outputs of their forEach elements. Stale outputs are only
+// removed if no other template or environment generates or may have generated
+// them: e.g. the pattern "*-prod.yaml" of the environment prod also matches the
+// outputs of the environment eu-prod.
+type outputClaims struct {
+	outputs  map[string]bool
+	patterns []claimPattern
+}
+
+type claimPattern struct {
+	source string
+	env    string
+	re     *regexp.Regexp
+}
+
+// claimOutputs computes the output claims of the templates for the environments.
+// Errors of forEach options and output paths are left to the rendering.
+func claimOutputs(tmpls map[string][]template, vals map[string]interface{}) outputClaims {
+	res := outputClaims{outputs: map[string]bool{}, patterns: []claimPattern{}}
+	for _, location := range maputils.KeysSorted(tmpls) {
+		for _, tmpl := range tmpls[location] {
+			for _, env := range maputils.KeysSorted(vals) {
+				items, err := tmpl.forEachItems(vals[env])
+				if err != nil {
+					continue
+				}
+				for _, item := range items {
+					if fp, _, err := outputPaths(env, tmpl, vals[env], item); err == nil {
+						res.outputs[fp] = true
+					}
+				}
+				if !tmpl.forEach() {
+					continue
+				}
+				if _, re, err := tmpl.stalePattern(env, vals[env], items); err == nil && re != nil {
+					res.patterns = append(res.patterns, claimPattern{source: tmpl.source, env: env, re: re})
+				}
+			}
+		}
+	}
+	return res
+}
+
+// claimed reports whether fp is an output of any template and environment or
+// matches the forEach outputs of another template or environment than the
+// template source for the environment env.
+func (c outputClaims) claimed(fp, source, env string) bool {
+	if c.outputs[fp] {
+		return true
+	}
+	for _, p := range c.patterns {
+		if (p.source != source || p.env != env) && p.re.MatchString(fp) {
+			return true
+		}
+	}
+	return false
+}
+
+// itemPlaceholders returns a list element that holds the placeholder for all
+// fields that the output path of the template references (and all keys of the
+// current items), so the path can be rendered even if no current element sets
+// them.
+func (t template) itemPlaceholders(items []*forEachItem) interface{} {
+	refs := [][]string{}
+	for _, p := range []string{t.basepath, t.subpath, t.namePrefix, t.outputPattern()} {
+		if !isTemplated(p) {
+			continue
+		}
+		// invalid paths are reported when the outputs are rendered
+		if parsed, err := gotemplate.New(p).Funcs(tmplFuncs()).Parse(p); err == nil {
+			refs = append(refs, itemRefs(parsed)...)
+		}
+	}
+	res := map[string]interface{}{t.forEachKey(): itemPlaceholder}
+	scalar := false
+	for _, r := range refs {
+		if len(r) == 0 {
+			scalar = true
+			continue
+		}
+		setPlaceholder(res, r)
+	}
+	if scalar && len(res) == 1 {
+		return itemPlaceholder
+	}
+	for _, item := range items {
+		if m, ok := item.value.(map[string]interface{}); ok {
+			for k := range m {
+				if _, ok := res[k]; !ok {
+					res[k] = itemPlaceholder
+				}
+			}
+		}
+	}
+	return res
+}
+
+func (t template) outputPattern() string {
+	if t.config == nil {
+		return ""
+	}
+	return t.config.Output
+}
+
+// setPlaceholder sets the placeholder at the path in m. Nested fields turn
+// placeholders of their parents into maps.
+func setPlaceholder(m map[string]interface{}, path []string) {
+	for _, k := range path[:len(path)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[k] = next
+		}
+		m = next
+	}
+	if _, ok := m[path[len(path)-1]].(map[string]interface{}); !ok {
+		m[path[len(path)-1]] = itemPlaceholder
+	}
+}
+
+// removeOutput deletes a generated file and all its parent folders that are
+// empty afterwards (up to the template location).
+func removeOutput(fsys files.FS, path, basepath string) error {
+	if err := fsys.Remove(path); err != nil {
+		return err
+	}
+	for dir := filepath.Dir(path); dir != basepath && strings.HasPrefix(dir, basepath); dir = filepath.Dir(dir) {
+		entries, err := fsys.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		if len(entries) != 0 {
+			return nil
+		}
+		if err := fsys.Remove(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneOutputs removes the outputs of list elements that have been removed from
+// the values of the environment. Only generated files that coco may overwrite
+// are removed (see versionIncompatible).
+func pruneOutputs(
+	c *ctx, fsys files.FS, env string, tmpl template, values interface{},
+	items []*forEachItem, outputs map[string]bool, claims outputClaims,
+	v *version.Version, takeControl bool,
+) error {
+	stale, err := tmpl.staleOutputs(fsys, env, values, items, outputs, claims)
+	if err != nil {
+		return err
+	}
+	for _, fp := range stale {
+		content, err := readFile(fsys, fp)
+		if err != nil {
+			return err
+		}
+		if !reVersion.Match(content) {
+			continue
+		}
+		if !takeControl && versionIncompatible(content, v.SemVer) {
+			continue
+		}
+		if err := removeOutput(fsys, fp, tmpl.staticLocation()); err != nil {
+			return err
+		}
+		c.addReport("removed output of a removed forEach element", log.Info(), log.Context{"file": fp})
+	}
+	return nil
+}
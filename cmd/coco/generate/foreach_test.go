@@ -0,0 +1,179 @@
+package generate
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestForEachItems(t *testing.T) {
+	for _, s := range []struct {
+		title   string
+		config  *inputfile.Coco
+		values  interface{}
+		want    []*forEachItem
+		wantErr error
+	}{
+		{
+			title:  "no forEach",
+			config: nil,
+			values: map[string]interface{}{},
+			want:   []*forEachItem{nil},
+		},
+		{
+			title:  "nested list with custom key",
+			config: &inputfile.Coco{ForEach: ".platform.tenants", ForEachKey: "id"},
+			values: map[string]interface{}{"platform": map[string]interface{}{
+				"tenants": []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}},
+			}},
+			want: []*forEachItem{
+				{key: "1", value: map[string]interface{}{"id": 1}},
+				{key: "2", value: map[string]interface{}{"id": 2}},
+			},
+		},
+		{
+			title:  "scalar elements",
+			config: &inputfile.Coco{ForEach: "regions"},
+			values: map[string]interface{}{"regions": []interface{}{"eu", "us"}},
+			want:   []*forEachItem{{key: "eu", value: "eu"}, {key: "us", value: "us"}},
+		},
+		{
+			title:  "missing list",
+			config: &inputfile.Coco{ForEach: ".tenants"},
+			values: map[string]interface{}{},
+			want:   []*forEachItem{},
+		},
+		{
+			title:   "no list",
+			config:  &inputfile.Coco{ForEach: ".tenants"},
+			values:  map[string]interface{}{"tenants": "a"},
+			wantErr: errors.New(`forEach path ".tenants" does not hold a list`),
+		},
+		{
+			title:   "missing key",
+			config:  &inputfile.Coco{ForEach: ".tenants"},
+			values:  map[string]interface{}{"tenants": []interface{}{map[string]interface{}{"id": "a"}}},
+			wantErr: errors.New(`element 0 of ".tenants": key "name" is missing`),
+		},
+		{
+			title:   "duplicate key",
+			config:  &inputfile.Coco{ForEach: ".tenants"},
+			values:  map[string]interface{}{"tenants": []interface{}{"a", "a"}},
+			wantErr: errors.New(`element 1 of ".tenants": duplicate key "a"`),
+		},
+		{
+			title:   "invalid key",
+			config:  &inputfile.Coco{ForEach: ".tenants"},
+			values:  map[string]interface{}{"tenants": []interface{}{"a/b"}},
+			wantErr: errors.New(`element 0 of ".tenants": "a/b" cannot be used in output paths`),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		got, err := template{config: s.config}.forEachItems(s.values)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		if s.wantErr == nil {
+			testfuncs.CheckEqualityInterface(t, s.want, got)
+		}
+	}
+}
+
+func TestStaleOutputs(t *testing.T) {
+	fsys := files.NewMemFS()
+	for _, f := range []string{
+		"/r/eu/a.yaml", "/r/eu/b.yaml", "/r/us/c.yaml", "/r/other.yaml",
+		"/s/c1/x.yaml", "/s/c1/y.yaml", "/s/c2/x.yaml",
+	} {
+		testfuncs.MustBeNil(t, fsys.MkdirAll(filepath.Dir(f), 0o755))
+		testfuncs.MustBeNil(t, fsys.WriteFile(f, nil, 0o644))
+	}
+	regions := &inputfile.Coco{
+		ForEach: ".tenants", ForEachKey: "id", Output: "{{ .Coco.Item.region }}/{{ .Coco.Item.id }}.yaml",
+	}
+	for _, s := range []struct {
+		title   string
+		tmpl    template
+		items   []*forEachItem
+		outputs map[string]bool
+		want    []string
+	}{
+		{
+			title:   "removed elements",
+			tmpl:    template{basepath: "/r", config: regions},
+			items:   []*forEachItem{{key: "a", value: map[string]interface{}{"id": "a", "region": "eu"}}},
+			outputs: map[string]bool{"/r/eu/a.yaml": true},
+			want:    []string{"/r/eu/b.yaml", "/r/us/c.yaml"},
+		},
+		{
+			title: "empty list",
+			tmpl:  template{basepath: "/r", config: regions},
+			items: []*forEachItem{},
+			want:  []string{"/r/eu/a.yaml", "/r/eu/b.yaml", "/r/us/c.yaml"},
+		},
+		{
+			title:   "elements without the referenced key",
+			tmpl:    template{basepath: "/r", config: regions},
+			items:   []*forEachItem{{key: "a", value: map[string]interface{}{"id": "a"}}},
+			outputs: map[string]bool{"/r/a.yaml": true},
+			want:    []string{"/r/eu/a.yaml", "/r/eu/b.yaml", "/r/us/c.yaml"},
+		},
+		{
+			title: "scalar elements",
+			tmpl: template{basepath: "/s", config: &inputfile.Coco{
+				ForEach: ".regions", Output: "{{ .Coco.Environment.Name }}/{{ .Coco.Item }}.yaml",
+			}},
+			items:   []*forEachItem{{key: "x", value: "x"}},
+			outputs: map[string]bool{"/s/c1/x.yaml": true},
+			want:    []string{"/s/c1/y.yaml"},
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		got, err := s.tmpl.staleOutputs(fsys, "c1", map[string]interface{}{}, s.items, s.outputs, outputClaims{})
+		testfuncs.MustBeNil(t, err)
+		testfuncs.CheckEqualityInterface(t, s.want, got)
+	}
+}
+
+func TestStaleOutputsOfOtherEnvironments(t *testing.T) {
+	fsys := files.NewMemFS()
+	for _, f := range []string{
+		"/r/t1-prod.yaml", "/r/t2-prod.yaml", "/r/t1-eu-prod.yaml", "/r/t3-eu-prod.yaml", "/r/app-prod.yaml",
+	} {
+		testfuncs.MustBeNil(t, fsys.MkdirAll(filepath.Dir(f), 0o755))
+		testfuncs.MustBeNil(t, fsys.WriteFile(f, nil, 0o644))
+	}
+	tenants := template{
+		source: "/r/{{ .Coco.Item.name }}-{{ .Coco.Environment.Name }}.tmpl", basepath: "/r",
+		namePrefix: "{{ .Coco.Item.name }}-{{ .Coco.Environment.Name }}",
+		config:     &inputfile.Coco{ForEach: ".tenants"},
+	}
+	app := template{source: "/r/app.tmpl", basepath: "/r", namePrefix: "app"}
+	vals := map[string]interface{}{
+		"prod":    map[string]interface{}{"tenants": []interface{}{map[string]interface{}{"name": "t1"}}},
+		"eu-prod": map[string]interface{}{"tenants": []interface{}{map[string]interface{}{"name": "t1"}}},
+	}
+	claims := claimOutputs(map[string][]template{"/r": {tenants, app}}, vals)
+
+	for _, s := range []struct {
+		env  string
+		want []string
+	}{
+		// t1-eu-prod.yaml is an output of eu-prod, app-prod.yaml an output of the
+		// other template, t3-eu-prod.yaml may be a stale output of eu-prod
+		{env: "prod", want: []string{"/r/t2-prod.yaml"}},
+		// t3-eu-prod.yaml also matches the outputs of prod
+		{env: "eu-prod", want: []string{}},
+	} {
+		t.Logf("test scenario: %s\n", s.env)
+		items, err := tenants.forEachItems(vals[s.env])
+		testfuncs.MustBeNil(t, err)
+		fp, err := filePath(s.env, tenants, vals[s.env], items[0])
+		testfuncs.MustBeNil(t, err)
+		got, err := tenants.staleOutputs(fsys, s.env, vals[s.env], items, map[string]bool{fp: true}, claims)
+		testfuncs.MustBeNil(t, err)
+		testfuncs.CheckEqualityInterface(t, s.want, got)
+	}
+}
@@ -1,16 +1,20 @@
 package generate
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/k8sschema"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
 )
 
 var (
 	renderer func(
-		string, []template, map[string]interface{},
-		chan<- renderReport, log.Level, string, *version.Version, bool,
+		string, []template, map[string]interface{}, outputClaims,
+		chan<- renderReport, log.Level, string, *version.Version, bool, bool, files.FS,
 	) = render
 )
 
@@ -23,46 +27,95 @@ var (
 //   - clusterValues: folder in which value files for file generation are located
 //   - envFilters: filters down the list of environments for which file generation is performed
 //   - folderFilters: filters down the list of template locations to specific sub-folders
+//   - ignoreFiles: names of the ignore files that are honoured when searching templates and
+//     value files (see files.FileRunner.IgnoreFiles)
 //   - version: coco version (for comparisons with the version in the existing generated files)
 //   - takeControl: overwrite to do file generation also on files that have a different version
+//   - failFast: stop rendering the templates of a location at the first error (otherwise
+//     rendering continues and all errors are reported)
 //   - logLvl: specifies the log level that will be used
+//   - hooks: repo-wide commands that run on the generated outputs and whether the hooks of
+//     the template configurations run
+//   - validator: validates the generated Kubernetes objects (nil disables the validation)
+//   - fsys: file system that templates and values are read from and generated files are
+//     written to (hooks always run on the files of the local disk)
+//   - out: redirects the generated files into a separate folder or archive (hooks run on
+//     the exported files) or writes them atomically
 func Generate(
 	basepath, templateIdentifier, persistenceFlag, configFileName string,
 	v *version.Version,
-	clusterValues, envFilters, folderFilters, excludeFolders []string,
-	logLvl log.Level, takeControl bool,
+	clusterValues, envFilters, folderFilters, excludeFolders, ignoreFiles []string,
+	logLvl log.Level, takeControl, failFast bool, hooks HookConfig,
+	validator *k8sschema.Validator,
+	fsys files.FS,
+	out Destination,
 ) error {
-	tmpls, err := findTemplates(basepath, templateIdentifier, folderFilters, excludeFolders)
+	for _, h := range hooks.Hooks {
+		if err := h.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := out.Validate(); err != nil {
+		return err
+	}
+	staging := out.stage(fsys)
+	tmpls, err := findTemplates(fsys, basepath, templateIdentifier, folderFilters, excludeFolders, ignoreFiles)
 	if err != nil {
 		return err
 	}
+	if err = readTemplateConfigs(fsys, tmpls, configFileName); err != nil {
+		return err
+	}
 
 	vals, err := readValueFiles(
+		fsys,
 		basepath,
 		configFileName,
 		clusterValues,
 		envFilters,
 		[]string{templateIdentifier},
+		ignoreFiles,
 	)
 	if err != nil {
 		return err
 	}
 
+	// templates render concurrently and must not write the same files
+	if err := outputCollisions(tmpls, vals); err != nil {
+		return err
+	}
+	// stale forEach outputs are only pruned if no template generates them for any
+	// environment, including the ones that the envFilters exclude
+	allVals := vals
+	if len(envFilters) > 0 {
+		allVals, err = readValueFiles(
+			fsys, basepath, configFileName, clusterValues, []string{}, []string{templateIdentifier}, ignoreFiles,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	claims := claimOutputs(tmpls, allVals)
+
 	reports := make(chan renderReport, len(tmpls))
 
 	// All template folders (or files) that have been found are rendered concurrently.
 	// Each concurrent process renders the template(s) for all specified environments
 	// (from the value files).
 	for name, tmpl := range tmpls {
-		go renderer(name, tmpl, vals, reports, logLvl, persistenceFlag, v, takeControl)
+		go renderer(name, tmpl, vals, claims, reports, logLvl, persistenceFlag, v, takeControl, failFast, staging)
 	}
-	return reportResults(reports)
+	return reportResults(staging, reports, hooks, validator, basepath, out)
 }
 
 // renderReport holds the aggregated result report of a render function call
-// If non-nil, it contains either warnings or error messages.
+// If non-nil, it contains either warnings or error messages. In addition, it
+// lists the generated outputs for running the post-render hooks and the files
+// that have been copied verbatim.
 type renderReport struct {
-	items []logItem
+	items   []logItem
+	outputs []generatedOutput
+	copies  []string
 }
 
 type logItem struct {
@@ -72,33 +125,142 @@ type logItem struct {
 	Context log.Context
 }
 
-// reportResults waits for the reports of all concurrent render function calls
-// and evaluates them. All results are sent to the logger and if the log level
-// is at Error level (2) or higher the reporter returns an error to the caller.
-func reportResults(reports chan renderReport) error {
+// reportResults waits for the reports of all concurrent render function calls,
+// validates the generated Kubernetes objects, writes the files of an atomic
+// generation or exports the generated files to a redirected output, runs the
+// post-render hooks on the generated outputs and evaluates the results.
+// All results are sent to the logger and if the log level is at Error level (2)
+// or higher the reporter returns an error to the caller.
+func reportResults(
+	fsys files.FS, reports chan renderReport, hooks HookConfig, validator *k8sschema.Validator, workingDir string,
+	out Destination,
+) error {
 	foundReports := []renderReport{}
+	outputs := []generatedOutput{}
+	copies := []string{}
 
+	ctx, stop := out.interruptible()
+	defer stop()
 	for i := 0; i < cap(reports); i++ {
-		r := <-reports
-		if len(r.items) > 0 {
-			foundReports = append(foundReports, r)
+		select {
+		case r := <-reports:
+			outputs = append(outputs, r.outputs...)
+			copies = append(copies, r.copies...)
+			if len(r.items) > 0 {
+				foundReports = append(foundReports, r)
+			}
+		case <-ctx.Done():
+			return errors.New("generation interrupted, no files have been changed")
 		}
 	}
 	close(reports)
 
-	if len(foundReports) > 0 {
-		errorsFound := false
-		for _, r := range foundReports {
-			for _, i := range r.items {
-				i.Context.Log(i.Msg, i.Level)
-				if i.Level.AsInt() >= 2 {
-					errorsFound = true
-				}
-			}
+	foundReports = append(foundReports, validateOutputs(fsys, outputs, validator)...)
+
+	if out.atomic() {
+		if err := summarize(foundReports); err != nil {
+			return fmt.Errorf("%w, no files have been changed", err)
+		}
+		if err := out.commit(ctx, fsys); err != nil {
+			return fmt.Errorf("failed to write the generated files, all changes have been rolled back: %w", err)
 		}
-		if errorsFound {
-			return fmt.Errorf("%d rendering errors encountered", len(foundReports))
+		stop()
+	}
+
+	hookDir := workingDir
+	if out.enabled() {
+		dir, cleanup, err := out.folder()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		if outputs, err = export(fsys, workingDir, dir, outputs, copies); err != nil {
+			return err
 		}
+		hookDir = dir
+	}
+	foundReports = append(foundReports, skippedTemplateHooks(outputs, hooks)...)
+	foundReports = append(
+		foundReports,
+		runHooks(hookRuns(outputs, hooks.Hooks, hooks.TemplateHooks), hooks.Workers, hookDir)...,
+	)
+	if out.Tar != "" {
+		if err := out.archive(hookDir); err != nil {
+			return fmt.Errorf("failed to write archive %q: %w", out.Tar, err)
+		}
+	}
+	return summarize(foundReports)
+}
+
+// summarize logs the reports and returns an error if any of them is at Error
+// level (2) or higher.
+func summarize(reports []renderReport) error {
+	errorsFound := logReports(reports)
+	if errorsFound > 0 {
+		for _, i := range errorSummary(reports) {
+			i.Context.Log(i.Msg, i.Level)
+		}
+		return fmt.Errorf("%d rendering errors encountered", errorsFound)
 	}
 	return nil
 }
+
+// errorSummary groups the report items at Error level (2) or higher by template
+// (or by file for errors that are not related to a template, e.g. of hooks) and
+// returns one item per group with the number of errors and the affected
+// environments.
+func errorSummary(reports []renderReport) []logItem {
+	type group struct {
+		errors int
+		envs   map[string]bool
+	}
+	groups := map[string]*group{}
+	for _, r := range reports {
+		for _, i := range r.items {
+			if i.Level.AsInt() < 2 {
+				continue
+			}
+			key, _ := i.Context["template"].(string)
+			if key == "" {
+				key, _ = i.Context["file"].(string)
+			}
+			g, ok := groups[key]
+			if !ok {
+				g = &group{envs: map[string]bool{}}
+				groups[key] = g
+			}
+			g.errors++
+			if env, ok := i.Context["environment"].(string); ok && env != "" {
+				g.envs[env] = true
+			}
+		}
+	}
+	res := make([]logItem, 0, len(groups))
+	for _, key := range maputils.KeysSorted(groups) {
+		g := groups[key]
+		res = append(res, logItem{
+			Msg:   "rendering failed",
+			Level: log.Error(),
+			Context: log.Context{
+				"source":       key,
+				"errors":       g.errors,
+				"environments": maputils.KeysSorted(g.envs),
+			},
+		})
+	}
+	return res
+}
+
+// logReports sends all report items to the logger and returns the number of
+// items at Error level (2) or higher.
+func logReports(reports []renderReport) (errorsFound int) {
+	for _, r := range reports {
+		for _, i := range r.items {
+			i.Context.Log(i.Msg, i.Level)
+			if i.Level.AsInt() >= 2 {
+				errorsFound++
+			}
+		}
+	}
+	return errorsFound
+}
@@ -3,10 +3,13 @@ package generate
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
 
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 	// The Generate function makes use of the general CLI logger. Hence its test
 	// needs to set it up correctly to test logging output as well.
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
@@ -311,6 +314,7 @@ func (s *scenarioGenerate) Test(t *testing.T) {
 	}
 
 	renderer = r.render
+	defer func() { renderer = render }()
 	err = Generate(
 		tmpDir,
 		s.tmplIdentifier,
@@ -321,8 +325,14 @@ func (s *scenarioGenerate) Test(t *testing.T) {
 		s.envFilters,
 		s.folderFilters,
 		s.exclFilters,
+		nil,
 		log.New("Debug"),
 		false,
+		false,
+		HookConfig{},
+		nil,
+		files.OS(),
+		Destination{},
 	)
 	testfuncs.CheckErrs(t, s.wantErr, err)
 
@@ -361,15 +371,16 @@ func (rm *renderMock) found(name string) bool {
 }
 
 func (rm *renderMock) render(
-	name string, tmpls []template, vals map[string]interface{},
+	name string, tmpls []template, vals map[string]interface{}, claims outputClaims,
 	reportChan chan<- renderReport,
 	logLvl log.Level,
 	persistenceComment string, v *version.Version,
-	takeControl bool,
+	takeControl, failFast bool,
+	fsys files.FS,
 ) {
 	rm.lock.Lock()
 	defer rm.lock.Unlock()
-	reportChan <- renderReport{rm.report}
+	reportChan <- renderReport{items: rm.report}
 	want, ok := rm.want[name]
 	if !ok {
 		rm.t.Errorf("unknown template name found: \ngot = \"%+v\"", name)
@@ -393,3 +404,83 @@ func (rm *renderMock) render(
 		rm.t.Fail()
 	}
 }
+
+func TestErrorSummary(t *testing.T) {
+	reports := []renderReport{
+		{items: []logItem{
+			{Msg: "a", Level: log.Error(), Context: log.Context{"template": "t1", "environment": "c2"}},
+			{Msg: "b", Level: log.Warn(), Context: log.Context{"template": "t1", "environment": "c3"}},
+			{Msg: "c", Level: log.Error(), Context: log.Context{"template": "t1", "environment": "c1"}},
+		}},
+		{items: []logItem{
+			{Msg: "d", Level: log.Error(), Context: log.Context{"hook": "h", "file": "f", "environment": "c1"}},
+		}},
+	}
+	testfuncs.CheckEqualityInterface(t, []logItem{
+		{
+			Msg: "rendering failed", Level: log.Error(),
+			Context: log.Context{"source": "f", "errors": 1, "environments": []string{"c1"}},
+		},
+		{
+			Msg: "rendering failed", Level: log.Error(),
+			Context: log.Context{"source": "t1", "errors": 2, "environments": []string{"c1", "c2"}},
+		},
+	}, errorSummary(reports))
+}
+
+func TestGenerateFileSystems(t *testing.T) {
+	if err := log.Init(log.Warn(), "", true); err != nil {
+		zap.S().Fatal(err)
+	}
+	tree := map[string]string{
+		"values/c1/coco.yaml":   "type: environment\nname: c1\nvalues:\n  - values.yaml\n",
+		"values/c1/values.yaml": "replicas: 2\nname: app\n",
+		"svc/.tmpl/deploy.yaml": "replicas: {{ .replicas }}\n",
+		"svc/name.tmpl":         "name: {{ .name }}\n",
+	}
+	wantOutputs := map[string]string{
+		"svc/c1/deploy.yaml": "replicas: 2\n",
+		"svc/name-c1.yaml":   "name: app\n",
+	}
+	header := fmt.Sprintf(genFileHeader, 0, 0)
+
+	disk := t.TempDir()
+	for name, content := range tree {
+		p := filepath.Join(disk, name)
+		testfuncs.MustBeNil(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		testfuncs.MustBeNil(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	mem := files.NewMemFS()
+	for name, content := range tree {
+		p := filepath.Join("/repo", name)
+		testfuncs.MustBeNil(t, mem.MkdirAll(filepath.Dir(p), 0o755))
+		testfuncs.MustBeNil(t, mem.WriteFile(p, []byte(content), 0o644))
+	}
+
+	for _, s := range []struct {
+		title    string
+		basepath string
+		fsys     files.FS
+	}{
+		{title: "in memory", basepath: "/repo", fsys: mem},
+		{title: "overlay on disk", basepath: disk, fsys: files.NewOverlay(files.NewMemFS(), files.OS())},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		err := Generate(
+			s.basepath, ".tmpl", "HumanInput", "coco.yaml", &version.Version{},
+			[]string{filepath.Join(s.basepath, "values") + "/"}, []string{}, []string{}, []string{}, nil,
+			log.Warn(), false, false, HookConfig{}, nil, s.fsys, Destination{},
+		)
+		testfuncs.MustBeNil(t, err)
+		for name, want := range wantOutputs {
+			got, err := s.fsys.ReadFile(filepath.Join(s.basepath, name))
+			testfuncs.MustBeNil(t, err)
+			testfuncs.CheckEqualityInterface(t, header+want, string(got))
+		}
+	}
+	// the overlay does not touch the disk
+	_, err := os.Stat(filepath.Join(disk, "svc/c1"))
+	if !os.IsNotExist(err) {
+		t.Errorf("generated files have been written to the disk: %v", err)
+	}
+}
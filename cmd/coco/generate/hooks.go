@@ -0,0 +1,192 @@
+package generate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	gotemplate "text/template"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/exec"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
+)
+
+var newExec = exec.New
+
+// HookConfig holds the repo-wide post-render hooks, which run in addition to the
+// hooks of the template configurations, and the maximal number of hooks that run
+// concurrently (number of CPUs if not positive). The hooks of the template
+// configurations only run with TemplateHooks, since every configuration file of
+// the repository can declare them.
+type HookConfig struct {
+	Hooks         []inputfile.Hook
+	Workers       int
+	TemplateHooks bool
+}
+
+// generatedOutput holds a file that has been generated (or confirmed to be up to
+// date) by the render function together with the hooks of its template.
+type generatedOutput struct {
+	env   string
+	file  string
+	dir   string
+	hooks []inputfile.Hook
+}
+
+func (t template) hooks() []inputfile.Hook {
+	if t.config == nil {
+		return []inputfile.Hook{}
+	}
+	return t.config.Hooks
+}
+
+// hookRun is a single execution of a hook on a generated file or directory.
+type hookRun struct {
+	hook inputfile.Hook
+	path string
+	env  string
+}
+
+// hookRuns returns the hook runs for all generated outputs. Hooks with file scope
+// run once per generated file and hooks with directory scope once per generated
+// directory. The hooks of the outputs' templates are only part of the runs with
+// templateHooks.
+func hookRuns(outputs []generatedOutput, repoHooks []inputfile.Hook, templateHooks bool) []hookRun {
+	seen := map[string]bool{}
+	res := []hookRun{}
+	for _, o := range outputs {
+		hooks := append([]inputfile.Hook{}, repoHooks...)
+		if templateHooks {
+			hooks = append(hooks, o.hooks...)
+		}
+		for _, h := range hooks {
+			path := o.file
+			if h.Scope == inputfile.HookScopeDirectory {
+				path = o.dir
+			}
+			id := strings.Join(append([]string{h.Scope, path}, h.Command...), "\x00")
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			res = append(res, hookRun{hook: h, path: path, env: o.env})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].path < res[j].path
+	})
+	return res
+}
+
+// skippedTemplateHooks returns a warning if the templates of the outputs have
+// hooks that do not run since TemplateHooks is not set.
+func skippedTemplateHooks(outputs []generatedOutput, hooks HookConfig) []renderReport {
+	if hooks.TemplateHooks {
+		return []renderReport{}
+	}
+	skipped := map[string]bool{}
+	for _, o := range outputs {
+		for _, h := range o.hooks {
+			skipped[h.Title()] = true
+		}
+	}
+	if len(skipped) == 0 {
+		return []renderReport{}
+	}
+	return []renderReport{{items: []logItem{{
+		Msg:     "hooks of template configurations are skipped (enable them with --run-hooks)",
+		Level:   log.Warn(),
+		Context: log.Context{"hooks": strings.Join(maputils.KeysSorted(skipped), ", ")},
+	}}}}
+}
+
+// runHooks runs the hooks in a bounded pool of workers and returns a report for
+// every failed run.
+func runHooks(runs []hookRun, workers int, workingDir string) []renderReport {
+	if len(runs) == 0 {
+		return []renderReport{}
+	}
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	e := newExec(context.Background(), log.Sugar, workingDir, exec.Public(os.Environ()...)...)
+
+	jobs := make(chan hookRun)
+	results := make(chan renderReport, len(runs))
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				if report, failed := r.run(e); failed {
+					results <- report
+				}
+			}
+		}()
+	}
+	for _, r := range runs {
+		jobs <- r
+	}
+	close(jobs)
+	wg.Wait()
+	close(results)
+
+	res := make([]renderReport, 0, len(results))
+	for r := range results {
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return fmt.Sprint(res[i].items[0].Context["file"]) < fmt.Sprint(res[j].items[0].Context["file"])
+	})
+	return res
+}
+
+func (r hookRun) run(e exec.Exec) (report renderReport, failed bool) {
+	c := log.Context{"hook": r.hook.Title(), "file": r.path, "environment": r.env}
+	args, err := r.args()
+	if err == nil {
+		var out []byte
+		out, err = e.Command(args[0], exec.Public(args[1:]...)...).CombinedOutput()
+		if err == nil {
+			return renderReport{}, false
+		}
+		c["output"] = strings.TrimSpace(string(out))
+	}
+	c["error"] = err.Error()
+	return renderReport{items: []logItem{{Msg: "hook failed", Level: log.Error(), Context: c}}}, true
+}
+
+// args renders the command of the hook with the path of the generated file or
+// directory (.Path) and the environment name (.Environment). If the command does
+// not use any template action, the path is appended as last argument.
+func (r hookRun) args() ([]string, error) {
+	data := map[string]string{"Path": r.path, "Environment": r.env}
+	res := make([]string, 0, len(r.hook.Command)+1)
+	templated := false
+	for _, a := range r.hook.Command {
+		if !isTemplated(a) {
+			res = append(res, a)
+			continue
+		}
+		templated = true
+		t, err := gotemplate.New("hook").Option("missingkey=error").Parse(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hook argument %q: %w", a, err)
+		}
+		var arg strings.Builder
+		if err := t.Execute(&arg, data); err != nil {
+			return nil, fmt.Errorf("failed to render hook argument %q: %w", a, err)
+		}
+		res = append(res, arg.String())
+	}
+	if !templated {
+		res = append(res, r.path)
+	}
+	return res, nil
+}
@@ -0,0 +1,103 @@
+package generate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestHookRuns(t *testing.T) {
+	lint := inputfile.Hook{Command: []string{"yamllint"}}
+	build := inputfile.Hook{Command: []string{"kustomize", "build"}, Scope: inputfile.HookScopeDirectory}
+	outputs := []generatedOutput{
+		{env: "c1", file: "base/c1/a.yaml", dir: "base/c1", hooks: []inputfile.Hook{build}},
+		{env: "c1", file: "base/c1/b.yaml", dir: "base/c1", hooks: []inputfile.Hook{build}},
+		{env: "c2", file: "base/c2.yaml", dir: "base"},
+	}
+	got := hookRuns(outputs, []inputfile.Hook{lint}, true)
+	want := []hookRun{
+		{hook: build, path: "base/c1", env: "c1"},
+		{hook: lint, path: "base/c1/a.yaml", env: "c1"},
+		{hook: lint, path: "base/c1/b.yaml", env: "c1"},
+		{hook: lint, path: "base/c2.yaml", env: "c2"},
+	}
+	testfuncs.CheckEqualityInterface(t, want, got)
+
+	// the hooks of the template configurations only run on request
+	got = hookRuns(outputs, []inputfile.Hook{lint}, false)
+	testfuncs.CheckEqualityInterface(t, want[1:], got)
+	testfuncs.CheckEqualityInterface(t, []renderReport{{items: []logItem{{
+		Msg:     "hooks of template configurations are skipped (enable them with --run-hooks)",
+		Level:   log.Warn(),
+		Context: log.Context{"hooks": "kustomize build"},
+	}}}}, skippedTemplateHooks(outputs, HookConfig{}))
+	testfuncs.CheckEqualityInterface(
+		t, []renderReport{}, skippedTemplateHooks(outputs, HookConfig{TemplateHooks: true}),
+	)
+}
+
+func TestHookArgs(t *testing.T) {
+	for _, s := range []struct {
+		title   string
+		command []string
+		want    []string
+		wantErr error
+	}{
+		{
+			title:   "path appended",
+			command: []string{"yamllint", "-s"},
+			want:    []string{"yamllint", "-s", "out/c1.yaml"},
+		},
+		{
+			title:   "templated path",
+			command: []string{"validate", "--file={{ .Path }}", "--env", "{{ .Environment }}"},
+			want:    []string{"validate", "--file=out/c1.yaml", "--env", "c1"},
+		},
+		{
+			title:   "unknown key",
+			command: []string{"validate", "{{ .File }}"},
+			wantErr: errors.New(
+				`failed to render hook argument "{{ .File }}": template: hook:1:3: ` +
+					`executing "hook" at <.File>: map has no entry for key "File"`,
+			),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		r := hookRun{hook: inputfile.Hook{Command: s.command}, path: "out/c1.yaml", env: "c1"}
+		got, err := r.args()
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		if s.wantErr == nil {
+			testfuncs.CheckEqualityInterface(t, s.want, got)
+		}
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	if err := log.Init(log.Debug(), "", true); err != nil {
+		t.Fatalf("unable to initialize logger: %v", err)
+	}
+	runs := []hookRun{
+		{hook: inputfile.Hook{Command: []string{"true"}}, path: "a.yaml", env: "c1"},
+		{
+			hook: inputfile.Hook{Name: "validator", Command: []string{"sh", "-c", "echo invalid {{ .Path }}; exit 1"}},
+			path: "b.yaml", env: "c1",
+		},
+		{hook: inputfile.Hook{Command: []string{"true"}}, path: "c.yaml", env: "c2"},
+	}
+	got := runHooks(runs, 2, "")
+	want := []renderReport{{items: []logItem{{
+		Msg:   "hook failed",
+		Level: log.Error(),
+		Context: log.Context{
+			"hook":        "validator",
+			"file":        "b.yaml",
+			"environment": "c1",
+			"output":      "invalid b.yaml",
+			"error":       "exit status 1",
+		},
+	}}}}
+	testfuncs.CheckEqualityInterface(t, want, got)
+}
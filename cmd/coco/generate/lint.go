@@ -0,0 +1,427 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	gotemplate "text/template"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/yamlfile"
+	"gopkg.in/yaml.v3"
+)
+
+// noValue is rendered by go templates for keys that are missing in the values.
+const noValue = "<no value>"
+
+// Lint checks all templates and value files without writing any file. Every
+// template is parsed and rendered for every environment. The following problems
+// are reported:
+//   - templates that cannot be parsed or rendered (error)
+//   - rendered yaml files that cannot be parsed (error)
+//   - referenced keys that are missing in some environments (error)
+//   - value keys that no template references (warning, only reported if no
+//     folder filters are set since all templates must be known)
+//   - values that are shadowed in a values chain without effect (warning)
+//
+// The inputs correspond to the inputs of Generate.
+func Lint(
+	basepath, templateIdentifier, configFileName string,
+	clusterValues, envFilters, folderFilters, excludeFolders, ignoreFiles []string,
+	fsys files.FS,
+) error {
+	tmpls, err := findTemplates(fsys, basepath, templateIdentifier, folderFilters, excludeFolders, ignoreFiles)
+	if err != nil {
+		return err
+	}
+	if err = readTemplateConfigs(fsys, tmpls, configFileName); err != nil {
+		return err
+	}
+	envs, err := inputfile.ReadEnvironments(
+		fsys,
+		basepath,
+		configFileName,
+		clusterValues,
+		envFilters,
+		[]string{templateIdentifier},
+		ignoreFiles,
+	)
+	if err != nil {
+		return err
+	}
+
+	locations := make([]string, 0, len(tmpls))
+	for l := range tmpls {
+		locations = append(locations, l)
+	}
+	sort.Strings(locations)
+
+	reports := []renderReport{}
+	refs := []valueRef{}
+	for _, l := range locations {
+		for _, tmpl := range tmpls[l] {
+			r, tmplRefs := lintTemplate(fsys, tmpl, envs)
+			refs = append(refs, tmplRefs...)
+			if len(r.items) > 0 {
+				reports = append(reports, r)
+			}
+		}
+	}
+	if len(folderFilters) == 0 && len(excludeFolders) == 0 {
+		if r := unusedValues(envs, refs); len(r.items) > 0 {
+			reports = append(reports, r)
+		}
+	}
+	r, err := shadowedValues(fsys, envs)
+	if err != nil {
+		return err
+	}
+	if len(r.items) > 0 {
+		reports = append(reports, r)
+	}
+
+	if errorsFound := logReports(reports); errorsFound > 0 {
+		return fmt.Errorf("%d lint errors encountered", errorsFound)
+	}
+	return nil
+}
+
+// lintTemplate renders the template for all environments and checks its
+// references to the values. It returns the findings and all references of the
+// template (including the references of its output path and forEach option).
+func lintTemplate(
+	fsys files.FS, tmpl template, envs map[string]inputfile.Environment,
+) (renderReport, []valueRef) {
+	report := renderReport{items: []logItem{}}
+	add := func(msg string, lvl log.Level, c log.Context) {
+		c["template"] = tmpl.source
+		report.items = append(report.items, logItem{Msg: msg, Level: lvl, Context: c})
+	}
+
+	content, raw, err := tmpl.read(fsys)
+	if err != nil {
+		add("template configuration error", log.Error(), log.Context{"error": err.Error()})
+		return report, []valueRef{}
+	}
+	if raw {
+		return report, tmpl.pathRefs()
+	}
+	p := parser{}
+	if err := p.parse(tmpl.source, content, tmpl.delimiters()); err != nil {
+		add("template syntax error", log.Error(), log.Context{"error": err.Error()})
+		return report, []valueRef{}
+	}
+	refs := collectRefs(p.tmpl)
+	pathRefs := tmpl.pathRefs()
+	yamlOpts, err := tmpl.yamlSettings()
+	if err != nil {
+		add("template configuration error", log.Error(), log.Context{"error": err.Error()})
+		return report, append(refs, pathRefs...)
+	}
+
+	missing := missingRefs(refs, envs)
+	for _, m := range missing {
+		add("referenced key is missing", log.Error(), log.Context{
+			"key":          m.ref.key(),
+			"location":     m.ref.location,
+			"environments": strings.Join(m.envs, ", "),
+		})
+	}
+	envsWithMissingRefs := map[string]bool{}
+	for _, m := range missing {
+		for _, e := range m.envs {
+			envsWithMissingRefs[e] = true
+		}
+	}
+
+	for _, env := range maputils.KeysSorted(envs) {
+		values := envs[env].Values
+		items, err := tmpl.forEachItems(values)
+		if err != nil {
+			add("forEach error", log.Error(), log.Context{"environment": env, "error": err.Error()})
+			continue
+		}
+		for _, item := range items {
+			c := log.Context{"environment": env}
+			if item != nil {
+				c["item"] = item.key
+			}
+			fp, _, err := outputPaths(env, tmpl, values, item)
+			if err != nil {
+				c["error"] = err.Error()
+				add("output path error", log.Error(), c)
+				continue
+			}
+			c["file"] = fp
+			generated, err := p.execute(renderData(env, values, item))
+			if err != nil {
+				c["error"] = err.Error()
+				add("render template error", log.Error(), c)
+				continue
+			}
+			if bytes.Contains(generated, []byte(noValue)) && !envsWithMissingRefs[env] {
+				add("rendered output contains "+noValue, log.Warn(), c)
+			}
+			if filepath.Ext(fp) != ".yaml" {
+				continue
+			}
+			if _, err := yamlfile.New(generated, yamlOpts...); err != nil {
+				c["error"] = err.Error()
+				add("rendered output is no valid yaml", log.Error(), c)
+			}
+		}
+	}
+	return report, append(refs, pathRefs...)
+}
+
+// pathRefs returns the references of the output path and of the forEach option
+// of the template.
+func (t template) pathRefs() []valueRef {
+	res := []valueRef{}
+	paths := []string{t.namePrefix, t.subpath}
+	if t.config != nil {
+		paths = append(paths, t.config.Output)
+		if t.forEach() {
+			res = append(res, valueRef{path: strings.Split(strings.TrimPrefix(t.config.ForEach, "."), ".")})
+		}
+	}
+	for _, p := range paths {
+		if !isTemplated(p) {
+			continue
+		}
+		parsed, err := gotemplate.New(p).Funcs(tmplFuncs()).Parse(p)
+		if err != nil {
+			// reported when the output path is rendered
+			continue
+		}
+		res = append(res, collectRefs(parsed)...)
+	}
+	return res
+}
+
+type missingRef struct {
+	ref  valueRef
+	envs []string
+}
+
+// missingRefs returns the mandatory references that are not set in all
+// environments that render them (one entry per key).
+func missingRefs(refs []valueRef, envs map[string]inputfile.Environment) []missingRef {
+	res := []missingRef{}
+	found := map[string]int{}
+	for _, r := range refs {
+		if r.optional || len(r.path) == 0 {
+			continue
+		}
+		for _, env := range maputils.KeysSorted(envs) {
+			values := envs[env].Values
+			if _, ok := lookupPath(values, r.path); ok || !guardsSet(values, r.guards) {
+				continue
+			}
+			i, ok := found[r.key()]
+			if !ok {
+				i = len(res)
+				found[r.key()] = i
+				res = append(res, missingRef{ref: r, envs: []string{}})
+			}
+			if !contains(res[i].envs, env) {
+				res[i].envs = append(res[i].envs, env)
+			}
+		}
+	}
+	return res
+}
+
+func guardsSet(values interface{}, guards [][]string) bool {
+	for _, g := range guards {
+		if !isSet(values, g) {
+			return false
+		}
+	}
+	return true
+}
+
+// unusedValues reports the keys of the values that are not referenced by any
+// template. A key counts as used if it or one of its parents or children is
+// referenced.
+func unusedValues(envs map[string]inputfile.Environment, refs []valueRef) renderReport {
+	used := map[string]bool{}
+	usedParents := map[string]bool{}
+	for _, r := range refs {
+		used[r.key()] = true
+		for i := range r.path {
+			usedParents[strings.Join(r.path[:i], ".")] = true
+		}
+	}
+	isUsed := func(path []string) bool {
+		if usedParents[strings.Join(path, ".")] {
+			return true
+		}
+		for i := range path {
+			if used[strings.Join(path[:i+1], ".")] {
+				return true
+			}
+		}
+		return used[""]
+	}
+
+	unused := map[string][]string{}
+	for _, env := range maputils.KeysSorted(envs) {
+		for _, l := range leaves(envs[env].Values, []string{}) {
+			if !isUsed(l.path) {
+				k := strings.Join(l.path, ".")
+				unused[k] = append(unused[k], env)
+			}
+		}
+	}
+	report := renderReport{items: []logItem{}}
+	for _, k := range maputils.KeysSorted(unused) {
+		report.items = append(report.items, logItem{
+			Msg:   "value is not used by any template",
+			Level: log.Warn(),
+			Context: log.Context{
+				"key":          k,
+				"environments": strings.Join(unused[k], ", "),
+			},
+		})
+	}
+	return report
+}
+
+// valueSetting is a key that is set in a value file.
+type valueSetting struct {
+	file string
+	key  string
+}
+
+// shadowedValues reports values that have no effect since they are overridden
+// in all value chains they are part of, and values that override another value
+// with the same value. Lists are skipped since they are merged according to the
+// array merge policies.
+func shadowedValues(fsys files.FS, envs map[string]inputfile.Environment) (renderReport, error) {
+	usedIn := map[valueSetting][]string{}
+	shadowedIn := map[valueSetting]map[string]bool{}
+	redundantIn := map[valueSetting][]string{}
+	contents := map[string][]leaf{}
+
+	for _, env := range maputils.KeysSorted(envs) {
+		current := map[string]valueSetting{}
+		values := map[string]interface{}{}
+		for _, f := range envs[env].ValueFiles {
+			settings, ok := contents[f]
+			if !ok {
+				var err error
+				if settings, err = readLeaves(fsys, f); err != nil {
+					return renderReport{}, err
+				}
+				contents[f] = settings
+			}
+			for _, l := range settings {
+				k := strings.Join(l.path, ".")
+				s := valueSetting{file: f, key: k}
+				if contains(usedIn[s], env) {
+					continue
+				}
+				usedIn[s] = append(usedIn[s], env)
+				if prev, ok := current[k]; ok && prev.file != f && reflect.DeepEqual(values[k], l.value) {
+					redundantIn[s] = append(redundantIn[s], env)
+				}
+				for ck, cs := range current {
+					if ck == k || strings.HasPrefix(ck, k+".") || strings.HasPrefix(k, ck+".") {
+						if shadowedIn[cs] == nil {
+							shadowedIn[cs] = map[string]bool{}
+						}
+						shadowedIn[cs][env] = true
+						delete(current, ck)
+					}
+				}
+				current[k] = s
+				values[k] = l.value
+			}
+		}
+	}
+
+	settings := make([]valueSetting, 0, len(usedIn))
+	for s := range usedIn {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool {
+		if settings[i].file != settings[j].file {
+			return settings[i].file < settings[j].file
+		}
+		return settings[i].key < settings[j].key
+	})
+
+	report := renderReport{items: []logItem{}}
+	for _, s := range settings {
+		c := log.Context{"file": s.file, "key": s.key}
+		switch {
+		case len(redundantIn[s]) > 0:
+			c["environments"] = strings.Join(redundantIn[s], ", ")
+			report.items = append(report.items, logItem{
+				Msg: "value overrides the same value", Level: log.Warn(), Context: c,
+			})
+		case len(shadowedIn[s]) == len(usedIn[s]):
+			c["environments"] = strings.Join(usedIn[s], ", ")
+			report.items = append(report.items, logItem{
+				Msg: "value is overridden in all environments", Level: log.Warn(), Context: c,
+			})
+		}
+	}
+	return report, nil
+}
+
+// leaf is a scalar (or list) value and its path in the values.
+type leaf struct {
+	path  []string
+	value interface{}
+}
+
+func leaves(values interface{}, path []string) []leaf {
+	m, ok := values.(map[string]interface{})
+	if !ok {
+		if len(path) == 0 {
+			return []leaf{}
+		}
+		return []leaf{{path: path, value: values}}
+	}
+	res := []leaf{}
+	for _, k := range maputils.KeysSorted(m) {
+		res = append(res, leaves(m[k], extendPath(path, k))...)
+	}
+	return res
+}
+
+// readLeaves returns all values of a value file except lists.
+func readLeaves(fsys files.FS, file string) ([]leaf, error) {
+	content, err := fsys.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %q: %w", file, err)
+	}
+	var values interface{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse values file %q: %w", file, err)
+	}
+	res := []leaf{}
+	for _, l := range leaves(values, []string{}) {
+		if _, isList := l.value.([]interface{}); !isList {
+			res = append(res, l)
+		}
+	}
+	return res, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, el := range list {
+		if el == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+package generate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	gotemplate "text/template"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestCollectRefs(t *testing.T) {
+	type ref struct {
+		Path     []string
+		Optional bool
+		Guards   [][]string
+	}
+	for _, s := range []struct {
+		title string
+		tmpl  string
+		want  []ref
+	}{
+		{
+			title: "fields and variables",
+			tmpl:  `{{ .a.b }} {{ $x := .c }}{{ $x.d }} {{ $.e }} {{ toJson .f | indent 2 }}`,
+			want: []ref{
+				{Path: []string{"a", "b"}, Guards: [][]string{}},
+				{Path: []string{"c"}, Guards: [][]string{}},
+				{Path: []string{"c", "d"}, Guards: [][]string{}},
+				{Path: []string{"e"}, Guards: [][]string{}},
+				{Path: []string{"f"}, Guards: [][]string{}},
+			},
+		},
+		{
+			title: "guards and optional references",
+			tmpl: `{{ if .enabled }}{{ .a }}{{ else }}{{ .b }}{{ end }}` +
+				`{{ with .c }}{{ .d }}{{ end }}{{ .e | default "x" }}{{ index .f "g" "h" }}`,
+			want: []ref{
+				{Path: []string{"enabled"}, Optional: true, Guards: [][]string{}},
+				{Path: []string{"a"}, Guards: [][]string{{"enabled"}}},
+				{Path: []string{"b"}, Guards: [][]string{}},
+				{Path: []string{"c"}, Optional: true, Guards: [][]string{}},
+				{Path: []string{"c", "d"}, Guards: [][]string{{"c"}}},
+				{Path: []string{"e"}, Optional: true, Guards: [][]string{}},
+				{Path: []string{"f", "g", "h"}, Guards: [][]string{}},
+			},
+		},
+		{
+			title: "range and template calls",
+			tmpl: `{{ define "t" }}{{ .x }}{{ end }}` +
+				`{{ range $i, $el := .list }}{{ .name }}{{ $el.id }}{{ $.root }}{{ end }}` +
+				`{{ template "t" .sub }}{{ .Coco.Environment.Name }}`,
+			want: []ref{
+				{Path: []string{"list"}, Optional: true, Guards: [][]string{}},
+				{Path: []string{"root"}, Guards: [][]string{{"list"}}},
+				{Path: []string{"sub"}, Guards: [][]string{}},
+				{Path: []string{"sub", "x"}, Guards: [][]string{}},
+			},
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		parsed, err := gotemplate.New("test").Funcs(tmplFuncs()).Parse(s.tmpl)
+		testfuncs.MustBeNil(t, err)
+		got := []ref{}
+		for _, r := range collectRefs(parsed) {
+			got = append(got, ref{Path: r.path, Optional: r.optional, Guards: r.guards})
+		}
+		testfuncs.CheckEqualityInterface(t, s.want, got)
+	}
+}
+
+func TestLintTemplate(t *testing.T) {
+	dir := t.TempDir()
+	envs := map[string]inputfile.Environment{
+		"c1": {Values: map[string]interface{}{
+			"name": "a", "ingress": map[string]interface{}{"className": "nginx"},
+		}},
+		"c2": {Values: map[string]interface{}{
+			"name": "b", "ingress": map[string]interface{}{},
+		}},
+	}
+	for _, s := range []struct {
+		title string
+		tmpl  string
+		want  []logItem
+	}{
+		{
+			title: "valid template",
+			tmpl:  "name: {{ .name }}\n{{ with .ingress.className }}class: {{ . }}{{ end }}\n",
+			want:  []logItem{},
+		},
+		{
+			title: "syntax error",
+			tmpl:  "name: {{ .name }\n",
+			want: []logItem{{
+				Msg:   "template syntax error",
+				Level: log.Error(),
+				Context: log.Context{
+					"error": fmt.Sprintf(
+						`template: %[1]s:1: unexpected "}" in operand`,
+						filepath.Join(dir, "syntax error.tmpl.yaml"),
+					),
+				},
+			}},
+		},
+		{
+			title: "missing key and invalid yaml",
+			tmpl:  "name: {{ .name }}\nclass: {{ .ingress.className }}\n  broken: indent\n",
+			want: []logItem{
+				{
+					Msg:   "referenced key is missing",
+					Level: log.Error(),
+					Context: log.Context{
+						"key": "ingress.className",
+						"location": fmt.Sprintf(
+							"%s:2:18", filepath.Join(dir, "missing key and invalid yaml.tmpl.yaml"),
+						),
+						"environments": "c2",
+					},
+				},
+				{
+					Msg:   "rendered output is no valid yaml",
+					Level: log.Error(),
+					Context: log.Context{
+						"environment": "c1",
+						"file":        filepath.Join(dir, "missing key and invalid yaml-c1.yaml"),
+						"error":       "unmarshalling failed yaml: line 3: mapping values are not allowed in this context",
+					},
+				},
+				{
+					Msg:   "rendered output is no valid yaml",
+					Level: log.Error(),
+					Context: log.Context{
+						"environment": "c2",
+						"file":        filepath.Join(dir, "missing key and invalid yaml-c2.yaml"),
+						"error":       "unmarshalling failed yaml: line 3: mapping values are not allowed in this context",
+					},
+				},
+			},
+		},
+		{
+			title: "render error",
+			tmpl:  "name: {{ if eq .name \"a\" }}{{ fail \"name a is not allowed\" }}{{ end }}\n",
+			want:  []logItem{},
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		source := filepath.Join(dir, s.title+".tmpl.yaml")
+		testfuncs.MustBeNil(t, os.WriteFile(source, []byte(s.tmpl), 0o644))
+		tmpl := template{source: source, basepath: dir, namePrefix: s.title}
+
+		got, _ := lintTemplate(files.OS(), tmpl, envs)
+		for _, i := range s.want {
+			i.Context["template"] = source
+		}
+		if s.title == "render error" {
+			s.want = append(s.want, logItem{
+				Msg:   "render template error",
+				Level: log.Error(),
+				Context: log.Context{
+					"template":    source,
+					"environment": "c1",
+					"file":        filepath.Join(dir, "render error-c1.yaml"),
+					"error": fmt.Sprintf(
+						`template: %s:1:30: executing "%[1]s" at <fail "name a is not allowed">: `+
+							`error calling fail: name a is not allowed`,
+						source,
+					),
+				},
+			})
+		}
+		testfuncs.CheckEqualityInterface(t, s.want, got.items)
+	}
+}
+
+func TestUnusedValues(t *testing.T) {
+	envs := map[string]inputfile.Environment{
+		"c1": {Values: map[string]interface{}{
+			"a": map[string]interface{}{"b": 1, "c": 2},
+			"d": []interface{}{1},
+			"e": "x",
+		}},
+		"c2": {Values: map[string]interface{}{
+			"a": map[string]interface{}{"b": 1},
+			"e": "y",
+		}},
+	}
+	refs := []valueRef{{path: []string{"a", "b"}}, {path: []string{"d", "0"}}}
+	got := unusedValues(envs, refs)
+	want := []logItem{
+		{
+			Msg:     "value is not used by any template",
+			Level:   log.Warn(),
+			Context: log.Context{"key": "a.c", "environments": "c1"},
+		},
+		{
+			Msg:     "value is not used by any template",
+			Level:   log.Warn(),
+			Context: log.Context{"key": "e", "environments": "c1, c2"},
+		},
+	}
+	testfuncs.CheckEqualityInterface(t, want, got.items)
+
+	got = unusedValues(envs, []valueRef{{path: []string{}}})
+	testfuncs.CheckEqualityInterface(t, []logItem{}, got.items)
+}
+
+func TestShadowedValues(t *testing.T) {
+	dir := t.TempDir()
+	tree := map[string]string{
+		"defaults.yaml": "replicas: 1\nimage:\n  tag: v1\nlist: [1]\nregion: eu\n",
+		"c1.yaml":       "replicas: 2\nimage:\n  tag: v1\nlist: [2]\n",
+		"c2.yaml":       "replicas: 3\nimage: latest\n",
+	}
+	for name, content := range tree {
+		testfuncs.MustBeNil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	p := func(name string) string { return filepath.Join(dir, name) }
+	envs := map[string]inputfile.Environment{
+		"c1": {ValueFiles: []string{p("defaults.yaml"), p("c1.yaml")}},
+		"c2": {ValueFiles: []string{p("defaults.yaml"), p("c2.yaml")}},
+	}
+	got, err := shadowedValues(files.OS(), envs)
+	testfuncs.MustBeNil(t, err)
+	want := []logItem{
+		{
+			Msg:     "value overrides the same value",
+			Level:   log.Warn(),
+			Context: log.Context{"file": p("c1.yaml"), "key": "image.tag", "environments": "c1"},
+		},
+		{
+			Msg:     "value is overridden in all environments",
+			Level:   log.Warn(),
+			Context: log.Context{"file": p("defaults.yaml"), "key": "image.tag", "environments": "c1, c2"},
+		},
+		{
+			Msg:     "value is overridden in all environments",
+			Level:   log.Warn(),
+			Context: log.Context{"file": p("defaults.yaml"), "key": "replicas", "environments": "c1, c2"},
+		},
+	}
+	testfuncs.CheckEqualityInterface(t, want, got.items)
+}
+
+func TestLint(t *testing.T) {
+	if err := log.Init(log.Debug(), "", true); err != nil {
+		t.Fatalf("unable to initialize logger: %v", err)
+	}
+	for _, s := range []struct {
+		title    string
+		template string
+		wantErr  error
+	}{
+		{
+			title:    "clean repository",
+			template: "value: {{ .value }}\n{{ with .optional }}optional: {{ . }}{{ end }}\n",
+		},
+		{
+			title:    "missing value",
+			template: "value: {{ .value }}\nother: {{ .other }}\n",
+			wantErr:  fmt.Errorf("1 lint errors encountered"),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		dir, err := testfuncs.PrepareTestDirTree(map[string][]byte{
+			"app/app.tmpl.yaml":   []byte(s.template),
+			"values/c1/coco.yaml": []byte("type: environment\nname: c1\nvalues:\n  - v.yaml\n"),
+			"values/c1/v.yaml":    []byte("value: 1\noptional: x\n"),
+			"values/c2/coco.yaml": []byte("type: environment\nname: c2\nvalues:\n  - v.yaml\n"),
+			"values/c2/v.yaml":    []byte("value: 2\n"),
+		})
+		testfuncs.MustBeNil(t, err)
+
+		err = Lint(
+			dir.Path(), ".tmpl", "coco.yaml",
+			[]string{filepath.Join(dir.Path(), "values") + string(os.PathSeparator)},
+			[]string{}, []string{}, []string{}, nil, files.OS(),
+		)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		for _, f := range []string{"app/app-c1.yaml", "app/app-c2.yaml"} {
+			if _, err := os.Stat(filepath.Join(dir.Path(), f)); !os.IsNotExist(err) {
+				t.Errorf("lint must not write %q", f)
+			}
+		}
+		dir.Cleanup(t)
+	}
+}
@@ -17,6 +17,7 @@ type scenarioMergeSort struct {
 	from               []byte
 	into               []byte
 	persistenceComment string
+	settings           []yamlfile.UpdateSettingsFunc
 	want               resMergeSort
 }
 
@@ -114,6 +115,105 @@ k0: n0
 k1:
   k10: !stay o10
   k11: n1
+`,
+				"\n")),
+			warnings: []yamlfile.Warning{},
+			err:      nil,
+		},
+	},
+	{
+		title: "template order",
+		from: []byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: old
+  annotations: !stay {a: b}
+data:
+  k: old
+`),
+		into: []byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: new
+data:
+  k: new
+`),
+		persistenceComment: "stay",
+		settings:           []yamlfile.UpdateSettingsFunc{yamlfile.SetSortMode(yamlfile.TemplateOrder)},
+		want: resMergeSort{
+			res: []byte(strings.TrimLeft(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: new
+  annotations: !stay {a: b}
+data:
+  k: new
+`,
+				"\n")),
+			warnings: []yamlfile.Warning{},
+			err:      nil,
+		},
+	},
+	{
+		title: "template comments and blank lines",
+		from: []byte(`
+# Code generated by CLI 'coco generate ...' (version: 99.99); DO NOT EDIT.
+
+a: o1
+b:
+  x: o2
+  keep: !stay o3
+`),
+		into: []byte(`
+# head of a
+a: n1 # line a
+
+# section b
+b:
+  x: n2 # line x
+  # foot of b
+`),
+		persistenceComment: "stay",
+		settings:           []yamlfile.UpdateSettingsFunc{yamlfile.SetSortMode(yamlfile.TemplateOrder)},
+		want: resMergeSort{
+			res: []byte(strings.TrimLeft(`
+# head of a
+a: n1 # line a
+
+# section b
+b:
+  x: n2 # line x
+  keep: !stay o3
+  # foot of b
+`,
+				"\n")),
+			warnings: []yamlfile.Warning{},
+			err:      nil,
+		},
+	},
+	{
+		title: "kubernetes order",
+		from:  []byte(``),
+		into: []byte(`
+data:
+  k: new
+metadata:
+  name: new
+kind: ConfigMap
+apiVersion: v1
+`),
+		settings: []yamlfile.UpdateSettingsFunc{yamlfile.SetSortMode(yamlfile.Kubernetes)},
+		want: resMergeSort{
+			res: []byte(strings.TrimLeft(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: new
+data:
+  k: new
 `,
 				"\n")),
 			warnings: []yamlfile.Warning{},
@@ -159,7 +259,7 @@ func TestMergeSort(te *testing.T) {
 	for _, s := range scenariosMergeSort {
 		te.Logf("test scenario: %s\n", s.title)
 
-		res, warnings, err := mergeSort(s.from, s.into, s.persistenceComment)
+		res, warnings, err := mergeSort(s.from, s.into, s.persistenceComment, s.settings...)
 		testfuncs.CheckErrs(te, s.want.err, err)
 		s.want.CheckRes(te, res)
 		s.want.CheckWarnings(te, warnings)
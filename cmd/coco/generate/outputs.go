@@ -0,0 +1,132 @@
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
+)
+
+// Output is a file that the templates generate for an environment. For .tmpl
+// folders Dir holds the generated folder, for .tmpl files it is empty.
+type Output struct {
+	File string
+	Dir  string
+	// Location is the folder of the template
+	Location string
+	// Source is the template file
+	Source string
+}
+
+// Generated reports whether the existing file of the output has been generated
+// and may be changed or removed by coco: rendered files must hold the header of a
+// compatible coco version (see versionIncompatible), raw copies must have the
+// content of their template. Missing files are not generated.
+func (o Output) Generated(fsys files.FS, v *version.Version) (bool, error) {
+	content, err := fsys.ReadFile(o.File)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if reVersion.Match(content) {
+		return !versionIncompatible(content, v.SemVer), nil
+	}
+	source, err := fsys.ReadFile(o.Source)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(content, source), nil
+}
+
+// EnvironmentOutputs returns the outputs that the templates in basepath generate
+// for the environment env with the given values. The outputs are ordered by
+// template and forEach element, so that the outputs of the same values for
+// different environment names correspond to each other. Templates are read from
+// fsys; templates in paths listed in the ignoreFiles are skipped.
+func EnvironmentOutputs(
+	fsys files.FS, basepath, templateIdentifier, configFileName, env string, values interface{},
+	ignoreFiles []string,
+) ([]Output, error) {
+	tmpls, err := findTemplates(fsys, basepath, templateIdentifier, []string{}, []string{}, ignoreFiles)
+	if err != nil {
+		return nil, err
+	}
+	if err := readTemplateConfigs(fsys, tmpls, configFileName); err != nil {
+		return nil, err
+	}
+	res := []Output{}
+	for _, location := range maputils.KeysSorted(tmpls) {
+		for _, tmpl := range tmpls[location] {
+			items, err := tmpl.forEachItems(values)
+			if err != nil {
+				return nil, fmt.Errorf("forEach error in template %q: %w", tmpl.source, err)
+			}
+			for _, item := range items {
+				fp, dir, err := outputPaths(env, tmpl, values, item)
+				if err != nil {
+					return nil, err
+				}
+				o := Output{File: fp, Location: tmpl.basepath, Source: tmpl.source}
+				if tmpl.subpath != "" {
+					o.Dir = dir
+				}
+				res = append(res, o)
+			}
+		}
+	}
+	return res, nil
+}
+
+// outputCollisions computes the output paths of all templates for all
+// environments and returns an error that lists every path that more than one
+// template generates or that a template generates for several environments
+// (e.g. an output pattern without the environment name). Errors of output paths
+// are left to the rendering.
+func outputCollisions(tmpls map[string][]template, vals map[string]interface{}) error {
+	sources := map[string]map[string][]string{}
+	for _, location := range maputils.KeysSorted(tmpls) {
+		for _, tmpl := range tmpls[location] {
+			for _, env := range maputils.KeysSorted(vals) {
+				items, err := tmpl.forEachItems(vals[env])
+				if err != nil {
+					continue
+				}
+				for _, item := range items {
+					fp, _, err := outputPaths(env, tmpl, vals[env], item)
+					if err != nil {
+						continue
+					}
+					if sources[fp] == nil {
+						sources[fp] = map[string][]string{}
+					}
+					envs := sources[fp][tmpl.source]
+					if len(envs) == 0 || envs[len(envs)-1] != env {
+						sources[fp][tmpl.source] = append(envs, env)
+					}
+				}
+			}
+		}
+	}
+	errs := []error{}
+	for _, fp := range maputils.KeysSorted(sources) {
+		switch srcs := maputils.KeysSorted(sources[fp]); {
+		case len(srcs) > 1:
+			errs = append(errs, fmt.Errorf(
+				"output path %q is generated by several templates: %s", fp, strings.Join(srcs, ", "),
+			))
+		case len(sources[fp][srcs[0]]) > 1:
+			errs = append(errs, fmt.Errorf(
+				"output path %q is generated by template %q for several environments: %s",
+				fp, srcs[0], strings.Join(sources[fp][srcs[0]], ", "),
+			))
+		}
+	}
+	return errors.Join(errs...)
+}
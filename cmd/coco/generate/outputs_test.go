@@ -0,0 +1,100 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestEnvironmentOutputs(t *testing.T) {
+	dir, err := testfuncs.PrepareTestDirTree(map[string][]byte{
+		"app/app.tmpl":          []byte("value: {{ .value }}\n"),
+		"svc/svc.tmpl/a.yaml":   []byte("a: 1\n"),
+		"svc/svc.tmpl/b/b.yaml": []byte("b: 1\n"),
+		"tenants/t.tmpl":        []byte("name: {{ .Coco.Item.name }}\n"),
+		"tenants/coco.yaml":     []byte("type: template\nforEach: .tenants\n"),
+	})
+	testfuncs.MustBeNil(t, err)
+	defer dir.Cleanup(t)
+	p := func(f string) string { return filepath.Join(dir.Path(), f) }
+
+	values := map[string]interface{}{
+		"value":   1,
+		"tenants": []interface{}{map[string]interface{}{"name": "x"}, map[string]interface{}{"name": "y"}},
+	}
+	got, err := EnvironmentOutputs(files.OS(), dir.Path(), ".tmpl", "coco.yaml", "c1", values, nil)
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, []Output{
+		{File: p("app/app-c1.yaml"), Location: p("app"), Source: p("app/app.tmpl")},
+		{File: p("svc/svc-c1/a.yaml"), Dir: p("svc/svc-c1"), Location: p("svc"), Source: p("svc/svc.tmpl/a.yaml")},
+		{
+			File: p("svc/svc-c1/b/b.yaml"), Dir: p("svc/svc-c1"), Location: p("svc"), Source: p("svc/svc.tmpl/b/b.yaml"),
+		},
+		{File: p("tenants/t-c1/x.yaml"), Location: p("tenants"), Source: p("tenants/t.tmpl")},
+		{File: p("tenants/t-c1/y.yaml"), Location: p("tenants"), Source: p("tenants/t.tmpl")},
+	}, got)
+}
+
+func TestEnvironmentOutputsOfTemplatedFolders(t *testing.T) {
+	dir, err := testfuncs.PrepareTestDirTree(map[string][]byte{
+		"apps/{{ .region }}/{{ .Coco.Environment.Name }}.yaml.tmpl": []byte("a: 1\n"),
+	})
+	testfuncs.MustBeNil(t, err)
+	defer dir.Cleanup(t)
+	p := func(f string) string { return filepath.Join(dir.Path(), f) }
+
+	got, err := EnvironmentOutputs(
+		files.OS(), dir.Path(), ".tmpl", "coco.yaml", "c1", map[string]interface{}{"region": "eu"}, nil,
+	)
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, []Output{{
+		File:     p("apps/eu/c1.yaml"),
+		Location: p("apps/{{ .region }}"),
+		Source:   p("apps/{{ .region }}/{{ .Coco.Environment.Name }}.yaml.tmpl"),
+	}}, got)
+}
+
+func TestOutputCollisions(t *testing.T) {
+	dir, err := testfuncs.PrepareTestDirTree(map[string][]byte{
+		"app/a.tmpl":             []byte("a: 1\n"),
+		"app/b.tmpl":             []byte("b: 1\n"),
+		"app/coco.yaml":          []byte("type: template\noutput: \"{{ .Coco.Environment.Name }}/app.yaml\"\n"),
+		"svc/svc.tmpl/a.yaml":    []byte("a: 1\n"),
+		"svc/svc-c1/.tmpl":       []byte("a: 1\n"),
+		"svc/svc-c1/coco.yaml":   []byte("type: template\noutput: a.yaml\n"),
+		"other/name.tmpl":        []byte("name: 1\n"),
+		"other/name-c1.tmpl/x.y": []byte("x: 1\n"),
+		"shared/s.tmpl":          []byte("s: 1\n"),
+		"shared/coco.yaml":       []byte("type: template\noutput: s.yaml\n"),
+	})
+	testfuncs.MustBeNil(t, err)
+	defer dir.Cleanup(t)
+	p := func(f string) string { return filepath.Join(dir.Path(), f) }
+
+	tmpls, err := findTemplates(files.OS(), dir.Path(), ".tmpl", []string{}, []string{}, nil)
+	testfuncs.MustBeNil(t, err)
+	testfuncs.MustBeNil(t, readTemplateConfigs(files.OS(), tmpls, "coco.yaml"))
+
+	vals := map[string]interface{}{"c1": map[string]interface{}{}, "c2": map[string]interface{}{}}
+	testfuncs.CheckErrs(t, errors.Join(
+		fmt.Errorf("output path %q is generated by several templates: %s, %s",
+			p("app/c1/app.yaml"), p("app/a.tmpl"), p("app/b.tmpl")),
+		fmt.Errorf("output path %q is generated by several templates: %s, %s",
+			p("app/c2/app.yaml"), p("app/a.tmpl"), p("app/b.tmpl")),
+		fmt.Errorf("output path %q is generated by template %q for several environments: c1, c2",
+			p("shared/s.yaml"), p("shared/s.tmpl")),
+		fmt.Errorf("output path %q is generated by several templates: %s, %s",
+			p("svc/svc-c1/a.yaml"), p("svc/svc-c1/.tmpl"), p("svc/svc.tmpl/a.yaml")),
+	), outputCollisions(tmpls, vals))
+
+	// for the environment c3 only the app templates collide (the outputs of the
+	// .tmpl folder only collide with svc/svc-c1 for the environment c1 and the
+	// shared template generates its output for one environment only)
+	testfuncs.CheckErrs(t, fmt.Errorf("output path %q is generated by several templates: %s, %s",
+		p("app/c3/app.yaml"), p("app/a.tmpl"), p("app/b.tmpl"),
+	), outputCollisions(tmpls, map[string]interface{}{"c3": map[string]interface{}{}}))
+}
@@ -0,0 +1,87 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+)
+
+// read returns the content of the template file and whether it must be copied
+// verbatim instead of being rendered. This applies to files in .tmpl folders
+// that match a raw copy pattern of the template configuration or whose content
+// is no valid UTF-8 (e.g. images or certificates in DER format).
+func (t template) read(fsys files.FS) (content []byte, raw bool, err error) {
+	content, err = fsys.ReadFile(t.source)
+	if err != nil || t.subpath == "" {
+		return content, false, err
+	}
+	matched, err := t.matchesRawCopy()
+	if err != nil {
+		return nil, false, err
+	}
+	return content, matched || !utf8.Valid(content), nil
+}
+
+// matchesRawCopy reports whether the path in the .tmpl folder or its file name
+// matches one of the raw copy patterns (see path.Match).
+func (t template) matchesRawCopy() (bool, error) {
+	if t.config == nil {
+		return false, nil
+	}
+	p := strings.TrimPrefix(filepath.ToSlash(t.subpath), "/")
+	for _, pattern := range t.config.RawCopy {
+		for _, name := range []string{p, path.Base(p)} {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return false, fmt.Errorf("invalid raw copy pattern %q: %w", pattern, err)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// copyRaw writes the content of a raw template file to fp (without the coco
+// header) unless the file already has this content and mode. Since raw copies
+// carry no header, an existing file with other content and without the coco
+// header is considered hand-written and only overwritten with takeControl.
+func copyRaw(fsys files.FS, fp string, content []byte, mode os.FileMode, takeControl bool) error {
+	previous, err := readFile(fsys, fp)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(previous, content) {
+		return ensureMode(fsys, fp, mode)
+	}
+	if !takeControl && len(previous) != 0 && !reVersion.Match(previous) {
+		return errHandWritten
+	}
+	if err := fsys.MkdirAll(filepath.Dir(fp), allAllowed); err != nil {
+		return err
+	}
+	if err := fsys.WriteFile(fp, content, mode); err != nil {
+		return err
+	}
+	return ensureMode(fsys, fp, mode)
+}
+
+// ensureMode sets the permission bits of the file to mode (WriteFile only
+// applies the mode to new files).
+func ensureMode(fsys files.FS, fp string, mode os.FileMode) error {
+	info, err := fsys.Stat(fp)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm() == mode.Perm() {
+		return nil
+	}
+	return fsys.Chmod(fp, mode.Perm())
+}
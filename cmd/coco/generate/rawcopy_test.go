@@ -0,0 +1,178 @@
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
+)
+
+func TestRawContent(t *testing.T) {
+	dir := t.TempDir()
+	binary := []byte{0x30, 0x82, 0xff, 0xfe, 0x00}
+	tree := map[string][]byte{
+		"text.yaml": []byte("a: {{ .a }}\n"),
+		"cert.der":  binary,
+		"chart.txt": []byte("{{ .Values.a }}\n"),
+	}
+	for name, content := range tree {
+		testfuncs.MustBeNil(t, os.WriteFile(filepath.Join(dir, name), content, 0o644))
+	}
+	cfg := &inputfile.Coco{RawCopy: []string{"*.txt"}}
+
+	for _, s := range []struct {
+		title       string
+		tmpl        template
+		wantRaw     bool
+		wantContent []byte
+		wantErr     error
+	}{
+		{
+			title: "template file",
+			tmpl:  template{source: filepath.Join(dir, "cert.der")},
+		},
+		{
+			title: "text file in .tmpl folder",
+			tmpl:  template{source: filepath.Join(dir, "text.yaml"), subpath: "/text.yaml", config: cfg},
+		},
+		{
+			title:       "binary file in .tmpl folder",
+			tmpl:        template{source: filepath.Join(dir, "cert.der"), subpath: "/certs/cert.der"},
+			wantRaw:     true,
+			wantContent: binary,
+		},
+		{
+			title:       "raw copy pattern",
+			tmpl:        template{source: filepath.Join(dir, "chart.txt"), subpath: "/a/chart.txt", config: cfg},
+			wantRaw:     true,
+			wantContent: []byte("{{ .Values.a }}\n"),
+		},
+		{
+			title: "invalid pattern",
+			tmpl: template{
+				source: filepath.Join(dir, "chart.txt"), subpath: "/chart.txt",
+				config: &inputfile.Coco{RawCopy: []string{"["}},
+			},
+			wantErr: errors.New(`invalid raw copy pattern "[": syntax error in pattern`),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		content, raw, err := s.tmpl.read(files.OS())
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		testfuncs.CheckEqualityInterface(t, s.wantRaw, raw)
+		if s.wantRaw {
+			testfuncs.CheckEqualityInterface(t, s.wantContent, content)
+		}
+	}
+}
+
+func TestRenderFileModes(t *testing.T) {
+	if err := log.Init(log.Debug(), "", true); err != nil {
+		t.Fatalf("unable to initialize logger: %v", err)
+	}
+	dir := t.TempDir()
+	binary := []byte{0x89, 0x50, 0x4e, 0x47, 0xff, 0x00}
+	tree := map[string]struct {
+		content []byte
+		mode    os.FileMode
+	}{
+		"svc/coco.yaml":            {[]byte("type: template\nrawCopy: [\"*.tpl\"]\n"), 0o644},
+		"svc/.tmpl/run.sh":         {[]byte("#!/bin/sh\necho {{ .v }}\n"), 0o755},
+		"svc/.tmpl/logo.png":       {binary, 0o600},
+		"svc/.tmpl/chart/_app.tpl": {[]byte("{{ .Values.v }}\n"), 0o644},
+	}
+	for name, f := range tree {
+		p := filepath.Join(dir, name)
+		testfuncs.MustBeNil(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		testfuncs.MustBeNil(t, os.WriteFile(p, f.content, f.mode))
+	}
+	// an existing generated file gets the mode of the template
+	testfuncs.MustBeNil(t, os.MkdirAll(filepath.Join(dir, "svc/c1"), 0o755))
+	testfuncs.MustBeNil(t, os.WriteFile(filepath.Join(dir, "svc/c1/run.sh"), []byte{}, 0o644))
+
+	tmpls, err := findTemplates(files.OS(), dir, ".tmpl", []string{}, []string{}, nil)
+	testfuncs.MustBeNil(t, err)
+	testfuncs.MustBeNil(t, readTemplateConfigs(files.OS(), tmpls, "coco.yaml"))
+	parserConfig = parserMock{}
+	yamlProcessor = mergeSort
+
+	reports := make(chan renderReport, 1)
+	render(
+		"svc", tmpls[filepath.Join(dir, "svc")], map[string]interface{}{"c1": map[string]interface{}{"v": 1}},
+		outputClaims{}, reports, log.Debug(), "HumanInput", &version.Version{}, false, false, files.OS(),
+	)
+	r := <-reports
+	testfuncs.CheckEqualityInterface(t, []logItem(nil), r.items)
+
+	for name, want := range map[string]struct {
+		content []byte
+		mode    os.FileMode
+	}{
+		"svc/c1/run.sh": {
+			[]byte("# Code generated by CLI 'coco generate ...' (version: 0.0); DO NOT EDIT.\n\n#!/bin/sh\necho 1\n"),
+			0o755,
+		},
+		"svc/c1/logo.png":       {binary, 0o600},
+		"svc/c1/chart/_app.tpl": {[]byte("{{ .Values.v }}\n"), 0o644},
+	} {
+		p := filepath.Join(dir, name)
+		content, err := os.ReadFile(p)
+		testfuncs.MustBeNil(t, err)
+		if !bytes.Equal(want.content, content) {
+			t.Errorf("content of %q does not match: \nwant = %q\ngot  = %q", name, want.content, content)
+		}
+		info, err := os.Stat(p)
+		testfuncs.MustBeNil(t, err)
+		testfuncs.CheckEqualityInterface(t, want.mode, info.Mode().Perm())
+	}
+	// raw files are no outputs for hooks and validations
+	testfuncs.CheckEqualityInterface(t, 1, len(r.outputs))
+}
+
+func TestCopyRaw(t *testing.T) {
+	content := []byte("{{ .Values.v }}\n")
+	generated := []byte("# Code generated by CLI 'coco generate ...' (version: 0.0); DO NOT EDIT.\n\nv: 1\n")
+	for _, s := range []struct {
+		title       string
+		previous    []byte
+		takeControl bool
+		want        []byte
+		wantErr     error
+	}{
+		{title: "new file", want: content},
+		{title: "unchanged file", previous: content, want: content},
+		{title: "generated file", previous: generated, want: content},
+		{
+			title:    "hand-written file",
+			previous: []byte("written by hand\n"),
+			want:     []byte("written by hand\n"),
+			wantErr:  errHandWritten,
+		},
+		{
+			title:       "hand-written file with takeControl",
+			previous:    []byte("written by hand\n"),
+			takeControl: true,
+			want:        content,
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		fsys := files.NewMemFS()
+		fp := "/repo/svc/c1/_app.tpl"
+		if s.previous != nil {
+			testfuncs.MustBeNil(t, fsys.MkdirAll(filepath.Dir(fp), 0o755))
+			testfuncs.MustBeNil(t, fsys.WriteFile(fp, s.previous, 0o644))
+		}
+		err := copyRaw(fsys, fp, content, 0o644, s.takeControl)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		got, err := fsys.ReadFile(fp)
+		testfuncs.MustBeNil(t, err)
+		testfuncs.CheckEqualityInterface(t, string(s.want), string(got))
+	}
+}
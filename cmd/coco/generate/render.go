@@ -12,8 +12,12 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	gotemplate "text/template"
 
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/yamlfile"
 )
@@ -29,13 +33,19 @@ const (
 )
 
 var (
-	yamlProcessor func([]byte, []byte, string) ([]byte, []yamlfile.Warning, error) = mergeSort
+	yamlProcessor func(
+		[]byte, []byte, string, ...yamlfile.UpdateSettingsFunc,
+	) ([]byte, []yamlfile.Warning, error) = mergeSort
 
 	parserConfig = parserMock{Mock: false}
 
 	reVersion = regexp.MustCompile(
 		`# Code generated by CLI 'coco generate ...' \(version: ([0-9]*)\.([0-9]*).*\); DO NOT EDIT.`,
 	)
+
+	errHandWritten = errors.New(
+		"the file has not been generated by coco and is not overwritten (use the force flag to overwrite it)",
+	)
 )
 
 type template struct {
@@ -50,20 +60,31 @@ type template struct {
 	// subpaths is empty for .tmpl files but for .tmpl folders it holds the subpaths
 	// inside the .tmpl folder. E.g. ".tmpl/a/b/hello.yaml" -> "a/b/hello.yaml"
 	subpath string
+	// config holds the template configuration that is located next to the
+	// template (nil if there is none)
+	config *inputfile.Coco
+	// mode holds the permission bits of the source that the generated files
+	// inherit (set when rendering)
+	mode os.FileMode
 }
 
 // The render function is the core of the file generation. It renders all provided
 // templates for all provided values (environments) and saves the resulting yaml
 // files in the dedicated paths (see ./readme.md for further details on file names).
+// A failure skips the affected output, environment or template and rendering
+// continues with the next one, unless failFast is set. All failures are reported.
 func render(
-	name string, tmpls []template, vals map[string]interface{},
+	name string, tmpls []template, vals map[string]interface{}, claims outputClaims,
 	reportChan chan<- renderReport,
 	logLvl log.Level,
 	persistenceComment string,
 	v *version.Version,
 	takeControl bool,
+	failFast bool,
+	fsys files.FS,
 ) {
 	report := renderReport{}
+	defer func() { reportChan <- report }()
 
 	var p parserInt
 	if parserConfig.Mock {
@@ -72,76 +93,231 @@ func render(
 		p = &parser{}
 	}
 
+	r := renderRun{
+		p: p, fsys: fsys, logLvl: logLvl, persistenceComment: persistenceComment, v: v, takeControl: takeControl,
+		claims: claims,
+	}
 	for _, tmpl := range tmpls {
 		c := ctx{
 			log.Context{"template": tmpl.source},
 			&report,
-			reportChan,
 		}
 		c.AddDebug(logLvl, "go-routine", name)
-
-		err := p.parse(tmpl.source)
-		if c.checkErr("parse template error", err) {
+		if !r.template(c, tmpl, vals, failFast) && failFast {
 			return
 		}
+	}
+}
 
-		for env, values := range vals {
-			c.AddDebug(logLvl, "values", fmt.Sprintf("%+v", values))
+// renderRun holds the settings of a render function call.
+type renderRun struct {
+	p                  parserInt
+	fsys               files.FS
+	logLvl             log.Level
+	persistenceComment string
+	v                  *version.Version
+	takeControl        bool
+	// claims are the outputs of all templates that pruning must not remove
+	claims outputClaims
+}
 
-			fp := filePath(env, tmpl)
-			c.Context["file"] = fp
-			c.Log("processing values", log.Debug())
+// template renders the template for all environments and returns false if any
+// error occurred.
+func (r renderRun) template(c ctx, tmpl template, vals map[string]interface{}, failFast bool) (ok bool) {
+	info, err := r.fsys.Stat(tmpl.source)
+	if c.checkErr("read template error", err) {
+		return false
+	}
+	tmpl.mode = info.Mode().Perm()
+	content, raw, err := tmpl.read(r.fsys)
+	if c.checkErr("template configuration error", err) {
+		return false
+	}
+	if raw {
+		return r.copyTemplate(c, tmpl, vals, content, failFast)
+	}
 
-			previousContent, err := readFile(fp)
-			if c.checkErr("read current file error", err) {
-				return
-			}
+	err = r.p.parse(tmpl.source, content, tmpl.delimiters())
+	if c.checkErr("parse template error", err) {
+		return false
+	}
 
-			// no file generation when the following conditions are met:
-			// - takeControl flag is false (only generated files with matching version are overwritten)
-			// - the previous version of the file is not empty
-			// - the versions of coco and the version in the generated file do not match
-			if !takeControl &&
-				len(previousContent) != 0 &&
-				versionIncompatible(previousContent, v.SemVer) {
-				continue
-			}
+	yamlOpts, err := tmpl.yamlSettings()
+	if c.checkErr("template configuration error", err) {
+		return false
+	}
 
-			generated, err := p.execute(values)
-			if c.checkErr("render template error", err) {
-				return
+	ok = true
+	for _, env := range maputils.KeysSorted(vals) {
+		envCtx := c.with(log.Context{"environment": env})
+		if !r.environment(envCtx, tmpl, env, vals[env], yamlOpts, failFast) {
+			ok = false
+			if failFast {
+				return false
 			}
+		}
+	}
+	return ok
+}
 
-			newFile, warnings, err := processFile(fp, previousContent, generated, persistenceComment)
-			if c.checkErr("MergeSort failed", err) {
-				return
+// environment renders all outputs of the template for the environment and
+// returns false if any error occurred.
+func (r renderRun) environment(
+	c ctx, tmpl template, env string, values interface{},
+	yamlOpts []yamlfile.UpdateSettingsFunc, failFast bool,
+) (ok bool) {
+	c.AddDebug(r.logLvl, "values", fmt.Sprintf("%+v", values))
+
+	items, err := tmpl.forEachItems(values)
+	if c.checkErr("forEach error", err) {
+		return false
+	}
+	ok = true
+	outputs := make(map[string]bool, len(items))
+	for _, item := range items {
+		fp, dir, err := outputPaths(env, tmpl, values, item)
+		if c.checkErr("output path error", err) {
+			ok = false
+			if failFast {
+				return false
 			}
-			headlessContent, err := removeHeader(previousContent, genFileHeader)
-			if c.checkErr("remove header failed", err) {
-				return
+			continue
+		}
+		if outputs[fp] {
+			c.checkErr("output path error", fmt.Errorf("several forEach elements render to %q", fp))
+			return false
+		}
+		outputs[fp] = true
+		itemCtx := c.with(log.Context{"file": fp})
+		out, itemOk := r.output(itemCtx, tmpl, env, values, item, fp, yamlOpts)
+		if out != nil {
+			out.dir = dir
+			c.report.outputs = append(c.report.outputs, *out)
+		}
+		if !itemOk {
+			ok = false
+			if failFast {
+				return false
 			}
-			if reflect.DeepEqual(newFile, headlessContent) {
-				continue
+		}
+	}
+
+	if tmpl.forEach() {
+		err = pruneOutputs(&c, r.fsys, env, tmpl, values, items, outputs, r.claims, r.v, r.takeControl)
+		if c.checkErr("prune outputs error", err) {
+			return false
+		}
+	}
+	return ok
+}
+
+// output renders the file fp and returns the generated output (nil if the file
+// has not been generated) and false if an error occurred.
+func (r renderRun) output(
+	c ctx, tmpl template, env string, values interface{}, item *forEachItem, fp string,
+	yamlOpts []yamlfile.UpdateSettingsFunc,
+) (*generatedOutput, bool) {
+	c.Log("processing values", log.Debug())
+
+	previousContent, err := readFile(r.fsys, fp)
+	if c.checkErr("read current file error", err) {
+		return nil, false
+	}
+
+	// hand-written files (without the coco header) are only overwritten with the
+	// takeControl flag
+	if !r.takeControl && len(previousContent) != 0 && !reVersion.Match(previousContent) {
+		c.checkErr("existing file error", errHandWritten)
+		return nil, false
+	}
+
+	// no file generation when the following conditions are met:
+	// - takeControl flag is false (only generated files with matching version are overwritten)
+	// - the previous version of the file is not empty
+	// - the versions of coco and the version in the generated file do not match
+	if !r.takeControl &&
+		len(previousContent) != 0 &&
+		versionIncompatible(previousContent, r.v.SemVer) {
+		return nil, true
+	}
+
+	generated, err := r.p.execute(renderData(env, values, item))
+	if c.checkErr("render template error", err) {
+		return nil, false
+	}
+
+	newFile, warnings, err := processFile(
+		fp, previousContent, generated, r.persistenceComment, yamlOpts...,
+	)
+	if c.checkErr("MergeSort failed", err) {
+		return nil, false
+	}
+	headlessContent, err := removeHeader(previousContent, genFileHeader)
+	if c.checkErr("remove header failed", err) {
+		return nil, false
+	}
+	out := &generatedOutput{env: env, file: fp, hooks: tmpl.hooks()}
+	if reflect.DeepEqual(newFile, headlessContent) {
+		if c.checkErr("write to file error", ensureMode(r.fsys, fp, tmpl.mode)) {
+			return nil, false
+		}
+		return out, true
+	}
+	for _, w := range warnings {
+		c.addReport(w.Warning, log.Warn(), log.Context{"keys": w.Keys})
+	}
+
+	err = writeToFile(
+		r.fsys,
+		fp,
+		fmt.Sprintf(genFileHeader, r.v.SemVer.Major, r.v.SemVer.Minor),
+		newFile,
+		tmpl.mode,
+	)
+	if c.checkErr("write to file error", err) {
+		return nil, false
+	}
+	return out, true
+}
+
+// copyTemplate copies the raw template file verbatim to the outputs of all
+// environments and returns false if any error occurred. Raw files are no
+// outputs for hooks and validations.
+func (r renderRun) copyTemplate(
+	c ctx, tmpl template, vals map[string]interface{}, content []byte, failFast bool,
+) (ok bool) {
+	ok = true
+	for _, env := range maputils.KeysSorted(vals) {
+		envCtx := c.with(log.Context{"environment": env})
+		items, err := tmpl.forEachItems(vals[env])
+		if envCtx.checkErr("forEach error", err) {
+			ok = false
+			if failFast {
+				return false
 			}
-			for _, w := range warnings {
-				c.addReport(w.Warning, log.Warn(), log.Context{"keys": w.Keys})
+			continue
+		}
+		for _, item := range items {
+			fp, _, err := outputPaths(env, tmpl, vals[env], item)
+			if envCtx.checkErr("output path error", err) {
+				ok = false
+			} else if envCtx.with(log.Context{"file": fp}).checkErr(
+				"copy file error", copyRaw(r.fsys, fp, content, tmpl.mode, r.takeControl),
+			) {
+				ok = false
+			} else {
+				c.report.copies = append(c.report.copies, fp)
 			}
-
-			err = writeToFile(
-				fp,
-				fmt.Sprintf(genFileHeader, v.SemVer.Major, v.SemVer.Minor),
-				newFile,
-			)
-			if c.checkErr("write to file error", err) {
-				return
+			if !ok && failFast {
+				return false
 			}
 		}
 	}
-	reportChan <- report
+	return ok
 }
 
-func readFile(path string) ([]byte, error) {
-	content, err := os.ReadFile(path)
+func readFile(fsys files.FS, path string) ([]byte, error) {
+	content, err := fsys.ReadFile(path)
 	if errors.Is(err, os.ErrNotExist) {
 		content = []byte{}
 		err = nil
@@ -149,15 +325,23 @@ func readFile(path string) ([]byte, error) {
 	return content, err
 }
 
-func writeToFile(path, header string, content []byte) error {
-	f, err := openFileAsEmpty(path, header)
-	if err != nil {
+// writeToFile writes the header and the content to the file. Existing files get
+// the permission bits of mode (if set).
+func writeToFile(fsys files.FS, path, header string, content []byte, mode os.FileMode) error {
+	if err := fsys.MkdirAll(filepath.Dir(path), allAllowed); err != nil {
 		return err
 	}
-	defer f.Close()
-
-	_, err = f.Write(content)
-	return err
+	perm := mode
+	if perm == 0 {
+		perm = files.AllReadWrite
+	}
+	if err := fsys.WriteFile(path, append([]byte(header), content...), perm); err != nil {
+		return err
+	}
+	if mode == 0 {
+		return nil
+	}
+	return ensureMode(fsys, path, mode)
 }
 
 func removeHeader(content []byte, header string) ([]byte, error) {
@@ -177,25 +361,53 @@ func removeHeader(content []byte, header string) ([]byte, error) {
 	return headlessContent.Bytes(), nil
 }
 
+// delimiters returns the configured action delimiters of the template content
+// (nil for the defaults).
+func (t template) delimiters() *inputfile.Delimiters {
+	if t.config == nil {
+		return nil
+	}
+	return t.config.Delimiters
+}
+
+// yamlSettings returns the yamlfile settings for merging previously generated
+// files into newly rendered ones and for sorting the result as configured in
+// the template configuration.
+func (t template) yamlSettings() ([]yamlfile.UpdateSettingsFunc, error) {
+	if t.config == nil {
+		return []yamlfile.UpdateSettingsFunc{}, nil
+	}
+	res, err := t.config.ArrayMerge.Settings()
+	if err != nil {
+		return nil, err
+	}
+	sortMode, err := t.config.SortMode()
+	if err != nil {
+		return nil, err
+	}
+	return append(res, sortMode...), nil
+}
+
 func processFile(
 	path string, from, into []byte, persistenceComment string,
+	opts ...yamlfile.UpdateSettingsFunc,
 ) ([]byte, []yamlfile.Warning, error) {
 	if filepath.Ext(path) != ".yaml" {
 		return into, []yamlfile.Warning{}, nil
 	}
 	return yamlProcessor(
-		from, into, persistenceComment,
+		from, into, persistenceComment, opts...,
 	)
 }
 
 func mergeSort(
-	from, into []byte, persistenceComment string,
+	from, into []byte, persistenceComment string, opts ...yamlfile.UpdateSettingsFunc,
 ) ([]byte, []yamlfile.Warning, error) {
 	f, err := yamlfile.New(from)
 	if err != nil {
 		return nil, nil, err
 	}
-	i, err := yamlfile.New(into)
+	i, err := yamlfile.New(into, opts...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -213,18 +425,152 @@ func mergeSort(
 	return renderedData.Bytes(), warnings, nil
 }
 
-func filePath(env string, tmpl template) string {
-	envName := env
-	if tmpl.namePrefix != "" {
-		envName = fmt.Sprintf("%s-%s", tmpl.namePrefix, envName)
+// filePath returns the path of the file that is generated from tmpl for the
+// environment env (and the forEach item, if not nil). Template actions in the
+// folder names of the template location, the name prefix, the subpath and the
+// output pattern of the template configuration are rendered with the values of
+// the environment (see pathData).
+func filePath(env string, tmpl template, values interface{}, item *forEachItem) (string, error) {
+	fp, _, err := outputPaths(env, tmpl, values, item)
+	return fp, err
+}
+
+// outputPaths returns the path of the generated file (see filePath) and the
+// directory of the output. For .tmpl folders this is the generated folder, for
+// .tmpl files it is the folder that holds the generated file.
+func outputPaths(
+	env string, tmpl template, values interface{}, item *forEachItem,
+) (file, dir string, err error) {
+	data := pathData(env, tmpl, values, item)
+	location, err := tmpl.outputLocation(data)
+	if err != nil {
+		return "", "", err
+	}
+	subpath, err := renderPath(tmpl.subpath, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	// a rendered name replaces "<prefix>-<env>" and may already hold the extension
+	name, rendered := env, true
+	switch {
+	case tmpl.config != nil && tmpl.config.Output != "":
+		name, err = renderPath(tmpl.config.Output, data)
+	case isTemplated(tmpl.namePrefix):
+		name, err = renderPath(tmpl.namePrefix, data)
+	case tmpl.namePrefix != "":
+		name, rendered = fmt.Sprintf("%s-%s", tmpl.namePrefix, env), false
+	default:
+		rendered = false
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if item != nil && !rendered {
+		name = filepath.Join(name, item.key)
 	}
+
 	var fp string
-	if tmpl.subpath != "" {
-		fp = filepath.Join(tmpl.basepath, envName, tmpl.subpath)
-	} else {
-		fp = filepath.Join(tmpl.basepath, tmpl.subpath, fmt.Sprintf("%s.yaml", envName))
+	switch {
+	case subpath != "":
+		fp = filepath.Join(name, subpath)
+	case rendered && filepath.Ext(name) == ".yaml":
+		fp = name
+	default:
+		fp = fmt.Sprintf("%s.yaml", name)
 	}
-	return fp
+	fp = filepath.Clean(fp)
+	if filepath.IsAbs(fp) || fp == ".." || strings.HasPrefix(fp, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("output path %q of template %q is outside of %q", fp, tmpl.source, location)
+	}
+	file = filepath.Join(location, fp)
+	if subpath != "" {
+		return file, filepath.Join(location, name), nil
+	}
+	return file, filepath.Dir(file), nil
+}
+
+// outputLocation returns the folder that the outputs of the template are
+// generated in: the template location with rendered folder names, e.g. the
+// template "{{ .region }}/app.yaml.tmpl" generates "eu/app-<env>.yaml" for the
+// region eu. A rendered folder name must be a single path element.
+func (t template) outputLocation(data interface{}) (string, error) {
+	if !isTemplated(t.basepath) {
+		return t.basepath, nil
+	}
+	folders := strings.Split(t.basepath, string(filepath.Separator))
+	for i, f := range folders {
+		if !isTemplated(f) {
+			continue
+		}
+		rendered, err := renderPath(f, data)
+		if err != nil {
+			return "", err
+		}
+		if rendered == "" || rendered == "." || rendered == ".." ||
+			strings.ContainsRune(rendered, filepath.Separator) {
+			return "", fmt.Errorf(
+				"folder %q of template %q renders to the invalid folder name %q", f, t.source, rendered,
+			)
+		}
+		folders[i] = rendered
+	}
+	return strings.Join(folders, string(filepath.Separator)), nil
+}
+
+// staticLocation returns the part of the template location before its first
+// templated folder name.
+func (t template) staticLocation() string {
+	folders := strings.Split(t.basepath, string(filepath.Separator))
+	for i, f := range folders {
+		if isTemplated(f) {
+			return strings.Join(folders[:i], string(filepath.Separator))
+		}
+	}
+	return t.basepath
+}
+
+// pathData returns the data for rendering output paths: the values of the
+// environment extended by the key "Coco" that holds the environment name
+// (.Coco.Environment.Name), the template name prefix (.Coco.Template.Prefix) and
+// for templates with forEach option the list element (.Coco.Item).
+func pathData(env string, tmpl template, values interface{}, item *forEachItem) map[string]interface{} {
+	res := map[string]interface{}{}
+	if m, ok := values.(map[string]interface{}); ok {
+		for k, v := range m {
+			res[k] = v
+		}
+	}
+	coco := map[string]interface{}{
+		"Environment": map[string]interface{}{"Name": env},
+		"Template":    map[string]interface{}{"Prefix": tmpl.namePrefix},
+	}
+	if item != nil {
+		coco["Item"] = item.value
+	}
+	res["Coco"] = coco
+	return res
+}
+
+func isTemplated(path string) bool {
+	return strings.Contains(path, "{{")
+}
+
+// renderPath renders the template actions in path. Missing values are an error
+// since they would silently change the location of the generated files.
+func renderPath(path string, data interface{}) (string, error) {
+	if !isTemplated(path) {
+		return path, nil
+	}
+	t, err := gotemplate.New(path).Funcs(tmplFuncs()).Option("missingkey=error").Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid output path %q: %w", path, err)
+	}
+	var res strings.Builder
+	if err := t.Execute(&res, data); err != nil {
+		return "", fmt.Errorf("failed to render output path %q: %w", path, err)
+	}
+	return res.String(), nil
 }
 
 func versionIncompatible(content []byte, v version.SemVer) bool {
@@ -247,22 +593,21 @@ func versionIncompatible(content []byte, v version.SemVer) bool {
 	return false
 }
 
-func openFileAsEmpty(path, contentHeader string) (f *os.File, err error) {
-	if err = os.MkdirAll(filepath.Dir(path), allAllowed); err != nil {
-		return
-	}
-	f, err = os.Create(path)
-	if err != nil {
-		return
-	}
-	_, err = f.WriteString(contentHeader)
-	return
-}
-
 type ctx struct {
 	log.Context
-	report     *renderReport
-	reportChan chan<- renderReport
+	report *renderReport
+}
+
+// with returns a copy of the context that holds the additional context.
+func (c ctx) with(addedContext log.Context) ctx {
+	res := ctx{make(log.Context, len(c.Context)+len(addedContext)), c.report}
+	for k, v := range c.Context {
+		res.Context[k] = v
+	}
+	for k, v := range addedContext {
+		res.Context[k] = v
+	}
+	return res
 }
 
 func (c *ctx) AddDebug(lvl log.Level, key, value string) {
@@ -282,17 +627,26 @@ func (c *ctx) addReport(msg string, lvl log.Level, addedContext log.Context) {
 	c.report.items = append(c.report.items, logItem{msg, lvl, currentContext})
 }
 
+// reTemplatePosition matches the position in errors of text/template, e.g.
+// "template: path/.tmpl:3:12: executing ..." or "template: path/.tmpl:3: ...".
+var reTemplatePosition = regexp.MustCompile(`^template: .*?:(\d+)(?::(\d+))?: `)
+
 // checkErr holds the local logic for error checking in the render function.
-// The render function runs concurrently and must send a renderReport back via the
-// reportChan channel. This function does this if it finds an error.
-// In addition, it returns true if a non-nil error was found, so that the render
-// function exits subsequently.
-func (c ctx) checkErr(msg string, err error) (exitNow bool) {
-	if err != nil {
-		c.Context.Log(msg, log.Error())
-		c.addReport(err.Error(), log.Error(), log.Context{"error": err.Error()})
-		c.reportChan <- *c.report
-		return true
+// It adds a non-nil error to the report (with the line and column in the
+// template if available) and returns true, so that the render function skips
+// the affected output, environment or template.
+func (c ctx) checkErr(msg string, err error) (failed bool) {
+	if err == nil {
+		return false
 	}
-	return false
+	c.Context.Log(msg, log.Error())
+	errCtx := log.Context{"error": err.Error()}
+	if m := reTemplatePosition.FindStringSubmatch(err.Error()); m != nil {
+		errCtx["line"], _ = strconv.Atoi(m[1])
+		if m[2] != "" {
+			errCtx["column"], _ = strconv.Atoi(m[2])
+		}
+	}
+	c.addReport(err.Error(), log.Error(), errCtx)
+	return true
 }
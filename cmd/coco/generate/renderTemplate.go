@@ -3,24 +3,31 @@ package generate
 import (
 	"bytes"
 	"fmt"
-	"os"
 	gotemplate "text/template"
 
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
-	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/yamlfile"
 )
 
-var (
-	filesWrite = files.Write
-)
-
-func ParseTemplate(filename string, valueFiles []string, target string) error {
+// ParseTemplate renders the template file with the merged values of the value
+// files into the target file. All files are read from and written to fsys.
+// Non-nil delimiters replace "{{" and "}}".
+func ParseTemplate(
+	fsys files.FS, filename string, valueFiles []string, target string, delims *inputfile.Delimiters,
+) error {
+	if err := delims.Validate(); err != nil {
+		return err
+	}
 	p := parser{}
-	if err := p.parse(filename); err != nil {
+	content, err := fsys.ReadFile(filename)
+	if err == nil {
+		err = p.parse(filename, content, delims)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to parse file %q: %w", filename, err)
 	}
 
-	combinedValues, err := mergeValues(valueFiles)
+	combinedValues, err := inputfile.MergeValueFiles(fsys, valueFiles)
 	if err != nil {
 		return err
 	}
@@ -32,14 +39,14 @@ func ParseTemplate(filename string, valueFiles []string, target string) error {
 	if err != nil {
 		return fmt.Errorf("failed to render template %q: %w", filename, err)
 	}
-	if err := filesWrite(target, files.AllReadWrite, output); err != nil {
+	if err := writeToFile(fsys, target, "", output, 0); err != nil {
 		return fmt.Errorf("failed to write to file %q: %w", target, err)
 	}
 	return nil
 }
 
 type parserInt interface {
-	parse(filename string) error
+	parse(filename string, content []byte, delims *inputfile.Delimiters) error
 	execute(data interface{}) ([]byte, error)
 }
 
@@ -48,7 +55,7 @@ type parserMock struct {
 	Err  error
 }
 
-func (m parserMock) parse(filename string) error {
+func (m parserMock) parse(filename string, content []byte, delims *inputfile.Delimiters) error {
 	return nil
 }
 
@@ -60,12 +67,13 @@ type parser struct {
 	tmpl *gotemplate.Template
 }
 
-func (p *parser) parse(filename string) error {
-	b, err := os.ReadFile(filename)
-	if err != nil {
-		return err
+// parse parses the content of the template file filename.
+func (p *parser) parse(filename string, content []byte, delims *inputfile.Delimiters) error {
+	t := gotemplate.New(filename).Funcs(tmplFuncs())
+	if delims != nil {
+		t = t.Delims(delims.Left, delims.Right)
 	}
-	parsed, err := gotemplate.New(filename).Funcs(tmplFuncs()).Parse(string(b))
+	parsed, err := t.Parse(string(content))
 	if err != nil {
 		return err
 	}
@@ -81,23 +89,3 @@ func (p parser) execute(data interface{}) ([]byte, error) {
 	}
 	return generated.Bytes(), nil
 }
-
-func mergeValues(valueFiles []string) (res yamlfile.Yaml, err error) {
-	res, err = yamlfile.New([]byte{}, yamlfile.SetArrayMergePolicy(yamlfile.Strict))
-	if err != nil {
-		err = fmt.Errorf("failed to create combined values file: %w", err)
-		return
-	}
-	for _, v := range valueFiles {
-		content, e := files.Read(v)
-		if e != nil {
-			err = fmt.Errorf("failed to read file %q: %w", v, e)
-			return
-		}
-		if _, e := res.MergeBytes(content); e != nil {
-			err = fmt.Errorf("failed to combine values file %q: %w", v, err)
-			return
-		}
-	}
-	return
-}
@@ -8,6 +8,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
 )
@@ -25,6 +27,7 @@ type parseInput struct {
 	templateContent  []byte
 	valueFiles       map[string][]byte
 	target           string
+	delims           *inputfile.Delimiters
 }
 
 type expectedOutput struct {
@@ -77,6 +80,21 @@ value2
 	`)),
 		},
 	},
+	{
+		title: "custom delimiters",
+		i: parseInput{
+			templateFileName: "example",
+			templateContent:  []byte(`[[ .key1 ]] {{ .Values.key }}`),
+			valueFiles: map[string][]byte{
+				"values.yaml": []byte(`key1: value1`),
+			},
+			target: "output",
+			delims: &inputfile.Delimiters{Left: "[[", Right: "]]"},
+		},
+		o: expectedOutput{
+			content: []byte(`value1 {{ .Values.key }}`),
+		},
+	},
 	{
 		title: "failed to read value files",
 		i: parseInput{
@@ -125,9 +143,11 @@ func (s *scenarioParserTemplate) Test(te *testing.T) {
 		valueFiles = append(valueFiles, filepath.Join(tmpDir, v))
 	}
 	err = ParseTemplate(
+		files.OS(),
 		filepath.Join(tmpDir, s.i.templateFileName),
 		valueFiles,
 		filepath.Join(tmpDir, s.i.target),
+		s.i.delims,
 	)
 	testfuncs.CheckSimilarErrs(te, s.o.err, err)
 	if s.o.err == nil {
@@ -15,6 +15,8 @@ import (
 	"testing"
 	gotemplate "text/template"
 
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/version"
@@ -39,10 +41,12 @@ type renderInput struct {
 	persistenceComment string
 	version            string
 	takeControl        bool
+	failFast           bool
 }
 
 type renderOutput struct {
 	want       map[string][]byte
+	wantAbsent []string
 	wantReport []logItem
 }
 
@@ -53,7 +57,7 @@ var scenariosRender = []scenarioRender{
 			mockMergeSort: true,
 		},
 		i: renderInput{
-			templates:       []template{{"path/X/.tmpl", "path/X", "", ""}},
+			templates:       []template{{source: "path/X/.tmpl", basepath: "path/X", namePrefix: "", subpath: ""}},
 			templateContent: [][]byte{content(``)},
 			alreadyPresent:  map[string][]byte{},
 			values: map[string][]byte{
@@ -72,7 +76,7 @@ var scenariosRender = []scenarioRender{
 	{
 		title: "test template rendering",
 		i: renderInput{
-			templates: []template{{"path/.tmpl", "path", "", ""}},
+			templates: []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
 			templateContent: [][]byte{content(`
 constant: const-value
 key: !yamlFlag {{.value1}}
@@ -125,7 +129,7 @@ testSplitSelectEmpty: ""
 	{
 		title: "test non-yaml rendering",
 		i: renderInput{
-			templates: []template{{"path/.tmpl/nonYamlFile", "path", "", "nonYamlFile"}},
+			templates: []template{{source: "path/.tmpl/nonYamlFile", basepath: "path", namePrefix: "", subpath: "nonYamlFile"}},
 			templateContent: [][]byte{content(`
 VAR_1={{ joinElems "-" "hello" "world" "2" }}
 VAR_2={{ .value1 }}
@@ -144,6 +148,35 @@ value1: fromValues-1
 
 VAR_1=hello-world-2
 VAR_2=fromValues-1
+`),
+			},
+		},
+	},
+	{
+		title: "custom delimiters",
+		i: renderInput{
+			templates: []template{{
+				source: "path/.tmpl/app.tpl", basepath: "path", namePrefix: "", subpath: "app.tpl",
+				config: &inputfile.Coco{Delimiters: &inputfile.Delimiters{Left: "[[", Right: "]]"}},
+			}},
+			templateContent: [][]byte{content(`
+name: [[ .value1 ]]
+image: {{ .Values.image }}
+	`)},
+			values: map[string][]byte{
+				"c1": content(`
+value1: fromValues-1
+`),
+			},
+			version: "99.99.99",
+		},
+		o: renderOutput{
+			want: map[string][]byte{
+				"path/c1/app.tpl": content(`
+# Code generated by CLI 'coco generate ...' (version: 99.99); DO NOT EDIT.
+
+name: fromValues-1
+image: {{ .Values.image }}
 `),
 			},
 		},
@@ -151,7 +184,7 @@ VAR_2=fromValues-1
 	{
 		title: "template parsing fails",
 		i: renderInput{
-			templates:       []template{{"path/.tmpl", "path", "", ""}},
+			templates:       []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
 			templateContent: [][]byte{content(`fail: {{ doesNotExist }}`)},
 			values:          map[string][]byte{"c1": content(``)},
 			version:         "99.99.99",
@@ -165,6 +198,7 @@ VAR_2=fromValues-1
 					Context: map[string]interface{}{
 						"error":      `template: {{.TmpDir}}/path/.tmpl:1: function "doesNotExist" not defined`,
 						"go-routine": "template parsing fails",
+						"line":       1,
 						"template":   `{{.TmpDir}}/path/.tmpl`,
 					},
 				},
@@ -174,7 +208,7 @@ VAR_2=fromValues-1
 	{
 		title: "template rendering fails",
 		i: renderInput{
-			templates:       []template{{"path/.tmpl", "path", "", ""}},
+			templates:       []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
 			templateContent: [][]byte{content(``)},
 			values:          map[string][]byte{"c1": content(``)},
 			version:         "99.99.99",
@@ -190,11 +224,86 @@ VAR_2=fromValues-1
 					Msg:   `rendering error`,
 					Level: log.Error(),
 					Context: map[string]interface{}{
-						"error":      `rendering error`,
+						"environment": "c1",
+						"error":       `rendering error`,
+						"file":        `{{.TmpDir}}/path/c1.yaml`,
+						"go-routine":  "template rendering fails",
+						"template":    `{{.TmpDir}}/path/.tmpl`,
+						"values":      "",
+					},
+				},
+			},
+		},
+	},
+	{
+		title: "errors do not stop the other environments",
+		i: renderInput{
+			templates:       []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
+			templateContent: [][]byte{content(`value: {{ if .bad }}{{ fail "broken" }}{{ end }}{{ .v }}`)},
+			values: map[string][]byte{
+				"c1": content(`{bad: true, v: 1}`),
+				"c2": content(`{v: 2}`),
+			},
+			version: "99.99.99",
+		},
+		o: renderOutput{
+			want: map[string][]byte{
+				"path/c2.yaml": content(`
+# Code generated by CLI 'coco generate ...' (version: 99.99); DO NOT EDIT.
+
+value: 2
+`),
+			},
+			wantAbsent: []string{"path/c1.yaml"},
+			wantReport: []logItem{
+				{
+					Msg: `template: {{.TmpDir}}/path/.tmpl:1:23: executing "{{.TmpDir}}/path/.tmpl" ` +
+						`at <fail "broken">: error calling fail: broken`,
+					Level: log.Error(),
+					Context: map[string]interface{}{
+						"environment": "c1",
+						"error": `template: {{.TmpDir}}/path/.tmpl:1:23: executing "{{.TmpDir}}/path/.tmpl" ` +
+							`at <fail "broken">: error calling fail: broken`,
 						"file":       `{{.TmpDir}}/path/c1.yaml`,
-						"go-routine": "template rendering fails",
+						"go-routine": "errors do not stop the other environments",
+						"line":       1,
+						"column":     23,
 						"template":   `{{.TmpDir}}/path/.tmpl`,
-						"values":     "",
+						"values":     "map[bad:true v:1]",
+					},
+				},
+			},
+		},
+	},
+	{
+		title: "fail fast",
+		i: renderInput{
+			templates:       []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
+			templateContent: [][]byte{content(`value: {{ if .bad }}{{ fail "broken" }}{{ end }}{{ .v }}`)},
+			values: map[string][]byte{
+				"c1": content(`{bad: true, v: 1}`),
+				"c2": content(`{v: 2}`),
+			},
+			version:  "99.99.99",
+			failFast: true,
+		},
+		o: renderOutput{
+			wantAbsent: []string{"path/c1.yaml", "path/c2.yaml"},
+			wantReport: []logItem{
+				{
+					Msg: `template: {{.TmpDir}}/path/.tmpl:1:23: executing "{{.TmpDir}}/path/.tmpl" ` +
+						`at <fail "broken">: error calling fail: broken`,
+					Level: log.Error(),
+					Context: map[string]interface{}{
+						"environment": "c1",
+						"error": `template: {{.TmpDir}}/path/.tmpl:1:23: executing "{{.TmpDir}}/path/.tmpl" ` +
+							`at <fail "broken">: error calling fail: broken`,
+						"file":       `{{.TmpDir}}/path/c1.yaml`,
+						"go-routine": "fail fast",
+						"line":       1,
+						"column":     23,
+						"template":   `{{.TmpDir}}/path/.tmpl`,
+						"values":     "map[bad:true v:1]",
 					},
 				},
 			},
@@ -203,7 +312,7 @@ VAR_2=fromValues-1
 	{
 		title: "test warnings",
 		i: renderInput{
-			templates:          []template{{"path/.tmpl", "path", "", ""}},
+			templates:          []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
 			templateContent:    [][]byte{content(``)},
 			values:             map[string][]byte{"c1": content(``)},
 			persistenceComment: "",
@@ -231,22 +340,24 @@ VAR_2=fromValues-1
 					Msg:   "first warning",
 					Level: log.Warn(),
 					Context: map[string]interface{}{
-						"file":       `{{.TmpDir}}/path/c1.yaml`,
-						"go-routine": "test warnings",
-						"keys":       []string{"k1"},
-						"template":   `{{.TmpDir}}/path/.tmpl`,
-						"values":     "",
+						"environment": "c1",
+						"file":        `{{.TmpDir}}/path/c1.yaml`,
+						"go-routine":  "test warnings",
+						"keys":        []string{"k1"},
+						"template":    `{{.TmpDir}}/path/.tmpl`,
+						"values":      "",
 					},
 				},
 				{
 					Msg:   "second warning",
 					Level: log.Warn(),
 					Context: map[string]interface{}{
-						"file":       `{{.TmpDir}}/path/c1.yaml`,
-						"go-routine": "test warnings",
-						"keys":       []string{"k1", "k2"},
-						"template":   `{{.TmpDir}}/path/.tmpl`,
-						"values":     "",
+						"environment": "c1",
+						"file":        `{{.TmpDir}}/path/c1.yaml`,
+						"go-routine":  "test warnings",
+						"keys":        []string{"k1", "k2"},
+						"template":    `{{.TmpDir}}/path/.tmpl`,
+						"values":      "",
 					},
 				},
 			},
@@ -255,7 +366,7 @@ VAR_2=fromValues-1
 	{
 		title: "yamlProcessor fails",
 		i: renderInput{
-			templates:          []template{{"path/.tmpl", "path", "", ""}},
+			templates:          []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
 			templateContent:    [][]byte{content(``)},
 			values:             map[string][]byte{"c1": content(``)},
 			persistenceComment: "",
@@ -273,11 +384,12 @@ VAR_2=fromValues-1
 					Msg:   "intended failure",
 					Level: log.Error(),
 					Context: map[string]interface{}{
-						"error":      "intended failure",
-						"file":       `{{.TmpDir}}/path/c1.yaml`,
-						"go-routine": "yamlProcessor fails",
-						"template":   `{{.TmpDir}}/path/.tmpl`,
-						"values":     "",
+						"environment": "c1",
+						"error":       "intended failure",
+						"file":        `{{.TmpDir}}/path/c1.yaml`,
+						"go-routine":  "yamlProcessor fails",
+						"template":    `{{.TmpDir}}/path/.tmpl`,
+						"values":      "",
 					},
 				},
 			},
@@ -286,7 +398,7 @@ VAR_2=fromValues-1
 	{
 		title: "compatible versions",
 		i: renderInput{
-			templates:       []template{{"path/.tmpl", "path", "", ""}},
+			templates:       []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
 			templateContent: [][]byte{content(`minimal: template`)},
 			values:          map[string][]byte{"c1": content(``)},
 			version:         "99.99.99",
@@ -308,7 +420,7 @@ VAR_2=fromValues-1
 	{
 		title: "no change -> no updated version string",
 		i: renderInput{
-			templates:       []template{{"path/.tmpl", "path", "", ""}},
+			templates:       []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
 			templateContent: [][]byte{content("mocked mergeSort")},
 			values:          map[string][]byte{"c1": content(``)},
 			version:         "99.99.99",
@@ -331,9 +443,9 @@ VAR_2=fromValues-1
 		},
 	},
 	{
-		title: "skip incompatible versions - general",
+		title: "refuse to overwrite files without header",
 		i: renderInput{
-			templates:       []template{{"path/.tmpl", "path", "", ""}},
+			templates:       []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
 			templateContent: [][]byte{content(`minimal: template`)},
 			values:          map[string][]byte{"c1": content(``)},
 			version:         "99.99.99",
@@ -347,12 +459,26 @@ VAR_2=fromValues-1
 			want: map[string][]byte{
 				"path/c1.yaml": content("# this does not match"),
 			},
+			wantReport: []logItem{
+				{
+					Msg:   "the file has not been generated by coco and is not overwritten (use the force flag to overwrite it)",
+					Level: log.Error(),
+					Context: map[string]interface{}{
+						"environment": "c1",
+						"error":       "the file has not been generated by coco and is not overwritten (use the force flag to overwrite it)",
+						"file":        `{{.TmpDir}}/path/c1.yaml`,
+						"go-routine":  "refuse to overwrite files without header",
+						"template":    `{{.TmpDir}}/path/.tmpl`,
+						"values":      "",
+					},
+				},
+			},
 		},
 	},
 	{
 		title: "skip incompatible versions - major",
 		i: renderInput{
-			templates:       []template{{"path/.tmpl", "path", "", ""}},
+			templates:       []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
 			templateContent: [][]byte{content(`minimal: template`)},
 			values:          map[string][]byte{"c1": content(``)},
 			version:         "99.99.99",
@@ -371,7 +497,7 @@ VAR_2=fromValues-1
 	{
 		title: "skip incompatible versions - minor",
 		i: renderInput{
-			templates:       []template{{"path/.tmpl", "path", "", ""}},
+			templates:       []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
 			templateContent: [][]byte{content(`minimal: template`)},
 			values:          map[string][]byte{"c1": content(``)},
 			version:         "99.1.99",
@@ -390,7 +516,7 @@ VAR_2=fromValues-1
 	{
 		title: "hard overwrite incompatible versions",
 		i: renderInput{
-			templates:       []template{{"path/.tmpl", "path", "", ""}},
+			templates:       []template{{source: "path/.tmpl", basepath: "path", namePrefix: "", subpath: ""}},
 			templateContent: [][]byte{content(`minimal: template`)},
 			values:          map[string][]byte{"c1": content(``)},
 			version:         "99.99.99",
@@ -410,10 +536,129 @@ VAR_2=fromValues-1
 		},
 	},
 
+	{
+		title: "template array merge policy",
+		i: renderInput{
+			templates: []template{{
+				source: "path/.tmpl", basepath: "path",
+				config: &inputfile.Coco{
+					Type: inputfile.TEMPLATE,
+					ArrayMerge: &inputfile.ArrayMerge{
+						Policy: "strict",
+						Exceptions: []inputfile.ArrayMergeException{
+							{Path: "cidrs", Policy: "append"},
+						},
+					},
+				},
+			}},
+			templateContent: [][]byte{content(`
+cidrs:
+  - 10.0.0.0/24
+containers:
+  - generated
+`)},
+			alreadyPresent: map[string][]byte{"path/c1.yaml": content(`
+# Code generated by CLI 'coco generate ...' (version: 99.99); DO NOT EDIT.
+
+cidrs:
+  - 10.0.1.0/24 # HumanInput
+containers:
+  - manual # HumanInput
+`)},
+			values:             map[string][]byte{"c1": content(``)},
+			persistenceComment: "HumanInput",
+			version:            "99.99.99",
+		},
+		o: renderOutput{
+			want: map[string][]byte{
+				"path/c1.yaml": content(`
+# Code generated by CLI 'coco generate ...' (version: 99.99); DO NOT EDIT.
+
+cidrs:
+  - 10.0.0.0/24
+  - 10.0.1.0/24 # HumanInput
+containers:
+  - manual # HumanInput
+`),
+			},
+		},
+	},
+	{
+		title: "forEach over tenants",
+		i: renderInput{
+			templates: []template{{
+				source: "path/tenant.tmpl", basepath: "path", namePrefix: "tenant",
+				config: &inputfile.Coco{Type: inputfile.TEMPLATE, ForEach: ".tenants"},
+			}},
+			templateContent: [][]byte{content(`
+tenant: {{ .Coco.Item.name }}
+cluster: {{ .Coco.Environment.Name }}
+region: {{ .region }}
+`)},
+			alreadyPresent: map[string][]byte{
+				"path/tenant-c1/removed.yaml": content(`
+# Code generated by CLI 'coco generate ...' (version: 99.99); DO NOT EDIT.
+
+tenant: removed
+`),
+				"path/tenant-c1/manual.yaml": content(`tenant: manual`),
+				"path/tenant-c2/other.yaml": content(`
+# Code generated by CLI 'coco generate ...' (version: 99.99); DO NOT EDIT.
+
+tenant: other
+`),
+			},
+			values: map[string][]byte{"c1": content(`
+region: eu
+tenants:
+  - name: a
+  - name: b
+`)},
+			version: "99.99.99",
+		},
+		o: renderOutput{
+			want: map[string][]byte{
+				"path/tenant-c1/a.yaml": content(`
+# Code generated by CLI 'coco generate ...' (version: 99.99); DO NOT EDIT.
+
+cluster: c1
+region: eu
+tenant: a
+`),
+				"path/tenant-c1/b.yaml": content(`
+# Code generated by CLI 'coco generate ...' (version: 99.99); DO NOT EDIT.
+
+cluster: c1
+region: eu
+tenant: b
+`),
+				"path/tenant-c1/manual.yaml": content(`tenant: manual`),
+				"path/tenant-c2/other.yaml": content(`
+# Code generated by CLI 'coco generate ...' (version: 99.99); DO NOT EDIT.
+
+tenant: other
+`),
+			},
+			wantAbsent: []string{"path/tenant-c1/removed.yaml"},
+			wantReport: []logItem{
+				{
+					Msg:   "removed output of a removed forEach element",
+					Level: log.Info(),
+					Context: map[string]interface{}{
+						"environment": "c1",
+						"file":        `{{.TmpDir}}/path/tenant-c1/removed.yaml`,
+						"go-routine":  "forEach over tenants",
+						"template":    `{{.TmpDir}}/path/tenant.tmpl`,
+						"values":      "map[region:eu tenants:[map[name:a] map[name:b]]]",
+					},
+				},
+			},
+		},
+	},
 	{
 		title: "e2e example",
 		i: renderInput{
-			templates: []template{{"path/X/.tmpl", "path/X", "", ""}},
+			templates: []template{{source: "path/X/.tmpl", basepath: "path/X", namePrefix: "", subpath: ""}},
 			templateContent: [][]byte{content(`
 constant: const-value
 array: [{{.value3}}]
@@ -484,10 +729,11 @@ nested: <no value>
 					Msg:   "sequence length from (2) does not match length into (1)",
 					Level: log.Warn(),
 					Context: map[string]interface{}{
-						"file":       `{{.TmpDir}}/path/X/c1.yaml`,
-						"go-routine": "e2e example",
-						"keys":       []string{"array2"},
-						"template":   `{{.TmpDir}}/path/X/.tmpl`,
+						"environment": "c1",
+						"file":        `{{.TmpDir}}/path/X/c1.yaml`,
+						"go-routine":  "e2e example",
+						"keys":        []string{"array2"},
+						"template":    `{{.TmpDir}}/path/X/.tmpl`,
 						"values": map[string]interface{}{
 							"ifKey":  "parse",
 							"nested": map[string]interface{}{"key": "fromValues-4"},
@@ -562,6 +808,7 @@ func (s *scenarioRender) Test(te *testing.T) {
 			basepath:   filepath.Join(tmpDir, t.basepath),
 			namePrefix: t.namePrefix,
 			subpath:    t.subpath,
+			config:     t.config,
 		}
 	}
 
@@ -582,9 +829,9 @@ func (s *scenarioRender) Test(te *testing.T) {
 	report := make(chan renderReport, 1)
 
 	render(
-		s.title, testTemplates, valueFileContent, report,
+		s.title, testTemplates, valueFileContent, outputClaims{}, report,
 		log.Debug(), s.i.persistenceComment,
-		&v, s.i.takeControl,
+		&v, s.i.takeControl, s.i.failFast, files.OS(),
 	)
 	rep := <-report
 
@@ -593,6 +840,78 @@ func (s *scenarioRender) Test(te *testing.T) {
 	s.m.Check(te)
 }
 
+func TestFilePath(t *testing.T) {
+	values := map[string]interface{}{"region": "eu", "tenant": "t1", "path": "a/b"}
+	for _, s := range []struct {
+		title   string
+		tmpl    template
+		want    string
+		wantErr error
+	}{
+		{
+			title: "template file",
+			tmpl:  template{basepath: "base", namePrefix: "name"},
+			want:  "base/name-env1.yaml",
+		},
+		{
+			title: "template folder",
+			tmpl:  template{basepath: "base", subpath: "/a/b.yaml"},
+			want:  "base/env1/a/b.yaml",
+		},
+		{
+			title: "templated file name",
+			tmpl:  template{basepath: "base", namePrefix: "{{ .region }}-{{ .Coco.Environment.Name }}"},
+			want:  "base/eu-env1.yaml",
+		},
+		{
+			title: "templated folder",
+			tmpl:  template{basepath: "base", subpath: "/{{ .region }}/{{ .Coco.Environment.Name }}.yaml"},
+			want:  "base/env1/eu/env1.yaml",
+		},
+		{
+			title: "templated location folder",
+			tmpl:  template{basepath: "base/{{ .region }}", namePrefix: "{{ .Coco.Environment.Name }}.yaml"},
+			want:  "base/eu/env1.yaml",
+		},
+		{
+			title: "templated location folder with an invalid name",
+			tmpl:  template{source: "base/{{ .path }}/.tmpl", basepath: "base/{{ .path }}"},
+			wantErr: errors.New(
+				`folder "{{ .path }}" of template "base/{{ .path }}/.tmpl" renders to the invalid folder name "a/b"`,
+			),
+		},
+		{
+			title: "output pattern",
+			tmpl: template{
+				basepath: "base", namePrefix: "app",
+				config: &inputfile.Coco{Output: "{{ .region }}/{{ .tenant }}/{{ .Coco.Template.Prefix }}.yaml"},
+			},
+			want: "base/eu/t1/app.yaml",
+		},
+		{
+			title: "missing value",
+			tmpl:  template{source: "base/{{ .zone }}.tmpl", basepath: "base", namePrefix: "{{ .zone }}"},
+			wantErr: errors.New(
+				`failed to render output path "{{ .zone }}": template: {{ .zone }}:1:3: ` +
+					`executing "{{ .zone }}" at <.zone>: map has no entry for key "zone"`,
+			),
+		},
+		{
+			title: "outside of the template location",
+			tmpl: template{
+				source: "base/.tmpl", basepath: "base",
+				config: &inputfile.Coco{Output: "../{{ .region }}"},
+			},
+			wantErr: errors.New(`output path "../eu.yaml" of template "base/.tmpl" is outside of "base"`),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		got, err := filePath("env1", s.tmpl, values, nil)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		testfuncs.CheckEqualityInterface(t, s.want, got)
+	}
+}
+
 func setVersion(versionString string) (version.Version, error) {
 	res := version.Version{Version: fmt.Sprintf("v%v", versionString)}
 	re := regexp.MustCompile(`(\d*)\.(\d*)\.(\d*)`)
@@ -658,7 +977,7 @@ type mockOutput struct {
 	err      error
 }
 
-func (m *mock) mergeSort(from, into []byte, persistence string) (
+func (m *mock) mergeSort(from, into []byte, persistence string, _ ...yamlfile.UpdateSettingsFunc) (
 	[]byte, []yamlfile.Warning, error,
 ) {
 	if m.o.err != nil {
@@ -710,6 +1029,12 @@ func (ro renderOutput) CheckRes(t *testing.T, basedir string) {
 			t.Fail()
 		}
 	}
+	for _, name := range ro.wantAbsent {
+		if _, err := os.Stat(filepath.Join(basedir, name)); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("file %s should not exist", name)
+			failed = true
+		}
+	}
 	if failed {
 		t.Fail()
 		_ = filepath.WalkDir(basedir, func(path string, e fs.DirEntry, err error) error {
@@ -0,0 +1,322 @@
+package generate
+
+import (
+	"strings"
+	gotemplate "text/template"
+	"text/template/parse"
+)
+
+// maxTemplateCalls limits the depth of nested template calls that are followed
+// when references are collected (recursive templates would not terminate).
+const maxTemplateCalls = 10
+
+// guardFuncs are template functions that handle missing values. References in
+// their pipelines are optional.
+var guardFuncs = map[string]bool{
+	"default": true, "empty": true, "coalesce": true, "hasKey": true, "kindIs": true, "typeIs": true,
+}
+
+// valueRef is a reference of a template to a key of the values, e.g.
+// {{ .ingress.className }} references the path [ingress className].
+type valueRef struct {
+	path []string
+	// optional references do not need to be set (e.g. the condition of an if
+	// action or the argument of default)
+	optional bool
+	// guards hold the conditions of the enclosing if, with and range actions;
+	// the reference is only rendered if all of them are set
+	guards [][]string
+	// location of the reference in the template ("name:line:column")
+	location string
+}
+
+func (r valueRef) key() string {
+	return strings.Join(r.path, ".")
+}
+
+// refScope holds the state of a template while its parse tree is traversed.
+type refScope struct {
+	// dot is the path of the values that dot points to (nil if unknown, e.g.
+	// inside of range actions)
+	dot      []string
+	vars     map[string][]string
+	guards   [][]string
+	optional bool
+	calls    int
+}
+
+func (s refScope) child() refScope {
+	vars := make(map[string][]string, len(s.vars))
+	for k, v := range s.vars {
+		vars[k] = v
+	}
+	s.vars = vars
+	s.guards = append([][]string{}, s.guards...)
+	return s
+}
+
+type refCollector struct {
+	tmpl *gotemplate.Template
+	refs []valueRef
+}
+
+// collectRefs returns all references of the parsed template to keys of the
+// values. References that cannot be resolved statically (e.g. fields of range
+// elements) are left out.
+func collectRefs(t *gotemplate.Template) []valueRef {
+	c := refCollector{tmpl: t, refs: []valueRef{}}
+	if t == nil || t.Tree == nil {
+		return c.refs
+	}
+	root := []string{}
+	c.walk(t.Tree.Root, refScope{dot: root, vars: map[string][]string{"$": root}})
+	// the key Coco is added by coco itself (see renderData)
+	res := make([]valueRef, 0, len(c.refs))
+	for _, r := range c.refs {
+		if len(r.path) == 0 || r.path[0] != "Coco" {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// itemRefs returns the paths inside of the forEach element (.Coco.Item) that the
+// parsed template references, e.g. [region] for {{ .Coco.Item.region }}. A
+// reference to the element itself is the empty path.
+func itemRefs(t *gotemplate.Template) [][]string {
+	c := refCollector{tmpl: t, refs: []valueRef{}}
+	res := [][]string{}
+	if t == nil || t.Tree == nil {
+		return res
+	}
+	root := []string{}
+	c.walk(t.Tree.Root, refScope{dot: root, vars: map[string][]string{"$": root}})
+	for _, r := range c.refs {
+		if len(r.path) >= 2 && r.path[0] == "Coco" && r.path[1] == "Item" {
+			res = append(res, r.path[2:])
+		}
+	}
+	return res
+}
+
+func (c *refCollector) walk(node parse.Node, s refScope) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.walk(child, s)
+		}
+	case *parse.ActionNode:
+		c.pipe(n.Pipe, s, true)
+	case *parse.IfNode:
+		c.branch(&n.BranchNode, s, false)
+	case *parse.WithNode:
+		c.branch(&n.BranchNode, s, true)
+	case *parse.RangeNode:
+		cond := s
+		cond.optional = true
+		start := len(c.refs)
+		c.pipe(n.Pipe, cond, false)
+		inner := s.child()
+		inner.dot = nil
+		for _, r := range c.refs[start:] {
+			inner.guards = append(inner.guards, r.path)
+		}
+		for _, v := range n.Pipe.Decl {
+			inner.vars[v.Ident[0]] = nil
+		}
+		c.walk(n.List, inner)
+		c.walk(n.ElseList, s.child())
+	case *parse.TemplateNode:
+		arg := c.pipe(n.Pipe, s, false)
+		called := c.tmpl.Lookup(n.Name)
+		if called == nil || called.Tree == nil || s.calls >= maxTemplateCalls {
+			return
+		}
+		inner := s.child()
+		inner.dot = arg
+		inner.vars = map[string][]string{"$": arg}
+		inner.calls++
+		c.walk(called.Tree.Root, inner)
+	}
+}
+
+// branch handles if and with actions. The references of the condition guard the
+// list of the action; with actions additionally move dot.
+func (c *refCollector) branch(b *parse.BranchNode, s refScope, with bool) {
+	cond := s
+	cond.optional = true
+	start := len(c.refs)
+	res := c.pipe(b.Pipe, cond, true)
+	inner := s.child()
+	for _, r := range c.refs[start:] {
+		inner.guards = append(inner.guards, r.path)
+	}
+	if with {
+		inner.dot = res
+	}
+	c.walk(b.List, inner)
+	c.walk(b.ElseList, s.child())
+}
+
+// pipe collects the references of the pipeline and returns the path of its
+// result if it can be resolved (nil otherwise). Declared variables are bound to
+// the result if declare is set.
+func (c *refCollector) pipe(p *parse.PipeNode, s refScope, declare bool) []string {
+	if p == nil {
+		return nil
+	}
+	for _, cmd := range p.Cmds {
+		if len(cmd.Args) > 0 {
+			if id, ok := cmd.Args[0].(*parse.IdentifierNode); ok && guardFuncs[id.Ident] {
+				s.optional = true
+			}
+		}
+	}
+	var res []string
+	for _, cmd := range p.Cmds {
+		if path, ok := c.index(cmd, s); ok {
+			res = path
+			continue
+		}
+		res = nil
+		for _, a := range cmd.Args {
+			c.arg(a, s)
+		}
+		if len(cmd.Args) == 1 {
+			res = c.resolve(cmd.Args[0], s)
+		}
+	}
+	if len(p.Cmds) > 1 {
+		res = nil
+	}
+	if declare {
+		for _, v := range p.Decl {
+			s.vars[v.Ident[0]] = res
+		}
+	}
+	return res
+}
+
+// index resolves commands like {{ index .a "b" "c" }} with constant keys to the
+// path [a b c].
+func (c *refCollector) index(cmd *parse.CommandNode, s refScope) ([]string, bool) {
+	if len(cmd.Args) < 3 {
+		return nil, false
+	}
+	if id, ok := cmd.Args[0].(*parse.IdentifierNode); !ok || id.Ident != "index" {
+		return nil, false
+	}
+	base := c.resolve(cmd.Args[1], s)
+	if base == nil {
+		return nil, false
+	}
+	keys := make([]string, 0, len(cmd.Args)-2)
+	for _, a := range cmd.Args[2:] {
+		str, ok := a.(*parse.StringNode)
+		if !ok {
+			return nil, false
+		}
+		keys = append(keys, str.Text)
+	}
+	path := extendPath(base, keys...)
+	c.add(path, cmd, s)
+	return path, true
+}
+
+func (c *refCollector) arg(node parse.Node, s refScope) {
+	switch n := node.(type) {
+	case *parse.FieldNode, *parse.VariableNode, *parse.DotNode:
+		if path := c.resolve(n, s); path != nil {
+			c.add(path, n, s)
+		}
+	case *parse.ChainNode:
+		c.arg(n.Node, s)
+	case *parse.PipeNode:
+		c.pipe(n, s, false)
+	}
+}
+
+// resolve returns the path of the values that the node points to (nil if it
+// cannot be resolved).
+func (c *refCollector) resolve(node parse.Node, s refScope) []string {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		if s.dot == nil {
+			return nil
+		}
+		return extendPath(s.dot, n.Ident...)
+	case *parse.VariableNode:
+		base := s.vars[n.Ident[0]]
+		if base == nil {
+			return nil
+		}
+		return extendPath(base, n.Ident[1:]...)
+	case *parse.DotNode:
+		if s.dot == nil {
+			return nil
+		}
+		return extendPath(s.dot)
+	}
+	return nil
+}
+
+func (c *refCollector) add(path []string, node parse.Node, s refScope) {
+	location, _ := c.tmpl.ErrorContext(node)
+	c.refs = append(c.refs, valueRef{
+		path:     path,
+		optional: s.optional,
+		guards:   append([][]string{}, s.guards...),
+		location: location,
+	})
+}
+
+func extendPath(base []string, keys ...string) []string {
+	res := make([]string, 0, len(base)+len(keys))
+	return append(append(res, base...), keys...)
+}
+
+// lookupPath returns the value at the path and whether the path is set. Paths
+// that lead into non-map values (e.g. lists) cannot be checked and are reported
+// as set.
+func lookupPath(values interface{}, path []string) (interface{}, bool) {
+	current := values
+	for _, k := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, true
+		}
+		if current, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// isSet reports whether the guard is set and not empty in the values, i.e.
+// whether the guarded part of a template is rendered.
+func isSet(values interface{}, guard []string) bool {
+	v, ok := lookupPath(values, guard)
+	if !ok {
+		return false
+	}
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	case int:
+		return val != 0
+	case float64:
+		return val != 0
+	case map[string]interface{}:
+		return len(val) > 0
+	case []interface{}:
+		return len(val) > 0
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package generate
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/k8sschema"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+)
+
+// validateOutputs validates the Kubernetes objects of all generated yaml files
+// against their schemas. Every field that does not match its schema is reported
+// as error, objects without a known schema are reported as warning.
+func validateOutputs(fsys files.FS, outputs []generatedOutput, v *k8sschema.Validator) []renderReport {
+	res := []renderReport{}
+	if v == nil {
+		return res
+	}
+	outputFiles := map[string]string{}
+	for _, o := range outputs {
+		if ext := filepath.Ext(o.file); ext == ".yaml" || ext == ".yml" {
+			outputFiles[o.file] = o.env
+		}
+	}
+	names := make([]string, 0, len(outputFiles))
+	for f := range outputFiles {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+
+	for _, f := range names {
+		if r := validateFile(fsys, f, outputFiles[f], v); len(r.items) > 0 {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+func validateFile(fsys files.FS, file, env string, v *k8sschema.Validator) renderReport {
+	report := renderReport{items: []logItem{}}
+	content, err := fsys.ReadFile(file)
+	if err == nil {
+		var results []k8sschema.Result
+		results, err = v.ValidateYaml(content)
+		for _, r := range results {
+			report.items = append(report.items, resultItems(file, env, r)...)
+		}
+	}
+	if err != nil {
+		report.items = append(report.items, logItem{
+			Msg:     "kubernetes validation failed",
+			Level:   log.Error(),
+			Context: log.Context{"file": file, "environment": env, "error": err.Error()},
+		})
+	}
+	return report
+}
+
+func resultItems(file, env string, r k8sschema.Result) []logItem {
+	context := func() log.Context {
+		return log.Context{
+			"file":        file,
+			"environment": env,
+			"document":    r.Document,
+			"kind":        r.GroupVersionKind.String(),
+			"name":        r.Name,
+		}
+	}
+	if r.MissingSchema {
+		return []logItem{{Msg: "no kubernetes schema found", Level: log.Warn(), Context: context()}}
+	}
+	res := make([]logItem, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		c := context()
+		c["path"] = e.Path
+		c["error"] = e.Msg
+		res = append(res, logItem{Msg: "invalid kubernetes object", Level: log.Error(), Context: c})
+	}
+	return res
+}
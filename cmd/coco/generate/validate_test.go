@@ -0,0 +1,71 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/k8sschema"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestValidateOutputs(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "c1.yaml")
+	invalid := filepath.Join(dir, "c2.yaml")
+	for p, content := range map[string]string{
+		valid:   "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n",
+		invalid: "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: b\n---\napiVersion: x/v1\nkind: Foo\ndata: 1\n",
+	} {
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, err := k8sschema.New()
+	if err != nil {
+		t.Fatalf("unable to create validator: %v", err)
+	}
+	outputs := []generatedOutput{
+		{env: "c1", file: valid},
+		{env: "c2", file: invalid},
+		{env: "c2", file: invalid},
+		{env: "c3", file: filepath.Join(dir, "c3.json")},
+	}
+
+	testfuncs.CheckEqualityInterface(t, []renderReport{}, validateOutputs(files.OS(), outputs, nil))
+
+	got := validateOutputs(files.OS(), outputs, v)
+	want := []renderReport{{items: []logItem{{
+		Msg:   "no kubernetes schema found",
+		Level: log.Warn(),
+		Context: log.Context{
+			"file":        invalid,
+			"environment": "c2",
+			"document":    1,
+			"kind":        "x/v1/Foo",
+			"name":        "",
+		},
+	}}}}
+	testfuncs.CheckEqualityInterface(t, want, got)
+
+	if err := os.WriteFile(invalid, []byte("apiVersion: v1\nkind: Namespace\nspec:\n  foo: bar\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got = validateOutputs(files.OS(), outputs, v)
+	want = []renderReport{{items: []logItem{{
+		Msg:   "invalid kubernetes object",
+		Level: log.Error(),
+		Context: log.Context{
+			"file":        invalid,
+			"environment": "c2",
+			"document":    0,
+			"kind":        "v1/Namespace",
+			"name":        "",
+			"path":        "spec.foo",
+			"error":       "unknown field",
+		},
+	}}}}
+	testfuncs.CheckEqualityInterface(t, want, got)
+}
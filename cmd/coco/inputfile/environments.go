@@ -0,0 +1,238 @@
+package inputfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/yamlfile"
+)
+
+// Environment holds the config file of an environment together with the ordered
+// list of its value files and the values merged from them.
+type Environment struct {
+	Config Coco
+	// Path is the path of the config file
+	Path       string
+	ValueFiles []string
+	Values     interface{}
+}
+
+// fileSystem abstracts the access to config and value files, so that
+// environments can be read from a files.FS like the local disk (filesFS) as well as from other
+// sources like git trees (ioFS).
+type fileSystem interface {
+	Stat(name string) (fs.FileInfo, error)
+	Glob(pattern string) ([]string, error)
+	ReadFile(name string) ([]byte, error)
+	Join(elem ...string) string
+	Dir(name string) string
+}
+
+// filesFS accesses a files.FS with paths in the notation of the os package.
+type filesFS struct {
+	fsys files.FS
+}
+
+func (f filesFS) Stat(name string) (fs.FileInfo, error) { return f.fsys.Stat(name) }
+func (f filesFS) Glob(pattern string) ([]string, error) { return fs.Glob(f.fsys, pattern) }
+func (f filesFS) ReadFile(name string) ([]byte, error)  { return f.fsys.ReadFile(name) }
+func (f filesFS) Join(elem ...string) string            { return filepath.Join(elem...) }
+func (f filesFS) Dir(name string) string                { return filepath.Dir(name) }
+
+type ioFS struct {
+	fsys fs.FS
+}
+
+func (f ioFS) Stat(name string) (fs.FileInfo, error) { return fs.Stat(f.fsys, name) }
+func (f ioFS) Glob(pattern string) ([]string, error) { return fs.Glob(f.fsys, pattern) }
+func (f ioFS) ReadFile(name string) ([]byte, error)  { return fs.ReadFile(f.fsys, name) }
+func (f ioFS) Join(elem ...string) string            { return path.Join(elem...) }
+func (f ioFS) Dir(name string) string                { return path.Dir(name) }
+
+// ReadEnvironments finds all environment config files in fsys (see FindAll) and
+// merges the values of each environment. The result is keyed by the environment
+// name.
+func ReadEnvironments(
+	fsys files.FS, basepath, configFileName string, includeOr, includeAnd, exclude, ignoreFiles []string,
+) (map[string]Environment, error) {
+	configFiles, err := FindAll(fsys, basepath, configFileName, includeOr, includeAnd, exclude, ignoreFiles)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]Environment, len(configFiles))
+	for p, file := range configFiles {
+		if file.IsDir {
+			continue
+		}
+		coco, err := parse(file.Content)
+		if err != nil {
+			return nil, err
+		}
+		if err := addEnvironment(res, filesFS{fsys}, coco, p); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// ReadEnvironmentsFS reads the environments from the file system fsys (e.g. a git
+// tree). Config files named configFileName are searched in the folders (slash
+// separated paths relative to the root of fsys) and their sub folders; missing
+// folders are skipped. Like ReadEnvironments, paths listed in the ignoreFiles of
+// root (the slash separated basepath) and its sub folders are skipped.
+func ReadEnvironmentsFS(
+	fsys fs.FS, root, configFileName string, folders, ignoreFiles []string,
+) (map[string]Environment, error) {
+	root = path.Clean(root)
+	cleaned := make([]string, 0, len(folders))
+	for _, f := range folders {
+		cleaned = append(cleaned, path.Clean(strings.TrimSuffix(f, "/")))
+	}
+	// the folders are walked from root, so that the ignore files of their parent
+	// folders apply; folders outside of root are walked on their own
+	roots := []string{root}
+	for _, f := range cleaned {
+		if !within(root, f) {
+			roots = append(roots, f)
+		}
+	}
+	res := map[string]Environment{}
+	for _, r := range roots {
+		if _, err := fs.Stat(fsys, r); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		err := files.WalkDir(fsys, r, ignoreFiles, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				for _, f := range cleaned {
+					if within(p, f) || within(f, p) {
+						return nil
+					}
+				}
+				return fs.SkipDir
+			}
+			if d.Name() != configFileName || !withinAny(cleaned, p) {
+				return nil
+			}
+			content, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			coco, err := parse(content)
+			if err != nil {
+				return fmt.Errorf("failed to load %q: %w", p, err)
+			}
+			return addEnvironment(res, ioFS{fsys}, coco, p)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// within reports whether the slash separated path p is dir or located in dir.
+func within(dir, p string) bool {
+	return dir == "." || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+func withinAny(dirs []string, p string) bool {
+	for _, d := range dirs {
+		if within(d, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func addEnvironment(envs map[string]Environment, fsys fileSystem, c Coco, p string) error {
+	if !c.IsEnvironment() {
+		return nil
+	}
+	env, err := c.environment(fsys, p)
+	if err != nil {
+		return err
+	}
+	envs[c.Name] = env
+	return nil
+}
+
+func (c *Coco) environment(fsys fileSystem, p string) (Environment, error) {
+	valueFiles, err := c.resolveValues(fsys, fsys.Dir(p))
+	if err != nil {
+		return Environment{}, err
+	}
+	opts, err := c.ArrayMerge.Settings()
+	if err != nil {
+		return Environment{}, fmt.Errorf("invalid array merge configuration in %q: %w", p, err)
+	}
+	merged, err := mergeValueFiles(fsys, valueFiles, nil, opts...)
+	if err != nil {
+		return Environment{}, err
+	}
+	var values interface{}
+	if err := merged.Decode(&values); err != nil {
+		return Environment{}, err
+	}
+	return Environment{Config: *c, Path: p, ValueFiles: valueFiles, Values: values}, nil
+}
+
+// MergeValueFiles merges the valueFiles of fsys in order. Sequences are merged
+// with the strict array merge policy unless the opts specify otherwise.
+func MergeValueFiles(
+	fsys files.FS, valueFiles []string, opts ...yamlfile.UpdateSettingsFunc,
+) (yamlfile.Yaml, error) {
+	return mergeValueFiles(filesFS{fsys}, valueFiles, nil, opts...)
+}
+
+// MergeWithOrigins merges the value files of the environment like
+// ReadEnvironments and records for every node of the merged values the value
+// files that set it or contribute to it.
+func (e Environment) MergeWithOrigins(fsys files.FS) (yamlfile.Yaml, yamlfile.Origins, error) {
+	opts, err := e.Config.ArrayMerge.Settings()
+	if err != nil {
+		return yamlfile.Yaml{}, nil, fmt.Errorf("invalid array merge configuration in %q: %w", e.Path, err)
+	}
+	origins := yamlfile.Origins{}
+	merged, err := mergeValueFiles(filesFS{fsys}, e.ValueFiles, origins, opts...)
+	if err != nil {
+		return yamlfile.Yaml{}, nil, err
+	}
+	return merged, origins, nil
+}
+
+// mergeValueFiles merges the value files in order. The origins of the merged
+// nodes are recorded in origins unless it is nil.
+func mergeValueFiles(
+	fsys fileSystem, valueFiles []string, origins yamlfile.Origins, opts ...yamlfile.UpdateSettingsFunc,
+) (res yamlfile.Yaml, err error) {
+	settings := append([]yamlfile.UpdateSettingsFunc{yamlfile.SetArrayMergePolicy(yamlfile.Strict)}, opts...)
+	res, err = yamlfile.New([]byte{}, settings...)
+	if err != nil {
+		err = fmt.Errorf("failed to create combined values file: %w", err)
+		return
+	}
+	for _, v := range valueFiles {
+		content, e := fsys.ReadFile(v)
+		if e != nil {
+			err = fmt.Errorf("failed to read file %q: %w", v, e)
+			return
+		}
+		from, e := yamlfile.New(content)
+		if e == nil {
+			_, e = res.MergeTracked(from, v, origins)
+		}
+		if e != nil {
+			err = fmt.Errorf("failed to combine values file %q: %w", v, e)
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,89 @@
+package inputfile
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestReadEnvironmentsFS(t *testing.T) {
+	for _, s := range []struct {
+		title   string
+		files   fstest.MapFS
+		want    map[string]Environment
+		wantErr error
+	}{
+		{
+			title: "environments with globs and shared files",
+			files: fstest.MapFS{
+				"values/base.yaml":        {Data: []byte("a: 1\nlist: [1]\n")},
+				"values/c1/coco.yaml":     {Data: []byte("type: environment\nname: c1\nvalues:\n  - ../base.yaml\n  - '*.v.yaml'\n")},
+				"values/c1/1.v.yaml":      {Data: []byte("b: 2\nlist: [2]\n")},
+				"values/c1/2.v.yaml":      {Data: []byte("b: 3\n")},
+				"values/comp/coco.yaml":   {Data: []byte("type: component\nname: comp\n")},
+				"services/app/coco.yaml":  {Data: []byte("type: environment\nname: ignored\nvalues: []\n")},
+				"values/c2/sub/coco.yaml": {Data: []byte("type: environment\nname: c2\nvalues:\n  - opt.yaml?\n")},
+			},
+			want: map[string]Environment{
+				"c1": {
+					Config: Coco{
+						Type: ENVIRONMENT, Name: "c1",
+						Values: []ValueFile{{Path: "../base.yaml"}, {Path: "*.v.yaml"}},
+					},
+					Path:       "values/c1/coco.yaml",
+					ValueFiles: []string{"values/base.yaml", "values/c1/1.v.yaml", "values/c1/2.v.yaml"},
+					Values:     map[string]interface{}{"a": 1, "b": 3, "list": []interface{}{2}},
+				},
+				"c2": {
+					Config: Coco{
+						Type: ENVIRONMENT, Name: "c2",
+						Values: []ValueFile{{Path: "opt.yaml", Optional: true}},
+					},
+					Path:       "values/c2/sub/coco.yaml",
+					ValueFiles: []string{},
+					Values:     nil,
+				},
+			},
+		},
+		{
+			title: "missing value file",
+			files: fstest.MapFS{
+				"values/c1/coco.yaml": {Data: []byte("type: environment\nname: c1\nvalues:\n  - v.yaml\n")},
+			},
+			wantErr: errors.New(`values file "values/c1/v.yaml" of "c1" does not exist: ` + fs.ErrNotExist.Error()),
+		},
+		{
+			title: "ignored environments",
+			files: fstest.MapFS{
+				".cocoignore":                  {Data: []byte("archived/\n")},
+				"values/archived/c1/coco.yaml": {Data: []byte("type: environment\nname: c1\nvalues: []\n")},
+				"values/c2/.cocoignore":        {Data: []byte("coco.yaml\n")},
+				"values/c2/coco.yaml":          {Data: []byte("type: environment\nname: c2\nvalues: []\n")},
+				"values/c3/coco.yaml":          {Data: []byte("type: environment\nname: c3\nvalues: []\n")},
+			},
+			want: map[string]Environment{
+				"c3": {
+					Config:     Coco{Type: ENVIRONMENT, Name: "c3", Values: []ValueFile{}},
+					Path:       "values/c3/coco.yaml",
+					ValueFiles: []string{},
+				},
+			},
+		},
+		{
+			title: "missing folder",
+			files: fstest.MapFS{},
+			want:  map[string]Environment{},
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		got, err := ReadEnvironmentsFS(s.files, ".", "coco.yaml", []string{"values/"}, files.DefaultIgnoreFiles)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		if s.wantErr == nil {
+			testfuncs.CheckEqualityInterface(t, s.want, got)
+		}
+	}
+}
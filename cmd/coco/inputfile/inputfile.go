@@ -1,10 +1,15 @@
 package inputfile
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
 
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/yamlfile"
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,10 +18,261 @@ import (
 //
 //nolint:lll // no linebreaks available for struct tags
 type Coco struct {
-	Type         ConfigType `yaml:"type" doc:"msg=type of the configuration file,req,o=environment,o=component"`
-	Values       []string   `yaml:"values" doc:"msg=list relative paths to config files, req=for environments only"`
-	Name         string     `yaml:"name" doc:"msg=name of component or environment,req"`
-	Dependencies []string   `yaml:"dependencies" doc:"msg=list of components that this component depends on, req=for components only"`
+	Type         ConfigType        `yaml:"type" doc:"msg=type of the configuration file,req,o=environment,o=component,o=template"`
+	Values       []ValueFile       `yaml:"values" doc:"msg=list relative paths to config files (glob patterns allowed; a trailing '?' marks optional files), req=for environments only"`
+	Name         string            `yaml:"name" doc:"msg=name of component or environment,req"`
+	Labels       map[string]string `yaml:"labels" doc:"msg=labels of the environment (e.g. region: eu), req=for environments only"`
+	Dependencies []string          `yaml:"dependencies" doc:"msg=list of components that this component depends on, req=for components only"`
+	ArrayMerge   *ArrayMerge       `yaml:"arrayMerge"`
+	Sort         string            `yaml:"sort" doc:"msg=key order in generated yaml files, default=alphabetical, o=alphabetical, o=template, o=kubernetes, req=for templates only"`
+	Output       string            `yaml:"output" doc:"msg=go template for the output path relative to the template location (replaces <prefix>-<environment>), req=for templates only"`
+	ForEach      string            `yaml:"forEach" doc:"msg=path to a list in the environment values; the template is rendered once per element (e.g. .tenants), req=for templates only"`
+	ForEachKey   string            `yaml:"forEachKey" doc:"msg=key of the list elements that names the generated output, default=name, req=for templates only"`
+	Hooks        []Hook            `yaml:"hooks" doc:"msg=commands that run on the generated outputs, req=for templates only"`
+	Delimiters   *Delimiters       `yaml:"delimiters"`
+	RawCopy      []string          `yaml:"rawCopy" doc:"msg=patterns of files in .tmpl folders that are copied verbatim instead of rendered (files that are no valid UTF-8 are always copied), req=for templates only"`
+}
+
+// Hook is a command that runs after file generation on each generated file or
+// on each generated directory, e.g. a linter or a validator.
+//
+//nolint:lll // no linebreaks available for struct tags
+type Hook struct {
+	Name    string   `yaml:"name" doc:"msg=name of the hook in reports, default=the command"`
+	Command []string `yaml:"command" doc:"msg=command and arguments, {{ .Path }} is replaced by the generated file or directory (appended if missing),req"`
+	Scope   string   `yaml:"scope" doc:"msg=run on each generated file or once per generated directory,default=file,o=file,o=directory"`
+}
+
+const (
+	HookScopeFile      = "file"
+	HookScopeDirectory = "directory"
+)
+
+// Validate checks that the hook has a command and a known scope.
+func (h Hook) Validate() error {
+	if len(h.Command) == 0 {
+		return fmt.Errorf("hook %q has no command", h.Name)
+	}
+	switch h.Scope {
+	case "", HookScopeFile, HookScopeDirectory:
+		return nil
+	default:
+		return fmt.Errorf(
+			"unsupported hook scope: %q, available options: %+v",
+			h.Scope, []string{HookScopeDirectory, HookScopeFile},
+		)
+	}
+}
+
+// Title returns the name of the hook or its command if it has no name.
+func (h Hook) Title() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return strings.Join(h.Command, " ")
+}
+
+// Delimiters replace the default action delimiters "{{" and "}}" in the content
+// of templates, e.g. for templates of files that contain "{{ }}" themselves.
+//
+//nolint:lll // no linebreaks available for struct tags
+type Delimiters struct {
+	Left  string `yaml:"left" doc:"msg=left delimiter of template actions (e.g. [[),req"`
+	Right string `yaml:"right" doc:"msg=right delimiter of template actions (e.g. ]]),req"`
+}
+
+// Validate checks that both delimiters are set.
+func (d *Delimiters) Validate() error {
+	if d == nil {
+		return nil
+	}
+	if d.Left == "" || d.Right == "" {
+		return fmt.Errorf("delimiters require a left and a right delimiter, got %q and %q", d.Left, d.Right)
+	}
+	return nil
+}
+
+// SortMode returns the yamlfile settings for the configured key order of
+// generated yaml files (none if no sort mode is configured).
+func (c *Coco) SortMode() ([]yamlfile.UpdateSettingsFunc, error) {
+	if c.Sort == "" {
+		return []yamlfile.UpdateSettingsFunc{}, nil
+	}
+	m, err := yamlfile.ParseSortMode(c.Sort)
+	if err != nil {
+		return nil, err
+	}
+	return []yamlfile.UpdateSettingsFunc{yamlfile.SetSortMode(m)}, nil
+}
+
+// ArrayMerge configures how sequences are merged. For environments it governs
+// the merge of the values files (default: strict), for templates it governs
+// the merge of the previously generated files into the newly rendered ones
+// (default: standard).
+//
+//nolint:lll // no linebreaks available for struct tags
+type ArrayMerge struct {
+	Policy     string                `yaml:"policy" doc:"msg=array merge policy,o=strict,o=standard,o=append,o=by-key"`
+	Key        string                `yaml:"key" doc:"msg=map key that identifies sequence elements for the by-key policy,default=name"`
+	Exceptions []ArrayMergeException `yaml:"exceptions"`
+}
+
+// ArrayMergeException overwrites the array merge policy for the sequences that
+// match the path.
+//
+//nolint:lll // no linebreaks available for struct tags
+type ArrayMergeException struct {
+	Path   string `yaml:"path" doc:"msg=dot separated keys of the sequence ('*' matches any key),req"`
+	Policy string `yaml:"policy" doc:"msg=array merge policy,req,o=strict,o=standard,o=append,o=by-key"`
+	Key    string `yaml:"key" doc:"msg=map key that identifies sequence elements for the by-key policy"`
+}
+
+// Settings translates the array merge configuration into yamlfile settings. A nil
+// configuration results in no settings.
+func (a *ArrayMerge) Settings() ([]yamlfile.UpdateSettingsFunc, error) {
+	if a == nil {
+		return []yamlfile.UpdateSettingsFunc{}, nil
+	}
+	res := make([]yamlfile.UpdateSettingsFunc, 0, 2+len(a.Exceptions))
+	if a.Policy != "" {
+		p, err := yamlfile.ParseArrayMergePolicy(a.Policy)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, yamlfile.SetArrayMergePolicy(p))
+	}
+	if a.Key != "" {
+		res = append(res, yamlfile.SetArrayMergeKey(a.Key))
+	}
+	for _, e := range a.Exceptions {
+		if e.Path == "" {
+			return nil, fmt.Errorf("array merge exception without path")
+		}
+		p, err := yamlfile.ParseArrayMergePolicy(e.Policy)
+		if err != nil {
+			return nil, fmt.Errorf("array merge exception %q: %w", e.Path, err)
+		}
+		res = append(res, yamlfile.AddArrayMergeException(
+			yamlfile.ArrayMergeException{Path: e.Path, Policy: p, Key: e.Key},
+		))
+	}
+	return res, nil
+}
+
+// ValueFile references a values file of an environment. In the config file it
+// can either be given as a plain path or as a map with the keys "path" and
+// "optional". A plain path with a trailing "?" is a shorthand for an optional
+// file, e.g. "overrides.yaml?". The path may be a glob pattern (see
+// filepath.Match), e.g. "common/*.yaml". Trailing "?" are never wildcards, so
+// "values?" always is the optional file "values".
+//
+//nolint:lll // no linebreaks available for struct tags
+type ValueFile struct {
+	Path     string `yaml:"path" doc:"msg=path to the values file relative to the config file (glob patterns are allowed),req"`
+	Optional bool   `yaml:"optional" doc:"msg=missing files (or globs without matches) are ignored instead of failing,default=false"`
+}
+
+const optionalSuffix = "?"
+
+func (v *ValueFile) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		v.Path = n.Value
+		if strings.HasSuffix(v.Path, optionalSuffix) {
+			v.Path = strings.TrimSuffix(v.Path, optionalSuffix)
+			v.Optional = true
+		}
+		return nil
+	}
+	type plain ValueFile
+	var p plain
+	if err := n.Decode(&p); err != nil {
+		return err
+	}
+	*v = ValueFile(p)
+	if strings.HasSuffix(v.Path, optionalSuffix) {
+		v.Path = strings.TrimSuffix(v.Path, optionalSuffix)
+		v.Optional = true
+	}
+	return nil
+}
+
+// ResolveValues turns the values list of the config file into an ordered list of
+// existing file paths. All paths are interpreted relative to dir. Glob patterns
+// are expanded in lexical order, so that the merge order is deterministic.
+// A missing file (or a glob without any match) results in an error unless the
+// entry is marked as optional.
+func (c *Coco) ResolveValues(dir string) ([]string, error) {
+	return c.resolveValues(filesFS{files.OS()}, dir)
+}
+
+func (c *Coco) resolveValues(fsys fileSystem, dir string) ([]string, error) {
+	res := make([]string, 0, len(c.Values))
+	for _, v := range c.Values {
+		if v.Path == "" {
+			return nil, fmt.Errorf("empty values path in %q (%s)", c.Name, dir)
+		}
+		p := fsys.Join(dir, v.Path)
+		matches, err := matchFiles(fsys, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve values path %q: %w", v.Path, err)
+		}
+		if len(matches) == 0 {
+			if v.Optional {
+				continue
+			}
+			return nil, fmt.Errorf(
+				"values file %q of %q does not exist: %w", p, c.Name, fs.ErrNotExist,
+			)
+		}
+		res = append(res, matches...)
+	}
+	return res, nil
+}
+
+// matchFiles returns all regular files that match the pattern p in lexical order.
+func matchFiles(fsys fileSystem, p string) ([]string, error) {
+	pattern, glob := globPattern(p)
+	if !glob {
+		info, err := fsys.Stat(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return []string{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%q is a directory", p)
+		}
+		return []string{p}, nil
+	}
+	matches, err := fsys.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(matches))
+	for _, m := range matches {
+		info, err := fsys.Stat(m)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		res = append(res, m)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// globPattern returns the glob pattern for p and whether p is a pattern at all.
+// A trailing "?" is reserved for optional values files and is never a wildcard:
+// it does not make p a pattern and is matched literally within patterns.
+func globPattern(p string) (string, bool) {
+	trimmed := strings.TrimRight(p, optionalSuffix)
+	if !strings.ContainsAny(trimmed, "*?[") {
+		return p, false
+	}
+	return trimmed + strings.Repeat("[?]", len(p)-len(trimmed)), true
 }
 
 // Types of config files.
@@ -26,19 +282,32 @@ type ConfigType string
 const (
 	COMPONENT   ConfigType = "component"
 	ENVIRONMENT ConfigType = "environment"
+	TEMPLATE    ConfigType = "template"
 )
 
-var AllConfigTypes = map[ConfigType]bool{COMPONENT: true, ENVIRONMENT: true}
+var AllConfigTypes = map[ConfigType]bool{COMPONENT: true, ENVIRONMENT: true, TEMPLATE: true}
 
 // Receives a file path and reads the byte content into a Coco struct
 // File should be a yaml containing at least a valid type key
 func Load(file string) (Coco, error) {
-	content, err := files.Read(file)
+	return LoadFrom(files.OS(), file)
+}
+
+// LoadFrom reads the config file from fsys (see Load).
+func LoadFrom(fsys files.FS, file string) (Coco, error) {
+	content, err := fsys.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		content, err = []byte{}, nil
+	}
 	if err != nil {
 		return Coco{}, err
 	}
+	return parse(content)
+}
+
+func parse(content []byte) (Coco, error) {
 	res := Coco{}
-	err = yaml.Unmarshal(content, &res)
+	err := yaml.Unmarshal(content, &res)
 	if err != nil {
 		return Coco{}, err
 	}
@@ -55,14 +324,28 @@ func Load(file string) (Coco, error) {
 	return res, nil
 }
 
+// FindAll reads all files named configFileName below basepath in fsys that pass
+// the filters. Filters are parsed with files.ParseFilter, so they can be substrings
+// of the path (default), globs ("glob:") or regular expressions ("regex:"). Paths
+// listed in the ignoreFiles (see files.FileRunner.IgnoreFiles) are skipped.
 func FindAll(
-	basepath, configFileName string, includeOr, includeAnd, exclude []string,
+	fsys files.FS, basepath, configFileName string, includeOr, includeAnd, exclude, ignoreFiles []string,
 ) (map[string]files.File, error) {
-	includeAnd = append(includeAnd, configFileName)
+	filters := make([][]files.Filter, 3)
+	for i, f := range [][]string{includeOr, includeAnd, exclude} {
+		parsed, err := files.ParseFilters(f)
+		if err != nil {
+			return nil, err
+		}
+		filters[i] = parsed
+	}
 	fileRunner := files.New(basepath).
-		Include(files.OR, includeOr).
-		Include(files.AND, includeAnd).
-		Exclude(files.OR, exclude).
+		FileSystem(fsys).
+		IgnoreFiles(ignoreFiles...).
+		IncludeFilters(files.OR, filters[0]...).
+		IncludeFilters(files.AND, filters[1]...).
+		Include(files.AND, []string{configFileName}).
+		ExcludeFilters(files.OR, filters[2]...).
 		ReadContent()
 	vFiles, err := fileRunner.Execute()
 	if err != nil {
@@ -78,3 +361,7 @@ func (c *Coco) IsComponent() bool {
 func (c *Coco) IsEnvironment() bool {
 	return c.Type == ENVIRONMENT
 }
+
+func (c *Coco) IsTemplate() bool {
+	return c.Type == TEMPLATE
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/log"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
 	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
@@ -46,7 +47,35 @@ values:
 			{
 				Type:   ENVIRONMENT,
 				Name:   "name1",
-				Values: []string{"file1", "file2"},
+				Values: []ValueFile{{Path: "file1"}, {Path: "file2"}},
+			},
+		},
+		wantErr: nil,
+	},
+	{
+		title: "Optional and map-style value files",
+		input: map[string][]byte{
+			"coco": []byte(`
+type: environment
+name: name1
+values:
+  - file1
+  - overrides?
+  - path: common/*.yaml
+  - path: optional
+    optional: true
+`),
+		},
+		want: []Coco{
+			{
+				Type: ENVIRONMENT,
+				Name: "name1",
+				Values: []ValueFile{
+					{Path: "file1"},
+					{Path: "overrides", Optional: true},
+					{Path: "common/*.yaml"},
+					{Path: "optional", Optional: true},
+				},
 			},
 		},
 		wantErr: nil,
@@ -174,6 +203,103 @@ values:
 	},
 }
 
+type inputResolveValues struct {
+	title   string
+	files   map[string][]byte
+	values  []ValueFile
+	want    []string
+	wantErr error
+}
+
+var inputsResolveValues = []inputResolveValues{
+	{
+		title: "plain files keep their order",
+		files: map[string][]byte{"b.yaml": nil, "a.yaml": nil},
+		values: []ValueFile{
+			{Path: "b.yaml"}, {Path: "a.yaml"},
+		},
+		want: []string{"b.yaml", "a.yaml"},
+	},
+	{
+		title: "glob patterns are expanded in lexical order",
+		files: map[string][]byte{
+			"common/b.yaml": nil, "common/a.yaml": nil, "common/c.txt": nil, "env.yaml": nil,
+		},
+		values: []ValueFile{
+			{Path: "common/*.yaml"}, {Path: "env.yaml"},
+		},
+		want: []string{"common/a.yaml", "common/b.yaml", "env.yaml"},
+	},
+	{
+		title: "optional files may be missing",
+		files: map[string][]byte{"a.yaml": nil},
+		values: []ValueFile{
+			{Path: "a.yaml"}, {Path: "overrides.yaml", Optional: true},
+			{Path: "missing/*.yaml", Optional: true},
+		},
+		want: []string{"a.yaml"},
+	},
+	{
+		title: "trailing question marks are no wildcards",
+		files: map[string][]byte{"a": nil, "a?": nil, "b1?": nil, "b2?": nil, "b3": nil},
+		values: []ValueFile{
+			{Path: "a?"}, {Path: "b*?"},
+		},
+		want: []string{"a?", "b1?", "b2?"},
+	},
+	{
+		title:   "missing files are an error",
+		files:   map[string][]byte{"a.yaml": nil},
+		values:  []ValueFile{{Path: "a.yaml"}, {Path: "typo.yaml"}},
+		wantErr: fmt.Errorf("values file \"${ROOT}/typo.yaml\" of \"env\" does not exist: file does not exist"),
+	},
+	{
+		title:   "glob without matches is an error",
+		files:   map[string][]byte{"a.yaml": nil},
+		values:  []ValueFile{{Path: "common/*.yaml"}},
+		wantErr: fmt.Errorf("values file \"${ROOT}/common/*.yaml\" of \"env\" does not exist: file does not exist"),
+	},
+}
+
+func TestResolveValues(t *testing.T) {
+	for _, i := range inputsResolveValues {
+		t.Logf("test scenario: %s\n", i.title)
+		i.resolve(t)
+	}
+}
+
+func (i *inputResolveValues) resolve(t *testing.T) {
+	td, err := testfuncs.PrepareTestDirTree(i.files)
+	if err != nil {
+		t.Logf("unable to create test dir tree: %v\n", err)
+		t.FailNow()
+	}
+	defer td.Cleanup(t)
+	tmpDir := td.Path()
+
+	c := Coco{Type: ENVIRONMENT, Name: "env", Values: i.values}
+	got, err := c.ResolveValues(tmpDir)
+	var wantErr error
+	if i.wantErr != nil {
+		wantErr = fmt.Errorf("%s", strings.ReplaceAll(i.wantErr.Error(), "${ROOT}", tmpDir))
+	}
+	testfuncs.CheckErrs(t, wantErr, err)
+	if i.wantErr != nil {
+		return
+	}
+	res := make([]string, 0, len(got))
+	for _, p := range got {
+		res = append(res, strings.TrimPrefix(p, tmpDir+"/"))
+	}
+	if !reflect.DeepEqual(res, i.want) {
+		t.Errorf("results do not match: \nwant = \"%+v\"\ngot =  \"%+v\"",
+			i.want,
+			res,
+		)
+		t.Fail()
+	}
+}
+
 func TestFindAll(t *testing.T) {
 	if err := log.Init(log.Debug(), "", true); err != nil {
 		zap.S().Fatal(err)
@@ -202,13 +328,15 @@ func (i *inputFindAll) findAll(t *testing.T) {
 	}
 	defer td.Cleanup(t)
 	tmpDir := td.Path()
-	files, err := FindAll(tmpDir, i.configFileName, i.includeOrFilters, i.includeAndFilter, i.excludeFilter)
+	found, err := FindAll(
+		files.OS(), tmpDir, i.configFileName, i.includeOrFilters, i.includeAndFilter, i.excludeFilter, nil,
+	)
 	if err != nil {
 		t.Error(err)
 		t.FailNow()
 	}
 	var res = map[string][]byte{}
-	for p, v := range files {
+	for p, v := range found {
 		res[strings.Replace(p, tmpDir, "", 1)] = v.Content
 	}
 	if !reflect.DeepEqual(res, i.want) {
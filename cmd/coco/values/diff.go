@@ -0,0 +1,152 @@
+package values
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
+	"gopkg.in/yaml.v3"
+)
+
+// ChangeType classifies a difference between two sets of values.
+type ChangeType string
+
+const (
+	Added   ChangeType = "+"
+	Removed ChangeType = "-"
+	Changed ChangeType = "~"
+)
+
+// Change is a difference at a path of two sets of values, e.g. "a.b[0].c". From
+// is nil for added and To is nil for removed paths.
+type Change struct {
+	Path string
+	Type ChangeType
+	From interface{}
+	To   interface{}
+}
+
+// Diff returns the differences between the values from and to. Maps are compared
+// key by key and lists element by element. Added or removed maps and lists are
+// reported as a whole. Nil values (e.g. of missing environments) are compared
+// as empty maps.
+func Diff(from, to interface{}) []Change {
+	res := []Change{}
+	if from == nil {
+		from = map[string]interface{}{}
+	}
+	if to == nil {
+		to = map[string]interface{}{}
+	}
+	diff(from, to, "", &res)
+	return res
+}
+
+func diff(from, to interface{}, path string, res *[]Change) {
+	switch f := from.(type) {
+	case map[string]interface{}:
+		t, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for k := range f {
+			keys[k] = true
+		}
+		for k := range t {
+			keys[k] = true
+		}
+		for _, k := range maputils.KeysSorted(keys) {
+			p := joinKey(path, k)
+			fv, inFrom := f[k]
+			tv, inTo := t[k]
+			switch {
+			case !inFrom:
+				*res = append(*res, Change{Path: p, Type: Added, To: tv})
+			case !inTo:
+				*res = append(*res, Change{Path: p, Type: Removed, From: fv})
+			default:
+				diff(fv, tv, p, res)
+			}
+		}
+		return
+	case []interface{}:
+		t, ok := to.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(f) || i < len(t); i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(f):
+				*res = append(*res, Change{Path: p, Type: Added, To: t[i]})
+			case i >= len(t):
+				*res = append(*res, Change{Path: p, Type: Removed, From: f[i]})
+			default:
+				diff(f[i], t[i], p, res)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(from, to) {
+		*res = append(*res, Change{Path: path, Type: Changed, From: from, To: to})
+	}
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// PrintDiff writes one line per change, e.g.
+//
+//	~ ingress.className: traefik -> nginx
+//	+ ingress.tls: true
+//	- replicas: 2
+func PrintDiff(w io.Writer, changes []Change) error {
+	var b bytes.Buffer
+	for _, c := range changes {
+		path := c.Path
+		if path == "" {
+			path = "(root)"
+		}
+		switch c.Type {
+		case Added:
+			fmt.Fprintf(&b, "%s %s: %s\n", c.Type, path, inlineValue(c.To))
+		case Removed:
+			fmt.Fprintf(&b, "%s %s: %s\n", c.Type, path, inlineValue(c.From))
+		default:
+			fmt.Fprintf(&b, "%s %s: %s -> %s\n", c.Type, path, inlineValue(c.From), inlineValue(c.To))
+		}
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// inlineValue renders the value as single line (yaml flow style).
+func inlineValue(v interface{}) string {
+	var n yaml.Node
+	if err := n.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	setFlowStyle(&n)
+	out, err := yaml.Marshal(&n)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func setFlowStyle(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+		n.Style |= yaml.FlowStyle
+	}
+	for _, c := range n.Content {
+		setFlowStyle(c)
+	}
+}
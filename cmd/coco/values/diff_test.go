@@ -0,0 +1,51 @@
+package values
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestDiff(t *testing.T) {
+	from := map[string]interface{}{
+		"ingress":  map[string]interface{}{"className": "traefik", "annotations": map[string]interface{}{"a": "1"}},
+		"replicas": 2,
+		"list":     []interface{}{1, map[string]interface{}{"name": "x", "port": 80}},
+		"type":     map[string]interface{}{"a": 1},
+	}
+	to := map[string]interface{}{
+		"ingress": map[string]interface{}{"className": "nginx", "annotations": map[string]interface{}{"a": "1"}},
+		"tls":     map[string]interface{}{"enabled": true, "hosts": []interface{}{"a.b"}},
+		"list":    []interface{}{1, map[string]interface{}{"name": "x", "port": 81}, 3},
+		"type":    "scalar",
+	}
+	got := Diff(from, to)
+	want := []Change{
+		{Path: "ingress.className", Type: Changed, From: "traefik", To: "nginx"},
+		{Path: "list[1].port", Type: Changed, From: 80, To: 81},
+		{Path: "list[2]", Type: Added, To: 3},
+		{Path: "replicas", Type: Removed, From: 2},
+		{Path: "tls", Type: Added, To: map[string]interface{}{"enabled": true, "hosts": []interface{}{"a.b"}}},
+		{Path: "type", Type: Changed, From: map[string]interface{}{"a": 1}, To: "scalar"},
+	}
+	testfuncs.CheckEqualityInterface(t, want, got)
+
+	var b bytes.Buffer
+	testfuncs.MustBeNil(t, PrintDiff(&b, got))
+	testfuncs.CheckEqualityInterface(t, `~ ingress.className: traefik -> nginx
+~ list[1].port: 80 -> 81
++ list[2]: 3
+- replicas: 2
++ tls: {enabled: true, hosts: [a.b]}
+~ type: {a: 1} -> scalar
+`, b.String())
+
+	testfuncs.CheckEqualityInterface(t, []Change{}, Diff(from, from))
+}
+
+func TestDiffMissingValues(t *testing.T) {
+	values := map[string]interface{}{"a": 1}
+	testfuncs.CheckEqualityInterface(t, []Change{{Path: "a", Type: Added, To: 1}}, Diff(nil, values))
+	testfuncs.CheckEqualityInterface(t, []Change{{Path: "a", Type: Removed, From: 1}}, Diff(values, nil))
+}
@@ -0,0 +1,183 @@
+package values
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/yamlfile"
+	"gopkg.in/yaml.v3"
+)
+
+// Origin is a location in a value file that sets a value.
+type Origin struct {
+	File   string
+	Line   int
+	Column int
+	// Value is the value that is set at the location
+	Value interface{}
+}
+
+// Explanation describes how the value of a key is merged from the value files
+// of an environment.
+type Explanation struct {
+	Environment string
+	Key         string
+	// Value is the merged value
+	Value interface{}
+	// SetBy is the origin of the merged value. It is nil if the value is merged
+	// from several files (maps and lists that are not merged strictly).
+	SetBy *Origin
+	// Overridden holds the values that were replaced by the merged value in
+	// merge order. If SetBy is nil, it holds all contributing values instead.
+	Overridden []Origin
+}
+
+// Explain merges the value files of the environment and returns the origins of
+// the value of the dot separated key (e.g. "ingress.className"). Elements of lists
+// are addressed by their index (e.g. "ingress.hosts.0").
+func Explain(fsys files.FS, env inputfile.Environment, key string) (Explanation, error) {
+	merged, origins, err := env.MergeWithOrigins(fsys)
+	if err != nil {
+		return Explanation{}, err
+	}
+	node := lookup(merged.Node, strings.Split(key, "."))
+	if node == nil {
+		return Explanation{}, fmt.Errorf("key %q is not set in environment %q", key, env.Config.Name)
+	}
+	res := Explanation{Environment: env.Config.Name, Key: key, Overridden: []Origin{}}
+	if err := node.Decode(&res.Value); err != nil {
+		return Explanation{}, fmt.Errorf("failed to decode %q: %w", key, err)
+	}
+
+	recorded := origins[node]
+	if len(recorded) == 0 {
+		return res, nil
+	}
+	last := len(recorded) - 1
+	if recorded[last].Replaced {
+		setBy, err := origin(recorded[last])
+		if err != nil {
+			return Explanation{}, err
+		}
+		res.SetBy = &setBy
+		return res, appendOrigins(&res.Overridden, recorded[:last])
+	}
+	// a merged value consists of the contributions since it has been set last
+	first := 0
+	for i, o := range recorded {
+		if o.Replaced {
+			first = i
+		}
+	}
+	return res, appendOrigins(&res.Overridden, recorded[first:])
+}
+
+// lookup returns the node at the path in the merged values (nil if it is not
+// set). Path elements are map keys or indexes of sequence elements.
+func lookup(n *yaml.Node, path []string) *yaml.Node {
+	if n == nil || n.Kind == yaml.DocumentNode && len(n.Content) == 0 {
+		return nil
+	}
+	if n.Kind == yaml.DocumentNode {
+		n = n.Content[0]
+	}
+	for _, k := range path {
+		for n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+		switch n.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == k {
+					next = n.Content[i+1]
+				}
+			}
+			if next == nil {
+				return nil
+			}
+			n = next
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(n.Content) {
+				return nil
+			}
+			n = n.Content[i]
+		default:
+			return nil
+		}
+	}
+	return n
+}
+
+// origin returns the location and value of the recorded origin. The location is
+// the one of the map key, or of the value itself for list elements.
+func origin(o yamlfile.Origin) (Origin, error) {
+	pos := o.Node
+	if o.Key != nil {
+		pos = o.Key
+	}
+	var value interface{}
+	if err := o.Node.Decode(&value); err != nil {
+		return Origin{}, fmt.Errorf("failed to decode value in %q: %w", o.Source, err)
+	}
+	return Origin{File: o.Source, Line: pos.Line, Column: pos.Column, Value: value}, nil
+}
+
+func appendOrigins(res *[]Origin, recorded []yamlfile.Origin) error {
+	for _, r := range recorded {
+		o, err := origin(r)
+		if err != nil {
+			return err
+		}
+		*res = append(*res, o)
+	}
+	return nil
+}
+
+// Print writes the explanation in a human readable format. File paths are
+// shown relative to basepath.
+func (e Explanation) Print(w io.Writer, basepath string) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "environment: %s\nkey: %s\n", e.Environment, e.Key)
+	fmt.Fprintf(&b, "value:%s\n", formatValue(e.Value, "  "))
+	switch {
+	case e.SetBy != nil:
+		fmt.Fprintf(&b, "set in: %s\n", e.SetBy.location(basepath))
+		if len(e.Overridden) > 0 {
+			b.WriteString("overrides:\n")
+		}
+	case len(e.Overridden) > 0:
+		b.WriteString("merged from:\n")
+	}
+	for _, o := range e.Overridden {
+		fmt.Fprintf(&b, "  - %s:%s\n", o.location(basepath), formatValue(o.Value, "      "))
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func (o Origin) location(basepath string) string {
+	return fmt.Sprintf("%s:%d:%d", relativePath(basepath, o.File), o.Line, o.Column)
+}
+
+// formatValue renders scalars inline (with a leading space) and maps and lists as
+// indented yaml block.
+func formatValue(v interface{}, indent string) string {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+		return "\n" + indent + strings.Join(lines, "\n"+indent)
+	default:
+		return " " + strings.TrimSpace(string(out))
+	}
+}
@@ -0,0 +1,191 @@
+package values
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestExplain(t *testing.T) {
+	dir := t.TempDir()
+	tree := map[string]string{
+		"coco.yaml": "type: environment\nname: cluster_2\nvalues:\n  - common.yaml\n  - region.yaml\n  - cluster.yaml\n" +
+			"arrayMerge:\n  exceptions:\n    - path: ingress.hosts\n      policy: append\n",
+		"common.yaml": "defaults: &defaults\n  className: traefik\n" +
+			"ingress:\n  <<: *defaults\n  annotations:\n    a: common\n  hosts: [a.example.com]\n" +
+			"zones: [eu-1]\nreplicas: 2\n",
+		"region.yaml":  "ingress:\n  className: haproxy\nzones: [eu-2]\nreplicas: 3\n",
+		"cluster.yaml": "ingress:\n  className: nginx\n  annotations:\n    b: cluster\n  hosts: [b.example.com]\nreplicas: 2\n",
+	}
+	for name, content := range tree {
+		testfuncs.MustBeNil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	envs, err := inputfile.ReadEnvironments(files.OS(), dir, "coco.yaml", []string{}, []string{}, []string{}, nil)
+	testfuncs.MustBeNil(t, err)
+	env := envs["cluster_2"]
+	p := func(name string) string { return filepath.Join(dir, name) }
+
+	for _, s := range []struct {
+		title     string
+		key       string
+		want      Explanation
+		wantPrint string
+		wantErr   error
+	}{
+		{
+			title: "overridden scalar",
+			key:   "ingress.className",
+			want: Explanation{
+				Environment: "cluster_2",
+				Key:         "ingress.className",
+				Value:       "nginx",
+				SetBy:       &Origin{File: p("cluster.yaml"), Line: 2, Column: 3, Value: "nginx"},
+				Overridden: []Origin{
+					{File: p("common.yaml"), Line: 2, Column: 3, Value: "traefik"},
+					{File: p("region.yaml"), Line: 2, Column: 3, Value: "haproxy"},
+				},
+			},
+			wantPrint: `environment: cluster_2
+key: ingress.className
+value: nginx
+set in: cluster.yaml:2:3
+overrides:
+  - common.yaml:2:3: traefik
+  - region.yaml:2:3: haproxy
+`,
+		},
+		{
+			title: "merged map",
+			key:   "ingress.annotations",
+			want: Explanation{
+				Environment: "cluster_2",
+				Key:         "ingress.annotations",
+				Value:       map[string]interface{}{"a": "common", "b": "cluster"},
+				Overridden: []Origin{
+					{File: p("common.yaml"), Line: 5, Column: 3, Value: map[string]interface{}{"a": "common"}},
+					{File: p("cluster.yaml"), Line: 3, Column: 3, Value: map[string]interface{}{"b": "cluster"}},
+				},
+			},
+			wantPrint: `environment: cluster_2
+key: ingress.annotations
+value:
+  a: common
+  b: cluster
+merged from:
+  - common.yaml:5:3:
+      a: common
+  - cluster.yaml:3:3:
+      b: cluster
+`,
+		},
+		{
+			title: "equal values",
+			key:   "replicas",
+			want: Explanation{
+				Environment: "cluster_2",
+				Key:         "replicas",
+				Value:       2,
+				SetBy:       &Origin{File: p("cluster.yaml"), Line: 6, Column: 1, Value: 2},
+				Overridden: []Origin{
+					{File: p("common.yaml"), Line: 9, Column: 1, Value: 2},
+					{File: p("region.yaml"), Line: 4, Column: 1, Value: 3},
+				},
+			},
+			wantPrint: `environment: cluster_2
+key: replicas
+value: 2
+set in: cluster.yaml:6:1
+overrides:
+  - common.yaml:9:1: 2
+  - region.yaml:4:1: 3
+`,
+		},
+		{
+			title: "strictly merged list",
+			key:   "zones",
+			want: Explanation{
+				Environment: "cluster_2",
+				Key:         "zones",
+				Value:       []interface{}{"eu-2"},
+				SetBy:       &Origin{File: p("region.yaml"), Line: 3, Column: 1, Value: []interface{}{"eu-2"}},
+				Overridden:  []Origin{{File: p("common.yaml"), Line: 8, Column: 1, Value: []interface{}{"eu-1"}}},
+			},
+			wantPrint: `environment: cluster_2
+key: zones
+value:
+  - eu-2
+set in: region.yaml:3:1
+overrides:
+  - common.yaml:8:1:
+      - eu-1
+`,
+		},
+		{
+			title: "appended list",
+			key:   "ingress.hosts",
+			want: Explanation{
+				Environment: "cluster_2",
+				Key:         "ingress.hosts",
+				Value:       []interface{}{"a.example.com", "b.example.com"},
+				Overridden: []Origin{
+					{File: p("common.yaml"), Line: 7, Column: 3, Value: []interface{}{"a.example.com"}},
+					{File: p("cluster.yaml"), Line: 5, Column: 3, Value: []interface{}{"b.example.com"}},
+				},
+			},
+			wantPrint: `environment: cluster_2
+key: ingress.hosts
+value:
+  - a.example.com
+  - b.example.com
+merged from:
+  - common.yaml:7:3:
+      - a.example.com
+  - cluster.yaml:5:3:
+      - b.example.com
+`,
+		},
+		{
+			title: "list element",
+			key:   "ingress.hosts.1",
+			want: Explanation{
+				Environment: "cluster_2",
+				Key:         "ingress.hosts.1",
+				Value:       "b.example.com",
+				SetBy:       &Origin{File: p("cluster.yaml"), Line: 5, Column: 11, Value: "b.example.com"},
+				Overridden:  []Origin{},
+			},
+			wantPrint: `environment: cluster_2
+key: ingress.hosts.1
+value: b.example.com
+set in: cluster.yaml:5:11
+`,
+		},
+		{
+			title:   "unknown key",
+			key:     "ingress.tls",
+			wantErr: errors.New(`key "ingress.tls" is not set in environment "cluster_2"`),
+		},
+		{
+			title:   "unknown list element",
+			key:     "ingress.hosts.2",
+			wantErr: errors.New(`key "ingress.hosts.2" is not set in environment "cluster_2"`),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		got, err := Explain(files.OS(), env, s.key)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		if s.wantErr != nil {
+			continue
+		}
+		testfuncs.CheckEqualityInterface(t, s.want, got)
+		var b bytes.Buffer
+		testfuncs.MustBeNil(t, got.Print(&b, dir))
+		testfuncs.CheckEqualityInterface(t, s.wantPrint, b.String())
+	}
+}
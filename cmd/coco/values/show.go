@@ -0,0 +1,44 @@
+package values
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of Show.
+const (
+	FormatYaml = "yaml"
+	FormatJSON = "json"
+)
+
+// Show writes the merged values of the environment in the format (yaml or
+// json). Map keys are sorted alphabetically.
+func Show(w io.Writer, env inputfile.Environment, format string) error {
+	var out []byte
+	var err error
+	switch format {
+	case FormatYaml, "":
+		var b bytes.Buffer
+		e := yaml.NewEncoder(&b)
+		e.SetIndent(2)
+		err = e.Encode(env.Values)
+		out = b.Bytes()
+	case FormatJSON:
+		out, err = json.MarshalIndent(env.Values, "", "  ")
+		out = append(out, '\n')
+	default:
+		return fmt.Errorf(
+			"unsupported format: %q, available options: %+v", format, []string{FormatJSON, FormatYaml},
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode values of %q: %w", env.Config.Name, err)
+	}
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,46 @@
+package values
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestShow(t *testing.T) {
+	env := inputfile.Environment{
+		Config: inputfile.Coco{Name: "c1"},
+		Values: map[string]interface{}{
+			"ingress": map[string]interface{}{"className": "nginx"},
+			"list":    []interface{}{1, "a"},
+		},
+	}
+	for _, s := range []struct {
+		format  string
+		want    string
+		wantErr error
+	}{
+		{
+			format: FormatYaml,
+			want:   "ingress:\n  className: nginx\nlist:\n  - 1\n  - a\n",
+		},
+		{
+			format: FormatJSON,
+			want:   "{\n  \"ingress\": {\n    \"className\": \"nginx\"\n  },\n  \"list\": [\n    1,\n    \"a\"\n  ]\n}\n",
+		},
+		{
+			format:  "toml",
+			wantErr: errors.New(`unsupported format: "toml", available options: [json yaml]`),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.format)
+		var b bytes.Buffer
+		err := Show(&b, env, s.format)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		if s.wantErr == nil {
+			testfuncs.CheckEqualityInterface(t, s.want, b.String())
+		}
+	}
+}
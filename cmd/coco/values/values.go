@@ -0,0 +1,105 @@
+// Package values inspects the merged values of environments (see ./readme.md).
+package values
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/cmd/coco/inputfile"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/git"
+)
+
+// ErrEnvironmentNotFound is returned if no environment with the requested name
+// exists.
+var ErrEnvironmentNotFound = errors.New("environment not found")
+
+// ReadEnvironment returns the environment with the given name from the
+// environment config files in the value folders. Paths listed in the ignoreFiles
+// are skipped (see files.FileRunner.IgnoreFiles).
+func ReadEnvironment(
+	basepath, configFileName string, valueFolders, ignoreFiles []string, name string,
+) (inputfile.Environment, error) {
+	envs, err := inputfile.ReadEnvironments(
+		files.OS(), basepath, configFileName, valueFolders, []string{}, []string{}, ignoreFiles,
+	)
+	if err != nil {
+		return inputfile.Environment{}, err
+	}
+	return lookupEnvironment(envs, name, "")
+}
+
+// ReadEnvironmentAt returns the environment with the given name as of the git
+// revision (e.g. "origin/main" or "HEAD") without checking it out. basepath must
+// be located in a git repository; the value folders are relative to basepath.
+// Like ReadEnvironment, paths listed in the ignoreFiles of the tree are skipped.
+func ReadEnvironmentAt(
+	basepath, revision, configFileName string, valueFolders, ignoreFiles []string, name string,
+) (inputfile.Environment, error) {
+	repo, err := git.Open(basepath)
+	if err != nil {
+		return inputfile.Environment{}, fmt.Errorf("failed to open git repository in %q: %w", basepath, err)
+	}
+	root, err := repo.Root()
+	if err != nil {
+		return inputfile.Environment{}, err
+	}
+	tree, err := repo.Tree(revision)
+	if err != nil {
+		return inputfile.Environment{}, err
+	}
+	treePath := func(f string) (string, error) {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(basepath, f)
+		}
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil {
+			return "", err
+		}
+		return filepath.ToSlash(rel), nil
+	}
+	folders := make([]string, 0, len(valueFolders))
+	for _, f := range valueFolders {
+		folder, err := treePath(f)
+		if err != nil {
+			return inputfile.Environment{}, err
+		}
+		folders = append(folders, folder)
+	}
+	base, err := treePath(basepath)
+	if err != nil {
+		return inputfile.Environment{}, err
+	}
+	envs, err := inputfile.ReadEnvironmentsFS(tree.FS(), base, configFileName, folders, ignoreFiles)
+	if err != nil {
+		return inputfile.Environment{}, fmt.Errorf("failed to read environments at %q: %w", revision, err)
+	}
+	return lookupEnvironment(envs, name, revision)
+}
+
+func lookupEnvironment(
+	envs map[string]inputfile.Environment, name, revision string,
+) (inputfile.Environment, error) {
+	env, ok := envs[name]
+	if !ok && revision != "" {
+		return inputfile.Environment{}, fmt.Errorf("%w: %q at %q", ErrEnvironmentNotFound, name, revision)
+	}
+	if !ok {
+		return inputfile.Environment{}, fmt.Errorf("%w: %q", ErrEnvironmentNotFound, name)
+	}
+	return env, nil
+}
+
+// relativePath returns the path relative to basepath if possible.
+func relativePath(basepath, path string) string {
+	rel, err := filepath.Rel(basepath, path)
+	if err != nil || basepath == "" {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,98 @@
+package values
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestReadEnvironmentAt(t *testing.T) {
+	dir := t.TempDir()
+	client, err := git.PlainInit(dir, false)
+	testfuncs.MustBeNil(t, err)
+	w, err := client.Worktree()
+	testfuncs.MustBeNil(t, err)
+	commit := func(files map[string]string) {
+		for name, content := range files {
+			p := filepath.Join(dir, name)
+			testfuncs.MustBeNil(t, os.MkdirAll(filepath.Dir(p), 0o755))
+			testfuncs.MustBeNil(t, os.WriteFile(p, []byte(content), 0o644))
+			_, err := w.Add(name)
+			testfuncs.MustBeNil(t, err)
+		}
+		_, err := w.Commit("commit", &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		testfuncs.MustBeNil(t, err)
+	}
+	commit(map[string]string{
+		"config/values/common.yaml":  "replicas: 1\n",
+		"config/values/c1/coco.yaml": "type: environment\nname: c1\nvalues:\n  - ../common.yaml\n",
+	})
+	commit(map[string]string{
+		"config/values/c1/coco.yaml": "type: environment\nname: c1\nvalues:\n  - ../common.yaml\n  - c1.yaml\n",
+		"config/values/c1/c1.yaml":   "replicas: 3\n",
+		// ignored like in the working tree
+		"config/.cocoignore":                  "archived/\n",
+		"config/values/archived/c3/coco.yaml": "type: environment\nname: c3\nvalues: []\n",
+	})
+	// uncommitted changes are ignored
+	testfuncs.MustBeNil(t, os.WriteFile(filepath.Join(dir, "config/values/c1/c1.yaml"), []byte("replicas: 5\n"), 0o644))
+	basepath := filepath.Join(dir, "config")
+
+	for _, s := range []struct {
+		title    string
+		revision string
+		env      string
+		want     interface{}
+		wantErr  error
+	}{
+		{
+			title:    "previous commit",
+			revision: "HEAD~1",
+			env:      "c1",
+			want:     map[string]interface{}{"replicas": 1},
+		},
+		{
+			title:    "head",
+			revision: "HEAD",
+			env:      "c1",
+			want:     map[string]interface{}{"replicas": 3},
+		},
+		{
+			title:    "unknown environment",
+			revision: "HEAD",
+			env:      "c2",
+			wantErr:  errors.New(`environment not found: "c2" at "HEAD"`),
+		},
+		{
+			title:    "ignored environment",
+			revision: "HEAD",
+			env:      "c3",
+			wantErr:  errors.New(`environment not found: "c3" at "HEAD"`),
+		},
+		{
+			title:    "unknown revision",
+			revision: "unknown",
+			env:      "c1",
+			wantErr:  errors.New(`failed to resolve revision "unknown": reference not found`),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		env, err := ReadEnvironmentAt(
+			basepath, s.revision, "coco.yaml", []string{"values"}, files.DefaultIgnoreFiles, s.env,
+		)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		if s.wantErr != nil {
+			continue
+		}
+		testfuncs.CheckEqualityInterface(t, s.want, env.Values)
+	}
+}
@@ -0,0 +1,56 @@
+package exec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitArgs splits a command line into its arguments like a POSIX shell without
+// any expansions: arguments are separated by whitespace, single quotes preserve
+// their content literally, double quotes preserve their content except for
+// backslash escapes of '"' and '\', and a backslash outside of quotes escapes
+// the next character.
+func SplitArgs(command string) ([]string, error) {
+	res := []string{}
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				arg.WriteRune('\\')
+			}
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				res = append(res, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("command %q ends with an escape character", command)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("command %q has an unterminated quote", command)
+	}
+	if inArg {
+		res = append(res, arg.String())
+	}
+	return res, nil
+}
@@ -0,0 +1,49 @@
+package exec_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/exec"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestSplitArgs(t *testing.T) {
+	for _, s := range []struct {
+		title   string
+		command string
+		want    []string
+		wantErr error
+	}{
+		{title: "whitespace", command: " yamllint\t-s  file ", want: []string{"yamllint", "-s", "file"}},
+		{
+			title:   "single quotes",
+			command: `sh -c 'echo "$1" \n' x`,
+			want:    []string{"sh", "-c", `echo "$1" \n`, "x"},
+		},
+		{
+			title:   "double quotes",
+			command: `validate --msg "a \"b\" \c" --path="{{ .Path }}"`,
+			want:    []string{"validate", "--msg", `a "b" \c`, "--path={{ .Path }}"},
+		},
+		{title: "escapes", command: `ls my\ dir \'x`, want: []string{"ls", "my dir", "'x"}},
+		{title: "empty argument", command: `run "" ''`, want: []string{"run", "", ""}},
+		{
+			title:   "unterminated quote",
+			command: `run "a`,
+			wantErr: errors.New(`command "run \"a" has an unterminated quote`),
+		},
+		{
+			title:   "trailing escape",
+			command: `run \`,
+			wantErr: errors.New(`command "run \\" ends with an escape character`),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		got, err := exec.SplitArgs(s.command)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		if s.wantErr == nil {
+			testfuncs.CheckEqualityInterface(t, s.want, got)
+		}
+	}
+}
@@ -2,8 +2,8 @@ package files
 
 import (
 	"io/fs"
-	"os"
-	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -12,16 +12,22 @@ import (
 func New(root string) FileRunner {
 	return FileRunner{
 		root:        root,
-		include:     map[FilterJoin][]string{},
-		exclude:     map[FilterJoin][]string{},
+		include:     map[FilterJoin][]Filter{},
+		exclude:     map[FilterJoin][]Filter{},
+		ignoreFiles: append([]string{}, DefaultIgnoreFiles...),
+		workers:     runtime.NumCPU(),
+		fsys:        OS(),
 		readContent: false,
 	}
 }
 
 type FileRunner struct {
 	root        string
-	include     map[FilterJoin][]string
-	exclude     map[FilterJoin][]string
+	include     map[FilterJoin][]Filter
+	exclude     map[FilterJoin][]Filter
+	ignoreFiles []string
+	workers     int
+	fsys        FS
 	readContent bool
 }
 
@@ -32,23 +38,71 @@ const (
 	OR
 )
 
+// Include adds substring filters (see IncludeFilters).
 func (fr FileRunner) Include(joinBy FilterJoin, filters []string) FileRunner {
+	return fr.IncludeFilters(joinBy, substrings(filters)...)
+}
+
+// Exclude adds substring filters (see ExcludeFilters).
+func (fr FileRunner) Exclude(joinBy FilterJoin, filters []string) FileRunner {
+	return fr.ExcludeFilters(joinBy, substrings(filters)...)
+}
+
+// IncludeFilters restricts the result to paths that match all (AND) or at least
+// one (OR) of the filters.
+func (fr FileRunner) IncludeFilters(joinBy FilterJoin, filters ...Filter) FileRunner {
+	fr.include[joinBy] = append(fr.include[joinBy], fr.expand(filters)...)
+	return fr
+}
+
+// ExcludeFilters removes paths from the result that match all (AND) or at least
+// one (OR) of the filters.
+func (fr FileRunner) ExcludeFilters(joinBy FilterJoin, filters ...Filter) FileRunner {
+	fr.exclude[joinBy] = append(fr.exclude[joinBy], fr.expand(filters)...)
+	return fr
+}
+
+func substrings(filters []string) []Filter {
+	res := make([]Filter, 0, len(filters))
 	for _, f := range filters {
-		if strings.HasPrefix(f, "${BASEPATH}") {
-			f = strings.Replace(f, "${BASEPATH}", fr.root, 1)
-		}
-		fr.include[joinBy] = append(fr.include[joinBy], f)
+		res = append(res, Filter{Mode: Substring, Pattern: f})
 	}
-	return fr
+	return res
 }
 
-func (fr FileRunner) Exclude(joinBy FilterJoin, filters []string) FileRunner {
+// expand replaces the prefix ${BASEPATH} of substring filters by the root.
+func (fr FileRunner) expand(filters []Filter) []Filter {
+	res := make([]Filter, 0, len(filters))
 	for _, f := range filters {
-		if strings.HasPrefix(f, "${BASEPATH}") {
-			f = strings.Replace(f, "${BASEPATH}", fr.root, 1)
+		if f.Mode == Substring && strings.HasPrefix(f.Pattern, "${BASEPATH}") {
+			f.Pattern = strings.Replace(f.Pattern, "${BASEPATH}", fr.root, 1)
 		}
-		fr.exclude[joinBy] = append(fr.exclude[joinBy], f)
+		res = append(res, f)
 	}
+	return res
+}
+
+// IgnoreFiles sets the names of the ignore files (with gitignore semantics) that
+// are honoured in every folder of the walk. Ignored folders are not traversed.
+// Folders of version control systems (e.g. .git) are always skipped.
+func (fr FileRunner) IgnoreFiles(names ...string) FileRunner {
+	fr.ignoreFiles = names
+	return fr
+}
+
+// Workers sets the maximal number of concurrent read operations (folder
+// listings, stats and file reads) of the walk (default: number of CPUs).
+func (fr FileRunner) Workers(n int) FileRunner {
+	if n < 1 {
+		n = 1
+	}
+	fr.workers = n
+	return fr
+}
+
+// FileSystem sets the file system that is walked (default: OS).
+func (fr FileRunner) FileSystem(fsys FS) FileRunner {
+	fr.fsys = fsys
 	return fr
 }
 
@@ -58,40 +112,73 @@ func (fr FileRunner) ReadContent() FileRunner {
 }
 
 func (fr FileRunner) Execute() (files *Files, err error) {
+	m, err := fr.matchers()
+	if err != nil {
+		return nil, err
+	}
 	f := NewFiles(map[string]File{})
-	err = filepath.WalkDir(fr.root, fr.filterFunc(&f))
+	err = newWalker(&fr, m, &f).walk(fr.root)
 	files = &f
 	return
 }
 
-func (fr *FileRunner) fulfilsIncludeAND(path string) bool {
-	for _, f := range fr.include[AND] {
-		if !strings.Contains(path, f) {
+type matchFunc func(full, rel string) bool
+
+type matchers struct {
+	include map[FilterJoin][]matchFunc
+	exclude map[FilterJoin][]matchFunc
+}
+
+func (fr *FileRunner) matchers() (matchers, error) {
+	m := matchers{
+		include: map[FilterJoin][]matchFunc{},
+		exclude: map[FilterJoin][]matchFunc{},
+	}
+	for _, set := range []struct {
+		filters map[FilterJoin][]Filter
+		res     map[FilterJoin][]matchFunc
+	}{{fr.include, m.include}, {fr.exclude, m.exclude}} {
+		for joinBy, filters := range set.filters {
+			for _, f := range filters {
+				match, err := f.matcher()
+				if err != nil {
+					return matchers{}, err
+				}
+				set.res[joinBy] = append(set.res[joinBy], match)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m matchers) fulfilsIncludeAND(path, rel string) bool {
+	for _, match := range m.include[AND] {
+		if !match(path, rel) {
 			return false
 		}
 	}
 	return true
 }
-func (fr *FileRunner) fulfilsIncludeOR(path string) bool {
-	if len(fr.include[OR]) == 0 {
+func (m matchers) fulfilsIncludeOR(path, rel string) bool {
+	if len(m.include[OR]) == 0 {
 		return true
 	}
 	include := false
-	for _, f := range fr.include[OR] {
-		if strings.Contains(path, f) {
+	for _, match := range m.include[OR] {
+		if match(path, rel) {
 			include = true
 			break
 		}
 	}
 	return include
 }
-func (fr *FileRunner) fulfilsExcludeAND(path string) bool {
-	if len(fr.exclude[AND]) == 0 {
+func (m matchers) fulfilsExcludeAND(path, rel string) bool {
+	if len(m.exclude[AND]) == 0 {
 		return false
 	}
 	exclude := true
-	for _, f := range fr.exclude[AND] {
-		if !strings.Contains(path, f) {
+	for _, match := range m.exclude[AND] {
+		if !match(path, rel) {
 			exclude = false
 			break
 		}
@@ -100,61 +187,56 @@ func (fr *FileRunner) fulfilsExcludeAND(path string) bool {
 }
 
 // exclude when at least 1 filter is met
-func (fr *FileRunner) fulfilsExcludeOR(path string) bool {
-	for _, f := range fr.exclude[OR] {
-		if strings.Contains(path, f) {
+func (m matchers) fulfilsExcludeOR(path, rel string) bool {
+	for _, match := range m.exclude[OR] {
+		if match(path, rel) {
 			return true
 		}
 	}
 	return false
 }
 
-func (fr *FileRunner) filterFunc(files *Files) fs.WalkDirFunc {
-	return func(path string, entry fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !fr.fulfilsIncludeAND(path) {
-			return nil
-		}
-		if !fr.fulfilsIncludeOR(path) {
-			return nil
-		}
-		if fr.fulfilsExcludeAND(path) {
-			return nil
-		}
-		if fr.fulfilsExcludeOR(path) {
-			return nil
-		}
-
-		info, err := entry.Info()
-		if err != nil {
-			return err
-		}
-		f := File{
-			Name:     info.Name(),
-			Size:     info.Size(),
-			FileMode: info.Mode(),
-			IsDir:    info.IsDir(),
-			ModTime:  info.ModTime(),
-			Content:  []byte{},
-		}
+// visit adds the path to the files if it passes the filters.
+func (fr *FileRunner) visit(m matchers, files *Files, path, rel string, entry fs.DirEntry) error {
+	if !m.fulfilsIncludeAND(path, rel) {
+		return nil
+	}
+	if !m.fulfilsIncludeOR(path, rel) {
+		return nil
+	}
+	if m.fulfilsExcludeAND(path, rel) {
+		return nil
+	}
+	if m.fulfilsExcludeOR(path, rel) {
+		return nil
+	}
 
-		if !fr.readContent || info.IsDir() {
-			files.write(path, &f)
-			return nil
-		}
+	info, err := entry.Info()
+	if err != nil {
+		return err
+	}
+	f := File{
+		Name:     info.Name(),
+		Size:     info.Size(),
+		FileMode: info.Mode(),
+		IsDir:    info.IsDir(),
+		ModTime:  info.ModTime(),
+		Content:  []byte{},
+	}
 
-		c, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-		f.Content = make([]byte, len(c))
-		copy(f.Content, c)
+	if !fr.readContent || info.IsDir() {
 		files.write(path, &f)
 		return nil
 	}
+
+	c, err := fr.fsys.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	f.Content = make([]byte, len(c))
+	copy(f.Content, c)
+	files.write(path, &f)
+	return nil
 }
 
 func NewFiles(files map[string]File) Files {
@@ -182,6 +264,8 @@ func (f *Files) write(key string, value *File) {
 }
 
 func (f *Files) Content() map[string]File {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
 	res := make(map[string]File, len(f.m))
 	for k, v := range f.m {
 		res[k] = v
@@ -189,6 +273,18 @@ func (f *Files) Content() map[string]File {
 	return res
 }
 
+// Paths returns the paths of all files in lexical order.
+func (f *Files) Paths() []string {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	res := make([]string, 0, len(f.m))
+	for k := range f.m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
 func (f *Files) Read(key string) (File, bool) {
 	f.lock.RLock()
 	defer f.lock.RUnlock()
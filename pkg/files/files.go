@@ -2,7 +2,9 @@ package files
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
+	"math/rand"
 	"os"
 	"path/filepath"
 
@@ -25,15 +27,76 @@ func Read(path string) ([]byte, error) {
 	return content, err
 }
 
+// Write replaces the content of the file at path (see WriteAtomic). Missing
+// parent folders are created and new files get the given permissions.
 func Write(path string, permissions fs.FileMode, content []byte) error {
-	f, err := createOpen(path, os.O_APPEND|os.O_RDWR|os.O_CREATE|os.O_TRUNC, permissions)
+	return writeAtomic(path, permissions, content, createOpen)
+}
+
+// WriteAtomic writes content to a temporary file next to path and renames it to
+// path, so that path holds either its previous or its new content, even if the
+// process is interrupted. The content is synced to the disk before the rename
+// and the parent folder after it, so the new content also survives a crash of
+// the system. Existing files keep their permissions and symbolic links are
+// followed. The parent folder of path must exist.
+func WriteAtomic(path string, permissions fs.FileMode, content []byte) error {
+	return writeAtomic(path, permissions, content, os.OpenFile)
+}
+
+func writeAtomic(
+	path string, permissions fs.FileMode, content []byte,
+	open func(string, int, fs.FileMode) (*os.File, error),
+) (err error) {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	info, statErr := os.Stat(path)
+	if statErr == nil {
+		permissions = info.Mode().Perm()
+	}
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%d.tmp", filepath.Base(path), rand.Int63()))
+	f, err := open(tmp, os.O_RDWR|os.O_CREATE|os.O_EXCL, permissions)
 	if err != nil {
 		return err
 	}
-	if _, err := f.Write(content); err != nil {
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+	if _, err = f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
 		return err
 	}
-	return f.Close()
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if statErr == nil {
+		if err = os.Chmod(tmp, permissions); err != nil {
+			return err
+		}
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir syncs the folder, so that a rename in it is persisted. This is best
+// effort: the rename has been done already and some platforms (e.g. Windows)
+// cannot sync folders.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	_ = d.Sync()
 }
 
 func WriteOpen(path string, permissions fs.FileMode, content []byte) (*os.File, error) {
@@ -139,3 +139,31 @@ func cleanup(t *testing.T, tmpDir testfuncs.TestDir) {
 	createOpen = co
 	tmpDir.Cleanup(t)
 }
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "file")
+	link := filepath.Join(dir, "link")
+	testfuncs.MustBeNil(t, os.WriteFile(p, []byte("old"), 0o600))
+	testfuncs.MustBeNil(t, os.Symlink(p, link))
+
+	testfuncs.MustBeNil(t, WriteAtomic(link, 0o644, []byte("new")))
+	content, err := os.ReadFile(p)
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, "new", string(content))
+	info, err := os.Lstat(link)
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, fs.ModeSymlink, info.Mode().Type())
+	info, err = os.Stat(p)
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, fs.FileMode(0o600), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, 2, len(entries))
+
+	err = WriteAtomic(filepath.Join(dir, "missing", "file"), 0o644, []byte("new"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want a not exist error for a missing folder, got %v", err)
+	}
+}
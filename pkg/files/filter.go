@@ -0,0 +1,138 @@
+package files
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// MatchMode defines how the pattern of a Filter is matched against a path.
+type MatchMode uint8
+
+const (
+	// Substring matches if the pattern is contained in the full path (the
+	// prefix ${BASEPATH} is replaced by the root of the FileRunner).
+	Substring MatchMode = iota
+	// Glob matches the slash separated path relative to the root of the
+	// FileRunner against a glob pattern (see path.Match) that may contain "**"
+	// for any number of folders.
+	Glob
+	// Regex matches the slash separated path relative to the root of the
+	// FileRunner against a regular expression that is anchored at both ends.
+	Regex
+)
+
+var modePrefixes = map[string]MatchMode{
+	"substring:": Substring,
+	"glob:":      Glob,
+	"regex:":     Regex,
+}
+
+// Filter selects paths of a FileRunner. Glob and Regex filters also match all
+// paths below a matching folder, e.g. "glob:services/*" selects the service
+// folders together with their content.
+type Filter struct {
+	Mode    MatchMode
+	Pattern string
+}
+
+// ParseFilter reads a filter from its string representation. The mode is set by
+// the prefixes "glob:", "regex:" or "substring:"; filters without a prefix are
+// substring filters.
+func ParseFilter(s string) (Filter, error) {
+	f := Filter{Mode: Substring, Pattern: s}
+	for prefix, mode := range modePrefixes {
+		if strings.HasPrefix(s, prefix) {
+			f = Filter{Mode: mode, Pattern: strings.TrimPrefix(s, prefix)}
+			break
+		}
+	}
+	if _, err := f.matcher(); err != nil {
+		return Filter{}, err
+	}
+	return f, nil
+}
+
+// ParseFilters parses all filters (see ParseFilter).
+func ParseFilters(filters []string) ([]Filter, error) {
+	res := make([]Filter, 0, len(filters))
+	for _, s := range filters {
+		f, err := ParseFilter(s)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+// matcher validates the pattern and returns a function that reports whether a
+// path matches the filter. It receives the full path and the slash separated
+// path relative to the root.
+func (f Filter) matcher() (func(full, rel string) bool, error) {
+	switch f.Mode {
+	case Substring:
+		return func(full, _ string) bool {
+			return strings.Contains(full, f.Pattern)
+		}, nil
+	case Glob:
+		for _, segment := range strings.Split(f.Pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob filter %q: %w", f.Pattern, err)
+			}
+		}
+		pattern := strings.Split(f.Pattern, "/")
+		return func(_, rel string) bool {
+			return matchParents(rel, func(p string) bool {
+				return matchGlob(pattern, strings.Split(p, "/"))
+			})
+		}, nil
+	case Regex:
+		re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", f.Pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex filter %q: %w", f.Pattern, err)
+		}
+		return func(_, rel string) bool {
+			return matchParents(rel, re.MatchString)
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported filter mode %d for %q", f.Mode, f.Pattern)
+}
+
+// matchParents reports whether the relative path or one of its parent folders
+// matches.
+func matchParents(rel string, match func(string) bool) bool {
+	if rel == "." {
+		return match(rel)
+	}
+	for i, c := range rel {
+		if c == '/' && match(rel[:i]) {
+			return true
+		}
+	}
+	return match(rel)
+}
+
+// matchGlob matches the path segments against the pattern segments. A "**"
+// segment matches any number of path segments (including none).
+func matchGlob(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlob(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchGlob(pattern[1:], segments[1:])
+}
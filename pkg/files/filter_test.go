@@ -0,0 +1,136 @@
+package files_test
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestParseFilter(t *testing.T) {
+	for _, s := range []struct {
+		title   string
+		input   string
+		want    files.Filter
+		wantErr error
+	}{
+		{
+			title: "substring without prefix",
+			input: "services/test",
+			want:  files.Filter{Mode: files.Substring, Pattern: "services/test"},
+		},
+		{
+			title: "explicit substring",
+			input: "substring:glob:x",
+			want:  files.Filter{Mode: files.Substring, Pattern: "glob:x"},
+		},
+		{
+			title: "glob",
+			input: "glob:services/**/test",
+			want:  files.Filter{Mode: files.Glob, Pattern: "services/**/test"},
+		},
+		{
+			title: "regex",
+			input: "regex:services/(a|b)",
+			want:  files.Filter{Mode: files.Regex, Pattern: "services/(a|b)"},
+		},
+		{
+			title:   "invalid glob",
+			input:   "glob:services/[",
+			wantErr: errors.New(`invalid glob filter "services/[": syntax error in pattern`),
+		},
+		{
+			title:   "invalid regex",
+			input:   "regex:(",
+			wantErr: errors.New("invalid regex filter \"(\": error parsing regexp: missing closing ): `^(?:()$`"),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		got, err := files.ParseFilter(s.input)
+		testfuncs.CheckErrs(t, s.wantErr, err)
+		testfuncs.CheckEqualityInterface(t, s.want, got)
+	}
+}
+
+func TestFilterModes(t *testing.T) {
+	tmpDir, err := prepareTestDirTree(map[string][]byte{
+		"services/test/a.yaml":            nil,
+		"services/contest-service/a.yaml": nil,
+		"services/app/test/a.yaml":        nil,
+		"values/prod-eu/coco.yaml":        nil,
+		"values/dev/coco.yaml":            nil,
+	})
+	testfuncs.MustBeNil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	for _, s := range []struct {
+		title   string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{
+			title:   "substring exclude",
+			include: []string{"a.yaml"},
+			exclude: []string{"test"},
+			want:    []string{},
+		},
+		{
+			title:   "glob exclude of a folder",
+			include: []string{"a.yaml"},
+			exclude: []string{"glob:services/test"},
+			want:    []string{"services/app/test/a.yaml", "services/contest-service/a.yaml"},
+		},
+		{
+			title:   "glob exclude in any folder",
+			include: []string{"a.yaml"},
+			exclude: []string{"glob:**/test"},
+			want:    []string{"services/contest-service/a.yaml"},
+		},
+		{
+			title:   "anchored glob include",
+			include: []string{"glob:values/prod-*/*.yaml"},
+			want:    []string{"values/prod-eu/coco.yaml"},
+		},
+		{
+			title:   "anchored regex include",
+			include: []string{"regex:values/(dev|prod)"},
+			want:    []string{"values/dev", "values/dev/coco.yaml"},
+		},
+		{
+			title:   "regex does not match partially",
+			include: []string{"regex:test"},
+			want:    []string{},
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		include, err := files.ParseFilters(s.include)
+		testfuncs.MustBeNil(t, err)
+		exclude, err := files.ParseFilters(s.exclude)
+		testfuncs.MustBeNil(t, err)
+		res, err := files.New(tmpDir).
+			IncludeFilters(files.AND, include...).
+			ExcludeFilters(files.OR, exclude...).
+			Execute()
+		testfuncs.MustBeNil(t, err)
+		got := []string{}
+		for p := range res.Content() {
+			got = append(got, strings.TrimPrefix(p, tmpDir+"/"))
+		}
+		sort.Strings(got)
+		testfuncs.CheckEqualityInterface(t, s.want, got)
+	}
+}
+
+func TestInvalidFilter(t *testing.T) {
+	_, err := files.New(t.TempDir()).
+		IncludeFilters(files.AND, files.Filter{Mode: files.Regex, Pattern: "("}).
+		Execute()
+	testfuncs.CheckErrs(
+		t, errors.New("invalid regex filter \"(\": error parsing regexp: missing closing ): `^(?:()$`"), err,
+	)
+}
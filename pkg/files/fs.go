@@ -0,0 +1,55 @@
+package files
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FS is the file system that coco reads templates, configurations and values
+// from and writes generated files to. The read methods follow io/fs, but all
+// methods take paths in the notation of the os package (absolute or relative
+// to the working directory), so that implementations can be exchanged without
+// changing paths. The helpers of io/fs (e.g. fs.ReadDir, fs.Glob, fs.WalkDir)
+// can be used with an FS.
+type FS interface {
+	fs.StatFS
+	fs.ReadDirFS
+	fs.ReadFileFS
+	WriteFS
+}
+
+// WriteFS holds the write operations of an FS. They behave like their
+// counterparts in the os package.
+type WriteFS interface {
+	// WriteFile writes data to the named file, creating it with perm if
+	// necessary. The permissions of existing files are kept. Implementations
+	// replace the content atomically.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	MkdirAll(name string, perm fs.FileMode) error
+	// Remove removes the named file or empty folder.
+	Remove(name string) error
+	Chmod(name string, mode fs.FileMode) error
+}
+
+// OS returns the FS of the operating system.
+func OS() FS {
+	return osFS{}
+}
+
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error)          { return os.Open(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) Lstat(name string) (fs.FileInfo, error)     { return os.Lstat(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(name) }
+func (osFS) Glob(pattern string) ([]string, error)      { return filepath.Glob(pattern) }
+func (osFS) MkdirAll(name string, perm fs.FileMode) error {
+	return os.MkdirAll(name, perm)
+}
+func (osFS) Remove(name string) error                  { return os.Remove(name) }
+func (osFS) Chmod(name string, mode fs.FileMode) error { return os.Chmod(name, mode) }
+func (osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return WriteAtomic(name, perm, data)
+}
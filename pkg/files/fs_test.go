@@ -0,0 +1,198 @@
+package files_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func names(t *testing.T, fsys files.FS, dir string) []string {
+	t.Helper()
+	entries, err := fsys.ReadDir(dir)
+	testfuncs.MustBeNil(t, err)
+	res := []string{}
+	for _, e := range entries {
+		res = append(res, e.Name())
+	}
+	return res
+}
+
+func TestMemFS(t *testing.T) {
+	m := files.NewMemFS()
+	testfuncs.MustBeNil(t, m.MkdirAll("/repo/a/b", 0o755))
+	testfuncs.MustBeNil(t, m.WriteFile("/repo/a/b/f.yaml", []byte("a: 1\n"), 0o600))
+	testfuncs.MustBeNil(t, m.WriteFile("/repo/a/g.yaml", []byte("g"), 0o644))
+
+	content, err := m.ReadFile("/repo/a/b/f.yaml")
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, "a: 1\n", string(content))
+	testfuncs.CheckEqualityInterface(t, []string{"b", "g.yaml"}, names(t, m, "/repo/a"))
+
+	// existing files keep their mode
+	testfuncs.MustBeNil(t, m.WriteFile("/repo/a/b/f.yaml", []byte("a: 2\n"), 0o644))
+	info, err := m.Stat("/repo/a/b/f.yaml")
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, fs.FileMode(0o600), info.Mode())
+	testfuncs.MustBeNil(t, m.Chmod("/repo/a/b/f.yaml", 0o755))
+	info, err = m.Stat("/repo/a/b/f.yaml")
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, fs.FileMode(0o755), info.Mode())
+
+	if err := m.WriteFile("/repo/missing/f.yaml", nil, 0o644); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want a not exist error for a missing parent, got %v", err)
+	}
+	if err := m.Remove("/repo/a"); err == nil {
+		t.Errorf("want an error for removing a folder that is not empty")
+	}
+	testfuncs.MustBeNil(t, m.Remove("/repo/a/b/f.yaml"))
+	testfuncs.MustBeNil(t, m.Remove("/repo/a/b"))
+	testfuncs.CheckEqualityInterface(t, []string{"g.yaml"}, names(t, m, "/repo/a"))
+
+	// io/fs helpers work with the paths of the os package
+	matches, err := fs.Glob(m, "/repo/a/*.yaml")
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, []string{"/repo/a/g.yaml"}, matches)
+	walked := []string{}
+	testfuncs.MustBeNil(t, fs.WalkDir(m, "/repo", func(p string, d fs.DirEntry, err error) error {
+		walked = append(walked, p)
+		return err
+	}))
+	testfuncs.CheckEqualityInterface(t, []string{"/repo", "/repo/a", "/repo/a/g.yaml"}, walked)
+}
+
+func TestOverlay(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a/f.yaml": "f", "a/g.yaml": "g", "b/h.yaml": "h"} {
+		p := filepath.Join(dir, name)
+		testfuncs.MustBeNil(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		testfuncs.MustBeNil(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	o := files.NewOverlay(files.NewMemFS(), files.OS())
+	p := func(name string) string { return filepath.Join(dir, name) }
+
+	testfuncs.MustBeNil(t, o.WriteFile(p("a/f.yaml"), []byte("changed"), 0o600))
+	testfuncs.MustBeNil(t, o.MkdirAll(p("c"), 0o755))
+	testfuncs.MustBeNil(t, o.WriteFile(p("c/new.yaml"), []byte("new"), 0o644))
+	testfuncs.MustBeNil(t, o.Remove(p("a/g.yaml")))
+	testfuncs.MustBeNil(t, o.Remove(p("b/h.yaml")))
+	testfuncs.MustBeNil(t, o.Remove(p("b")))
+	testfuncs.MustBeNil(t, o.Chmod(p("a"), 0o700))
+
+	content, err := o.ReadFile(p("a/f.yaml"))
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, "changed", string(content))
+	info, err := o.Stat(p("a/f.yaml"))
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, fs.FileMode(0o644), info.Mode().Perm())
+	info, err = o.Stat(p("a"))
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, fs.FileMode(0o700), info.Mode().Perm())
+	if _, err := o.ReadFile(p("a/g.yaml")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want a not exist error for a removed file, got %v", err)
+	}
+	testfuncs.CheckEqualityInterface(t, []string{"a", "c"}, names(t, o, dir))
+	testfuncs.CheckEqualityInterface(t, []string{"f.yaml"}, names(t, o, p("a")))
+
+	// the lower file system is unchanged
+	content, err = os.ReadFile(p("a/f.yaml"))
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, "f", string(content))
+	testfuncs.CheckEqualityInterface(t, []string{"a", "b"}, names(t, files.OS(), dir))
+	testfuncs.CheckEqualityInterface(t, []string{"f.yaml", "g.yaml"}, names(t, files.OS(), p("a")))
+
+	// a removed folder can be created again without revealing its old content
+	testfuncs.MustBeNil(t, o.MkdirAll(p("b"), 0o755))
+	testfuncs.CheckEqualityInterface(t, []string{}, names(t, o, p("b")))
+}
+
+// failingFS fails to write the file fail.
+type failingFS struct {
+	*files.MemFS
+	fail string
+}
+
+func (f failingFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if name == f.fail {
+		return errors.New("write failed")
+	}
+	return f.MemFS.WriteFile(name, data, perm)
+}
+
+func TestOverlayCommit(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, s := range []struct {
+		title   string
+		ctx     context.Context
+		fail    string
+		want    map[string]string
+		wantErr error
+	}{
+		{
+			title: "all changes",
+			ctx:   context.Background(),
+			want:  map[string]string{"/r/a/f.yaml": "changed", "/r/c/d/new.yaml": "new"},
+		},
+		{
+			title:   "cancelled",
+			ctx:     cancelled,
+			want:    map[string]string{"/r/a/f.yaml": "f", "/r/a/g.yaml": "g", "/r/b/h.yaml": "h"},
+			wantErr: context.Canceled,
+		},
+		{
+			title:   "roll back",
+			ctx:     context.Background(),
+			fail:    "/r/c/d/new.yaml",
+			want:    map[string]string{"/r/a/f.yaml": "f", "/r/a/g.yaml": "g", "/r/b/h.yaml": "h"},
+			wantErr: errors.New("write failed"),
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		lower := failingFS{files.NewMemFS(), s.fail}
+		for name, content := range map[string]string{"/r/a/f.yaml": "f", "/r/a/g.yaml": "g", "/r/b/h.yaml": "h"} {
+			testfuncs.MustBeNil(t, lower.MkdirAll(filepath.Dir(name), 0o755))
+			testfuncs.MustBeNil(t, lower.WriteFile(name, []byte(content), 0o644))
+		}
+		o := files.NewOverlay(files.NewMemFS(), lower)
+		testfuncs.MustBeNil(t, o.WriteFile("/r/a/f.yaml", []byte("changed"), 0o644))
+		testfuncs.MustBeNil(t, o.Chmod("/r/a/f.yaml", 0o600))
+		testfuncs.MustBeNil(t, o.Remove("/r/a/g.yaml"))
+		testfuncs.MustBeNil(t, o.Remove("/r/b/h.yaml"))
+		testfuncs.MustBeNil(t, o.Remove("/r/b"))
+		testfuncs.MustBeNil(t, o.MkdirAll("/r/c/d", 0o755))
+		testfuncs.MustBeNil(t, o.WriteFile("/r/c/d/new.yaml", []byte("new"), 0o644))
+
+		err := o.Commit(s.ctx)
+		if s.wantErr == nil {
+			testfuncs.MustBeNil(t, err)
+		} else if err == nil || !strings.Contains(err.Error(), s.wantErr.Error()) {
+			t.Errorf("want error %q, got %v", s.wantErr, err)
+		}
+		got := map[string]string{}
+		testfuncs.MustBeNil(t, fs.WalkDir(lower, "/r", func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			content, err := lower.ReadFile(p)
+			got[p] = string(content)
+			return err
+		}))
+		testfuncs.CheckEqualityInterface(t, s.want, got)
+		if s.wantErr != nil {
+			// folders created by the commit have been removed again
+			testfuncs.CheckEqualityInterface(t, []string{"a", "b"}, names(t, lower, "/r"))
+			continue
+		}
+		info, err := lower.Stat("/r/a/f.yaml")
+		testfuncs.MustBeNil(t, err)
+		testfuncs.CheckEqualityInterface(t, fs.FileMode(0o600), info.Mode())
+		testfuncs.CheckEqualityInterface(t, []string{"a", "c"}, names(t, o, "/r"))
+	}
+}
@@ -0,0 +1,95 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// CocoIgnore is the name of the file that lists the paths (with gitignore
+// semantics) that coco does not traverse.
+const CocoIgnore = ".cocoignore"
+
+// DefaultIgnoreFiles are the ignore files that a new FileRunner honours (see
+// FileRunner.IgnoreFiles).
+var DefaultIgnoreFiles = []string{CocoIgnore}
+
+// vcsDirs are the folders of version control systems that are never traversed.
+var vcsDirs = map[string]bool{".git": true, ".hg": true, ".svn": true}
+
+// ignored reports whether the path (given by its slash separated segments
+// relative to the root) matches the ignore patterns.
+func ignored(patterns []gitignore.Pattern, segments []string, isDir bool) bool {
+	if isDir && vcsDirs[segments[len(segments)-1]] {
+		return true
+	}
+	return gitignore.NewMatcher(patterns).Match(segments, isDir)
+}
+
+// ignorePatterns appends the patterns of the ignore files of a folder (read by
+// their name with readFile) to the patterns of its parent folders. The patterns
+// of a folder only apply to its content (domain). The parent patterns are never
+// modified, so they can be shared between sibling folders.
+func ignorePatterns(
+	readFile func(name string) ([]byte, error), parent []gitignore.Pattern, domain, names []string,
+) ([]gitignore.Pattern, error) {
+	res := parent[:len(parent):len(parent)]
+	for _, name := range names {
+		content, err := readFile(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+				continue
+			}
+			res = append(res, gitignore.ParsePattern(line, domain))
+		}
+	}
+	return res, nil
+}
+
+// WalkDir walks the tree of fsys rooted at root like fs.WalkDir, but skips the
+// paths that a FileRunner with the same ignoreFiles skips (see
+// FileRunner.IgnoreFiles). Unlike a FileRunner it takes the slash separated paths
+// of io/fs, so it can walk file systems like git trees.
+func WalkDir(fsys fs.FS, root string, ignoreFiles []string, fn fs.WalkDirFunc) error {
+	patterns := map[string][]gitignore.Pattern{}
+	return fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fn(p, d, err)
+		}
+		domain := []string{}
+		if p != root {
+			rel := strings.TrimPrefix(p, root+"/")
+			if root == "." {
+				rel = p
+			}
+			domain = strings.Split(rel, "/")
+			if ignored(patterns[path.Dir(p)], domain, d.IsDir()) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.IsDir() {
+			readFile := func(name string) ([]byte, error) { return fs.ReadFile(fsys, path.Join(p, name)) }
+			patterns[p], err = ignorePatterns(readFile, patterns[path.Dir(p)], domain, ignoreFiles)
+			if err != nil {
+				return err
+			}
+		}
+		return fn(p, d, nil)
+	})
+}
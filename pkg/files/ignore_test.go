@@ -0,0 +1,101 @@
+package files_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestIgnoreFiles(t *testing.T) {
+	tmpDir, err := prepareTestDirTree(map[string][]byte{
+		".git/HEAD":                         []byte("ref: refs/heads/main"),
+		".cocoignore":                       []byte("# comment\nnode_modules/\n*.tmp\n!keep.tmp\n"),
+		".gitignore":                        []byte("build/\n"),
+		"a/coco.yaml":                       nil,
+		"a/node_modules/x/coco.yaml":        nil,
+		"a/build/coco.yaml":                 nil,
+		"a/file.tmp":                        nil,
+		"a/keep.tmp":                        nil,
+		"b/.cocoignore":                     []byte("/local\n"),
+		"b/local/coco.yaml":                 nil,
+		"b/sub/local/coco.yaml":             nil,
+		"c/local/coco.yaml":                 nil,
+		"c/node_modules_docs/coco.yaml":     nil,
+		"c/service/.git/config":             nil,
+		"c/service/.git.yaml":               nil,
+		"c/service/node_modules/index.yaml": nil,
+	})
+	testfuncs.MustBeNil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	for _, s := range []struct {
+		title       string
+		ignoreFiles []string
+		want        []string
+	}{
+		{
+			title:       "cocoignore",
+			ignoreFiles: files.DefaultIgnoreFiles,
+			want: []string{
+				".cocoignore", ".gitignore",
+				"a", "a/build", "a/build/coco.yaml", "a/coco.yaml", "a/keep.tmp",
+				"b", "b/.cocoignore", "b/sub", "b/sub/local", "b/sub/local/coco.yaml",
+				"c", "c/local", "c/local/coco.yaml", "c/node_modules_docs", "c/node_modules_docs/coco.yaml",
+				"c/service", "c/service/.git.yaml",
+			},
+		},
+		{
+			title:       "cocoignore and gitignore",
+			ignoreFiles: []string{files.CocoIgnore, ".gitignore"},
+			want: []string{
+				".cocoignore", ".gitignore",
+				"a", "a/coco.yaml", "a/keep.tmp",
+				"b", "b/.cocoignore", "b/sub", "b/sub/local", "b/sub/local/coco.yaml",
+				"c", "c/local", "c/local/coco.yaml", "c/node_modules_docs", "c/node_modules_docs/coco.yaml",
+				"c/service", "c/service/.git.yaml",
+			},
+		},
+		{
+			title:       "no ignore files",
+			ignoreFiles: []string{},
+			want: []string{
+				".cocoignore", ".gitignore",
+				"a", "a/build", "a/build/coco.yaml", "a/coco.yaml", "a/file.tmp", "a/keep.tmp",
+				"a/node_modules", "a/node_modules/x", "a/node_modules/x/coco.yaml",
+				"b", "b/.cocoignore", "b/local", "b/local/coco.yaml", "b/sub", "b/sub/local", "b/sub/local/coco.yaml",
+				"c", "c/local", "c/local/coco.yaml", "c/node_modules_docs", "c/node_modules_docs/coco.yaml",
+				"c/service", "c/service/.git.yaml", "c/service/node_modules", "c/service/node_modules/index.yaml",
+			},
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		res, err := files.New(tmpDir).IgnoreFiles(s.ignoreFiles...).Execute()
+		testfuncs.MustBeNil(t, err)
+		got := []string{}
+		for p := range res.Content() {
+			if p == tmpDir {
+				continue
+			}
+			got = append(got, filepath.ToSlash(strings.TrimPrefix(p, tmpDir+"/")))
+		}
+		sort.Strings(got)
+		testfuncs.CheckEqualityInterface(t, s.want, got)
+
+		// WalkDir skips the same paths in io/fs file systems
+		walked := []string{}
+		err = files.WalkDir(os.DirFS(tmpDir), ".", s.ignoreFiles, func(p string, _ fs.DirEntry, err error) error {
+			if p != "." {
+				walked = append(walked, p)
+			}
+			return err
+		})
+		testfuncs.MustBeNil(t, err)
+		testfuncs.CheckEqualityInterface(t, s.want, walked)
+	}
+}
@@ -0,0 +1,233 @@
+package files
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemFS is an FS that holds all files in memory. The root folders "/" and "."
+// always exist. It is safe for concurrent use.
+type MemFS struct {
+	lock  sync.RWMutex
+	files map[string]*memFile
+}
+
+type memFile struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemFS returns an empty MemFS.
+func NewMemFS() *MemFS {
+	return &MemFS{files: map[string]*memFile{}}
+}
+
+var errNotEmpty = errors.New("directory not empty")
+
+func pathErr(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// lookup returns the file or folder at the cleaned path (nil if it does not
+// exist). The caller must hold the lock.
+func (m *MemFS) lookup(name string) *memFile {
+	if name == "/" || name == "." {
+		return &memFile{mode: fs.ModeDir | 0o777}
+	}
+	return m.files[name]
+}
+
+func (m *MemFS) Open(name string) (fs.File, error) {
+	info, err := m.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		entries, err := m.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &memDir{info: info, entries: entries}, nil
+	}
+	data, err := m.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &memOpenFile{info: info, Reader: bytes.NewReader(data)}, nil
+}
+
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	name = filepath.Clean(name)
+	f := m.lookup(name)
+	if f == nil {
+		return nil, pathErr("stat", name, fs.ErrNotExist)
+	}
+	return fileInfo{name: filepath.Base(name), size: int64(len(f.data)), mode: f.mode, modTime: f.modTime}, nil
+}
+
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	name = filepath.Clean(name)
+	f := m.lookup(name)
+	if f == nil {
+		return nil, pathErr("readdir", name, fs.ErrNotExist)
+	}
+	if !f.mode.IsDir() {
+		return nil, pathErr("readdir", name, errors.New("not a directory"))
+	}
+	res := []fs.DirEntry{}
+	for p, child := range m.files {
+		if filepath.Dir(p) != name || p == name {
+			continue
+		}
+		res = append(res, fs.FileInfoToDirEntry(fileInfo{
+			name: filepath.Base(p), size: int64(len(child.data)), mode: child.mode, modTime: child.modTime,
+		}))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name() < res[j].Name() })
+	return res, nil
+}
+
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	name = filepath.Clean(name)
+	f := m.lookup(name)
+	if f == nil {
+		return nil, pathErr("open", name, fs.ErrNotExist)
+	}
+	if f.mode.IsDir() {
+		return nil, pathErr("read", name, errors.New("is a directory"))
+	}
+	return append([]byte{}, f.data...), nil
+}
+
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	name = filepath.Clean(name)
+	if parent := m.lookup(filepath.Dir(name)); parent == nil || !parent.mode.IsDir() {
+		return pathErr("open", name, fs.ErrNotExist)
+	}
+	if f := m.lookup(name); f != nil {
+		if f.mode.IsDir() {
+			return pathErr("open", name, errors.New("is a directory"))
+		}
+		perm = f.mode
+	}
+	m.files[name] = &memFile{data: append([]byte{}, data...), mode: perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	name = filepath.Clean(name)
+	missing := []string{}
+	for p := name; ; p = filepath.Dir(p) {
+		f := m.lookup(p)
+		if f != nil && !f.mode.IsDir() {
+			return pathErr("mkdir", p, errors.New("not a directory"))
+		}
+		if f != nil {
+			break
+		}
+		missing = append(missing, p)
+	}
+	for _, p := range missing {
+		m.files[p] = &memFile{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	}
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	name = filepath.Clean(name)
+	f := m.files[name]
+	if f == nil {
+		return pathErr("remove", name, fs.ErrNotExist)
+	}
+	if f.mode.IsDir() {
+		for p := range m.files {
+			if filepath.Dir(p) == name && p != name {
+				return pathErr("remove", name, errNotEmpty)
+			}
+		}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+func (m *MemFS) Chmod(name string, mode fs.FileMode) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	name = filepath.Clean(name)
+	f := m.files[name]
+	if f == nil {
+		return pathErr("chmod", name, fs.ErrNotExist)
+	}
+	m.files[name] = &memFile{data: f.data, mode: f.mode.Type() | mode.Perm(), modTime: f.modTime}
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+func (i fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i fileInfo) Sys() interface{}   { return nil }
+
+type memOpenFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *memOpenFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memOpenFile) Close() error               { return nil }
+
+type memDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, pathErr("read", d.info.Name(), errors.New("is a directory"))
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		res := d.entries
+		d.entries = nil
+		return res, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	res := d.entries[:n]
+	d.entries = d.entries[n:]
+	return res, nil
+}
@@ -0,0 +1,346 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Overlay is an FS that reads from upper with a fallback to lower and writes
+// only to upper. Files and folders of lower that are removed are hidden, so
+// lower is never modified. This allows e.g. rendering into a staging tree
+// (upper: MemFS) on top of the repository (lower: OS).
+//
+// Folders that exist in both layers are merged by ReadDir (and thus by
+// fs.ReadDir and fs.WalkDir), but not by reading an opened folder.
+//
+// Commit applies all changes to lower at once.
+type Overlay struct {
+	upper, lower FS
+
+	lock    sync.RWMutex
+	removed map[string]bool
+	// changed holds the files and folders that have been written to upper
+	changed map[string]bool
+}
+
+// NewOverlay returns an Overlay of the two file systems.
+func NewOverlay(upper, lower FS) *Overlay {
+	return &Overlay{upper: upper, lower: lower, removed: map[string]bool{}, changed: map[string]bool{}}
+}
+
+func (o *Overlay) hidden(name string) bool {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	return o.removed[filepath.Clean(name)]
+}
+
+// layer returns the file system that holds the named file (the error of lower
+// if the file does not exist).
+func (o *Overlay) layer(name string) (FS, fs.FileInfo, error) {
+	if o.hidden(name) {
+		return nil, nil, pathErr("stat", name, fs.ErrNotExist)
+	}
+	if info, err := o.upper.Stat(name); err == nil {
+		return o.upper, info, nil
+	}
+	info, err := o.lower.Stat(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return o.lower, info, nil
+}
+
+func (o *Overlay) Open(name string) (fs.File, error) {
+	layer, _, err := o.layer(name)
+	if err != nil {
+		return nil, err
+	}
+	return layer.Open(name)
+}
+
+func (o *Overlay) Stat(name string) (fs.FileInfo, error) {
+	_, info, err := o.layer(name)
+	return info, err
+}
+
+func (o *Overlay) ReadFile(name string) ([]byte, error) {
+	layer, _, err := o.layer(name)
+	if err != nil {
+		return nil, err
+	}
+	return layer.ReadFile(name)
+}
+
+func (o *Overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	if o.hidden(name) {
+		return nil, pathErr("readdir", name, fs.ErrNotExist)
+	}
+	upper, upperErr := o.upper.ReadDir(name)
+	lower, lowerErr := o.lower.ReadDir(name)
+	if upperErr != nil && lowerErr != nil {
+		return nil, lowerErr
+	}
+	entries := map[string]fs.DirEntry{}
+	for _, e := range lower {
+		if !o.hidden(filepath.Join(name, e.Name())) {
+			entries[e.Name()] = e
+		}
+	}
+	for _, e := range upper {
+		entries[e.Name()] = e
+	}
+	res := make([]fs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name() < res[j].Name() })
+	return res, nil
+}
+
+// unhide makes the path and its parent folders visible again and marks the
+// path as changed.
+func (o *Overlay) unhide(name string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.changed[filepath.Clean(name)] = true
+	for p := filepath.Clean(name); ; p = filepath.Dir(p) {
+		delete(o.removed, p)
+		if p == filepath.Dir(p) {
+			return
+		}
+	}
+}
+
+// copyUp creates the parent folders of name in upper.
+func (o *Overlay) copyUp(name string) error {
+	dir := filepath.Dir(name)
+	info, err := o.Stat(dir)
+	if err != nil {
+		return pathErr("open", name, fs.ErrNotExist)
+	}
+	if !info.IsDir() {
+		return pathErr("open", name, errors.New("not a directory"))
+	}
+	return o.upper.MkdirAll(dir, info.Mode().Perm())
+}
+
+func (o *Overlay) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := o.copyUp(name); err != nil {
+		return err
+	}
+	if info, err := o.Stat(name); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := o.upper.WriteFile(name, data, perm); err != nil {
+		return err
+	}
+	o.unhide(name)
+	return nil
+}
+
+func (o *Overlay) MkdirAll(name string, perm fs.FileMode) error {
+	if info, err := o.Stat(name); err == nil && !info.IsDir() {
+		return pathErr("mkdir", name, errors.New("not a directory"))
+	}
+	if err := o.upper.MkdirAll(name, perm); err != nil {
+		return err
+	}
+	o.unhide(name)
+	return nil
+}
+
+func (o *Overlay) Remove(name string) error {
+	layer, info, err := o.layer(name)
+	if err != nil {
+		return pathErr("remove", name, fs.ErrNotExist)
+	}
+	if info.IsDir() {
+		entries, err := o.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return pathErr("remove", name, errNotEmpty)
+		}
+	}
+	if layer == o.upper {
+		if err := o.upper.Remove(name); err != nil {
+			return err
+		}
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	delete(o.changed, filepath.Clean(name))
+	if _, err := o.lower.Stat(name); err == nil {
+		o.removed[filepath.Clean(name)] = true
+	}
+	return nil
+}
+
+func (o *Overlay) Chmod(name string, mode fs.FileMode) error {
+	layer, info, err := o.layer(name)
+	if err != nil {
+		return pathErr("chmod", name, fs.ErrNotExist)
+	}
+	if layer == o.lower {
+		if err := o.copyUp(name); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			err = o.upper.MkdirAll(name, info.Mode().Perm())
+		} else {
+			var content []byte
+			if content, err = o.lower.ReadFile(name); err == nil {
+				err = o.upper.WriteFile(name, content, info.Mode().Perm())
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := o.upper.Chmod(name, mode); err != nil {
+		return err
+	}
+	o.unhide(name)
+	return nil
+}
+
+// Commit applies the changes to lower: changed folders and files are written
+// (in lexical order) and removed paths are removed (children first). If a step
+// fails or ctx is cancelled, all steps that have been applied are rolled back,
+// so that lower is either unchanged or holds all changes. Afterwards, the
+// overlay shows the same content and can be used for further changes.
+func (o *Overlay) Commit(ctx context.Context) (err error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	undo := []func() error{}
+	defer func() {
+		if err == nil {
+			o.changed = map[string]bool{}
+			o.removed = map[string]bool{}
+			return
+		}
+		errs := []error{err}
+		for i := len(undo) - 1; i >= 0; i-- {
+			errs = append(errs, undo[i]())
+		}
+		err = errors.Join(errs...)
+	}()
+	apply := func(name string, step func() error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		restore, err := o.restorer(name)
+		if err != nil {
+			return err
+		}
+		undo = append(undo, restore)
+		return step()
+	}
+
+	for _, name := range sortedKeys(o.changed) {
+		info, err := o.upper.Stat(name)
+		if err != nil {
+			return err
+		}
+		// folders that are created with name need to be removed on a roll back
+		for _, dir := range o.missingParents(name) {
+			if err := apply(dir, func() error { return nil }); err != nil {
+				return err
+			}
+		}
+		if err := apply(name, func() error { return o.write(name, info) }); err != nil {
+			return err
+		}
+	}
+	removed := sortedKeys(o.removed)
+	for i := len(removed) - 1; i >= 0; i-- {
+		name := removed[i]
+		if err := apply(name, func() error { return o.lower.Remove(name) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write copies the file or folder of upper with the mode of info to lower.
+func (o *Overlay) write(name string, info fs.FileInfo) error {
+	perm := info.Mode().Perm()
+	if info.IsDir() {
+		if err := o.lower.MkdirAll(name, perm); err != nil {
+			return err
+		}
+	} else {
+		content, err := o.upper.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := o.lower.WriteFile(name, content, perm); err != nil {
+			return err
+		}
+	}
+	return o.lower.Chmod(name, perm)
+}
+
+// restorer returns a function that restores the current state of the path in
+// lower (removing it if it does not exist).
+func (o *Overlay) restorer(name string) (func() error, error) {
+	info, err := o.lower.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return func() error {
+			if err := o.lower.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			return nil
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	perm := info.Mode().Perm()
+	if info.IsDir() {
+		return func() error {
+			if err := o.lower.MkdirAll(name, perm); err != nil {
+				return err
+			}
+			return o.lower.Chmod(name, perm)
+		}, nil
+	}
+	content, err := o.lower.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		if err := o.lower.WriteFile(name, content, perm); err != nil {
+			return err
+		}
+		return o.lower.Chmod(name, perm)
+	}, nil
+}
+
+// missingParents returns the parent folders of name that do not exist in lower
+// (top-down).
+func (o *Overlay) missingParents(name string) []string {
+	res := []string{}
+	for dir := filepath.Dir(name); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, err := o.lower.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		res = append([]string{dir}, res...)
+	}
+	return res
+}
+
+func sortedKeys(m map[string]bool) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
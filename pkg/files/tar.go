@@ -0,0 +1,48 @@
+package files
+
+import (
+	"archive/tar"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// WriteTar writes all regular files below root of fsys as tar archive to w. The
+// names in the archive are relative to root and all files have the same
+// modification time, so the same tree always results in the same archive.
+func WriteTar(w io.Writer, fsys FS, root string) error {
+	tw := tar.NewWriter(w)
+	err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		content, err := fsys.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		err = tw.WriteHeader(&tar.Header{
+			Name:    filepath.ToSlash(rel),
+			Mode:    int64(info.Mode().Perm()),
+			Size:    int64(len(content)),
+			ModTime: time.Unix(0, 0),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(content)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,48 @@
+package files_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+func TestWriteTar(t *testing.T) {
+	m := files.NewMemFS()
+	testfuncs.MustBeNil(t, m.MkdirAll("/out/a/b", 0o755))
+	testfuncs.MustBeNil(t, m.WriteFile("/out/a/b/f.yaml", []byte("f"), 0o644))
+	testfuncs.MustBeNil(t, m.WriteFile("/out/a/run.sh", []byte("#!/bin/sh\n"), 0o755))
+	testfuncs.MustBeNil(t, m.WriteFile("/other.yaml", []byte("o"), 0o644))
+
+	var first, second bytes.Buffer
+	testfuncs.MustBeNil(t, files.WriteTar(&first, m, "/out"))
+	testfuncs.MustBeNil(t, files.WriteTar(&second, m, "/out"))
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("archives of the same tree differ")
+	}
+
+	type entry struct {
+		Content string
+		Mode    fs.FileMode
+	}
+	got := map[string]entry{}
+	tr := tar.NewReader(&first)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		testfuncs.MustBeNil(t, err)
+		content, err := io.ReadAll(tr)
+		testfuncs.MustBeNil(t, err)
+		got[h.Name] = entry{string(content), fs.FileMode(h.Mode)}
+	}
+	testfuncs.CheckEqualityInterface(t, map[string]entry{
+		"a/b/f.yaml": {"f", 0o644},
+		"a/run.sh":   {"#!/bin/sh\n", 0o755},
+	}, got)
+}
@@ -0,0 +1,168 @@
+package files
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// job is a path that a worker of the walker visits. Folders are read and their
+// entries are queued as new jobs.
+type job struct {
+	path  string
+	rel   string // slash separated path relative to the root
+	entry fs.DirEntry
+	// patterns are the ignore patterns of the parent folders
+	patterns []gitignore.Pattern
+}
+
+// lstatFS is implemented by file systems with symbolic links. Like
+// filepath.WalkDir, the walk does not follow a root that is a link.
+type lstatFS interface {
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// walker visits the tree below the root of a FileRunner with a bounded number
+// of workers that read folders, stat files and read their content. Jobs are
+// processed depth first from a shared queue, so the workers stay busy even if
+// the tree is unbalanced.
+type walker struct {
+	fr    *FileRunner
+	m     matchers
+	files *Files
+
+	lock    sync.Mutex
+	cond    *sync.Cond
+	queue   []job
+	pending int
+	errs    map[string]error
+}
+
+func newWalker(fr *FileRunner, m matchers, files *Files) *walker {
+	w := &walker{fr: fr, m: m, files: files, errs: map[string]error{}}
+	w.cond = sync.NewCond(&w.lock)
+	return w
+}
+
+// walk visits all paths below root. If visiting a path fails, the error of the
+// lexically first failing path is returned, so the result does not depend on
+// the scheduling of the workers.
+func (w *walker) walk(root string) error {
+	stat := w.fr.fsys.Stat
+	if l, ok := w.fr.fsys.(lstatFS); ok {
+		stat = l.Lstat
+	}
+	info, err := stat(root)
+	if err != nil {
+		return err
+	}
+	w.push(job{path: root, rel: ".", entry: fs.FileInfoToDirEntry(info)})
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.fr.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				j, ok := w.pop()
+				if !ok {
+					return
+				}
+				children, err := w.process(j)
+				w.push(children...)
+				w.done(j, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(w.errs) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(w.errs))
+	for p := range w.errs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return w.errs[paths[0]]
+}
+
+func (w *walker) push(jobs ...job) {
+	if len(jobs) == 0 {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.queue = append(w.queue, jobs...)
+	w.pending += len(jobs)
+	w.cond.Broadcast()
+}
+
+// pop returns the next job. It blocks until a job is queued and returns false
+// when all jobs are done.
+func (w *walker) pop() (job, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for len(w.queue) == 0 && w.pending > 0 {
+		w.cond.Wait()
+	}
+	if len(w.queue) == 0 {
+		return job{}, false
+	}
+	j := w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+	return j, true
+}
+
+func (w *walker) done(j job, err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err != nil {
+		w.errs[j.path] = err
+	}
+	w.pending--
+	if w.pending == 0 {
+		w.cond.Broadcast()
+	}
+}
+
+// process visits the path of the job and returns the jobs for the entries of a
+// folder that are not ignored.
+func (w *walker) process(j job) ([]job, error) {
+	if err := w.fr.visit(w.m, w.files, j.path, j.rel, j.entry); err != nil {
+		return nil, err
+	}
+	if !j.entry.IsDir() {
+		return nil, nil
+	}
+
+	domain := []string{}
+	if j.rel != "." {
+		domain = strings.Split(j.rel, "/")
+	}
+	readFile := func(name string) ([]byte, error) { return w.fr.fsys.ReadFile(filepath.Join(j.path, name)) }
+	patterns, err := ignorePatterns(readFile, j.patterns, domain, w.fr.ignoreFiles)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := w.fr.fsys.ReadDir(j.path)
+	if err != nil {
+		return nil, err
+	}
+	children := make([]job, 0, len(entries))
+	for _, e := range entries {
+		rel := path.Join(j.rel, e.Name())
+		if ignored(patterns, strings.Split(rel, "/"), e.IsDir()) {
+			continue
+		}
+		children = append(children, job{
+			path: filepath.Join(j.path, e.Name()), rel: rel, entry: e, patterns: patterns,
+		})
+	}
+	return children, nil
+}
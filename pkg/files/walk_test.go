@@ -0,0 +1,79 @@
+package files_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/files"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+// prepareLargeTree creates a tree of services with environment folders and
+// value files similar to the layout of a gitops repository.
+func prepareLargeTree(tb testing.TB, services, envs, valueFiles int) string {
+	tb.Helper()
+	root := tb.TempDir()
+	content := []byte("key: value\nlist:\n  - a\n  - b\n")
+	for s := 0; s < services; s++ {
+		for e := 0; e < envs; e++ {
+			dir := filepath.Join(root, "services", fmt.Sprintf("svc-%d", s), "values", fmt.Sprintf("env-%d", e))
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				tb.Fatal(err)
+			}
+			for v := 0; v < valueFiles; v++ {
+				if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("v%d.yaml", v)), content, 0o644); err != nil {
+					tb.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(dir, "coco.yaml"), []byte("type: environment\n"), 0o644); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+	return root
+}
+
+func TestWorkersDeterministic(t *testing.T) {
+	root := prepareLargeTree(t, 5, 4, 3)
+
+	sequential, err := files.New(root).Workers(1).ReadContent().Execute()
+	testfuncs.MustBeNil(t, err)
+	for _, workers := range []int{0, 2, 16} {
+		t.Logf("test scenario: %d workers\n", workers)
+		parallel, err := files.New(root).Workers(workers).ReadContent().Execute()
+		testfuncs.MustBeNil(t, err)
+		testfuncs.CheckEqualityInterface(t, sequential.Paths(), parallel.Paths())
+		if !reflect.DeepEqual(sequential.Content(), parallel.Content()) {
+			t.Errorf("results of %d workers differ from the sequential walk", workers)
+		}
+	}
+	// 1 root + 1 services folder + per service 2 folders + per env 1 folder and 4 files
+	testfuncs.CheckEqualityInterface(t, 1+1+5*2+5*4*5, len(sequential.Paths()))
+}
+
+func TestWalkMissingRoot(t *testing.T) {
+	_, err := files.New(filepath.Join(t.TempDir(), "missing")).Execute()
+	if !os.IsNotExist(err) {
+		t.Errorf("want a not exist error, got %v", err)
+	}
+}
+
+func BenchmarkExecute(b *testing.B) {
+	root := prepareLargeTree(b, 50, 10, 8)
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := files.New(root).
+					Include(files.AND, []string{".yaml"}).
+					Workers(workers).
+					ReadContent().
+					Execute(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
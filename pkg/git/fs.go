@@ -0,0 +1,146 @@
+package git
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// FS returns a read-only file system of the tree, e.g. for reading files with
+// fs.ReadFile or for walking the tree with fs.WalkDir. All files carry the time
+// of the head commit.
+func (t *Tree) FS() fs.FS {
+	return treeFS{t}
+}
+
+type treeFS struct {
+	tree *Tree
+}
+
+func (t treeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &treeDir{info: t.info(".", filemode.Dir, 0), tree: t.tree.T, fsys: t}, nil
+	}
+	entry, err := t.tree.T.FindEntry(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.Mode == filemode.Dir {
+		sub, err := t.tree.T.Tree(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &treeDir{info: t.info(name, entry.Mode, 0), tree: sub, fsys: t}, nil
+	}
+	if entry.Mode == filemode.Submodule {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	f, err := t.tree.T.File(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	r, err := f.Reader()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &treeFile{info: t.info(name, entry.Mode, f.Size), ReadCloser: r}, nil
+}
+
+func (t treeFS) info(name string, mode filemode.FileMode, size int64) fileInfo {
+	var modTime time.Time
+	if t.tree.HeadCommit != nil {
+		modTime = t.tree.HeadCommit.Committer.When
+	}
+	return fileInfo{name: path.Base(name), mode: mode, size: size, modTime: modTime}
+}
+
+type fileInfo struct {
+	name    string
+	mode    filemode.FileMode
+	size    int64
+	modTime time.Time
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+func (i fileInfo) IsDir() bool        { return i.mode == filemode.Dir }
+func (i fileInfo) Sys() interface{}   { return nil }
+
+func (i fileInfo) Mode() fs.FileMode {
+	m, err := i.mode.ToOSFileMode()
+	if err != nil {
+		return 0
+	}
+	return m
+}
+
+func (i fileInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i fileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+type treeFile struct {
+	info fileInfo
+	io.ReadCloser
+}
+
+func (f *treeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+type treeDir struct {
+	info    fileInfo
+	tree    *object.Tree
+	fsys    treeFS
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *treeDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *treeDir) Close() error               { return nil }
+
+func (d *treeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile. Entries are sorted by name.
+func (d *treeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.entries == nil {
+		d.entries = make([]fs.DirEntry, 0, len(d.tree.Entries))
+		for _, e := range d.tree.Entries {
+			if e.Mode == filemode.Submodule {
+				continue
+			}
+			size := int64(0)
+			if e.Mode != filemode.Dir {
+				if f, err := d.tree.TreeEntryFile(&e); err == nil {
+					size = f.Size
+				}
+			}
+			d.entries = append(d.entries, d.fsys.info(e.Name, e.Mode, size))
+		}
+		sort.Slice(d.entries, func(i, j int) bool {
+			return strings.Compare(d.entries[i].Name(), d.entries[j].Name()) < 0
+		})
+	}
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
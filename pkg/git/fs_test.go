@@ -0,0 +1,61 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestTreeFS(t *testing.T) {
+	dir := t.TempDir()
+	client, err := git.PlainInit(dir, false)
+	testfuncs.MustBeNil(t, err)
+	w, err := client.Worktree()
+	testfuncs.MustBeNil(t, err)
+
+	commit := func(files map[string]string) {
+		for name, content := range files {
+			p := filepath.Join(dir, name)
+			testfuncs.MustBeNil(t, os.MkdirAll(filepath.Dir(p), 0o755))
+			testfuncs.MustBeNil(t, os.WriteFile(p, []byte(content), 0o644))
+			_, err := w.Add(name)
+			testfuncs.MustBeNil(t, err)
+		}
+		_, err := w.Commit("commit", &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		testfuncs.MustBeNil(t, err)
+	}
+	commit(map[string]string{"a.txt": "first", "values/c1/coco.yaml": "name: c1\n"})
+	commit(map[string]string{"a.txt": "second", "values/c1/v.yaml": "key: value\n"})
+
+	repo, err := Open(filepath.Join(dir, "values"))
+	testfuncs.MustBeNil(t, err)
+	root, err := repo.Root()
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, dir, root)
+
+	head, err := repo.Tree("HEAD")
+	testfuncs.MustBeNil(t, err)
+	testfuncs.MustBeNil(t, fstest.TestFS(head.FS(), "a.txt", "values/c1/coco.yaml", "values/c1/v.yaml"))
+
+	previous, err := repo.Tree("HEAD~1")
+	testfuncs.MustBeNil(t, err)
+	content, err := fs.ReadFile(previous.FS(), "a.txt")
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, "first", string(content))
+	matches, err := fs.Glob(previous.FS(), "values/c1/*.yaml")
+	testfuncs.MustBeNil(t, err)
+	testfuncs.CheckEqualityInterface(t, []string{"values/c1/coco.yaml"}, matches)
+
+	_, err = repo.Tree("unknown")
+	testfuncs.CheckErrs(t, errors.New(`failed to resolve revision "unknown": reference not found`), err)
+}
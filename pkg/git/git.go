@@ -92,6 +92,43 @@ func New(
 	return Repository{client, token, path, remote, maxDepth}, nil
 }
 
+// Open opens the existing git repository that holds path (path or one of its
+// parent folders). Nothing is fetched or checked out.
+func Open(path string) (Repository, error) {
+	client, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return Repository{}, err
+	}
+	return Repository{Client: client, Path: path}, nil
+}
+
+// Root returns the root folder of the worktree of the repository.
+func (r *Repository) Root() (string, error) {
+	w, err := r.Client.Worktree()
+	if err != nil {
+		return "", err
+	}
+	return w.Filesystem.Root(), nil
+}
+
+// Tree returns the tree of the revision (e.g. a branch, "origin/main", "HEAD~1"
+// or a commit hash) without checking it out.
+func (r *Repository) Tree(revision string) (*Tree, error) {
+	hash, err := r.Client.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve revision %q: %w", revision, err)
+	}
+	commit, err := r.Client.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return &Tree{r.Client, tree, commit}, nil
+}
+
 func (r *Repository) Checkout(branch string, force bool) (res *Tree, err error) {
 	if _, err = r.Client.Branch(branch); err != nil {
 		if err = r.Client.Fetch(&git.FetchOptions{
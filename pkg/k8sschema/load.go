@@ -0,0 +1,209 @@
+package k8sschema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed all:schemas
+var bundled embed.FS
+
+const definitionsFile = "_definitions.json"
+
+// Validator validates Kubernetes objects against the schemas of their kind and
+// apiVersion.
+type Validator struct {
+	schemas map[string]*Schema
+	// byFileName holds the JSON schemas without group version kinds by the name
+	// of their file (see GroupVersionKind.fileKey)
+	byFileName map[string]*Schema
+	// fileKeys holds the keys of the schemas per file key, so that later schema
+	// files can replace them
+	fileKeys map[string][]string
+}
+
+// New creates a Validator with the bundled schemas and the schemas found in the
+// provided directories. Schemas from directories take precedence over bundled
+// schemas, later directories over earlier ones. A directory can hold
+//   - JSON schema files named "<kind>-<group>-<version>.json" (or
+//     "<kind>-<version>.json" for the core group), optionally with a shared
+//     "_definitions.json" file, as provided by the kubernetes-json-schema project
+//   - yaml files with CustomResourceDefinitions
+func New(dirs ...string) (*Validator, error) {
+	v := Validator{
+		schemas: map[string]*Schema{}, byFileName: map[string]*Schema{}, fileKeys: map[string][]string{},
+	}
+	sub, err := fs.Sub(bundled, "schemas")
+	if err != nil {
+		return nil, err
+	}
+	if err := v.load(sub); err != nil {
+		return nil, fmt.Errorf("failed to load bundled schemas: %w", err)
+	}
+	for _, d := range dirs {
+		if err := v.load(os.DirFS(d)); err != nil {
+			return nil, fmt.Errorf("failed to load schemas from %q: %w", d, err)
+		}
+	}
+	return &v, nil
+}
+
+// load reads all schemas of the file system. References between JSON schema
+// files are resolved within the directory of the referencing file.
+func (v *Validator) load(fsys fs.FS) error {
+	type jsonSchema struct {
+		name   string
+		schema *Schema
+	}
+	docs := map[string]map[string]*Schema{}
+	found := []jsonSchema{}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch path.Ext(p) {
+		case ".json":
+			s, err := readJSONSchema(fsys, p)
+			if err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+			dir := path.Dir(p)
+			if docs[dir] == nil {
+				docs[dir] = map[string]*Schema{}
+			}
+			docs[dir][path.Base(p)] = s
+			found = append(found, jsonSchema{p, s})
+		case ".yaml", ".yml":
+			if err := v.loadCRDs(fsys, p); err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range found {
+		if path.Base(f.name) == definitionsFile {
+			continue
+		}
+		if err := f.schema.link(f.schema, docs[path.Dir(f.name)], map[*Schema]bool{}); err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+		if len(f.schema.GroupVersionKinds) == 0 {
+			v.registerFile(strings.ToLower(strings.TrimSuffix(path.Base(f.name), ".json")), f.schema)
+			continue
+		}
+		for _, g := range f.schema.GroupVersionKinds {
+			v.register(g, f.schema)
+		}
+	}
+	return nil
+}
+
+func readJSONSchema(fsys fs.FS, p string) (*Schema, error) {
+	b, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return nil, err
+	}
+	var s Schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// register adds the schema for the group version kind. It replaces schemas that
+// have been loaded before for the same kind, also if they are only identified by
+// their file name.
+func (v *Validator) register(g GroupVersionKind, s *Schema) {
+	v.schemas[g.key()] = s
+	delete(v.byFileName, g.fileKey())
+	v.fileKeys[g.fileKey()] = append(v.fileKeys[g.fileKey()], g.key())
+}
+
+// registerFile adds a schema without group version kinds by its file key. It
+// replaces all schemas that have been loaded before for the same file key.
+func (v *Validator) registerFile(fileKey string, s *Schema) {
+	for _, k := range v.fileKeys[fileKey] {
+		delete(v.schemas, k)
+	}
+	delete(v.fileKeys, fileKey)
+	v.byFileName[fileKey] = s
+}
+
+// crd holds the relevant parts of a CustomResourceDefinition.
+type crd struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Spec       struct {
+		Group string `yaml:"group"`
+		Names struct {
+			Kind string `yaml:"kind"`
+		} `yaml:"names"`
+		Versions []struct {
+			Name   string `yaml:"name"`
+			Schema struct {
+				OpenAPIV3Schema interface{} `yaml:"openAPIV3Schema"`
+			} `yaml:"schema"`
+		} `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+// loadCRDs registers the schemas of all CustomResourceDefinitions in a yaml file.
+// Other documents are ignored. CRD schemas are closed, i.e. unknown fields are
+// reported (the API server would drop them silently).
+func (v *Validator) loadCRDs(fsys fs.FS, p string) error {
+	b, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return err
+	}
+	d := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var c crd
+		err := d.Decode(&c)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if c.Kind != "CustomResourceDefinition" || !strings.HasPrefix(c.APIVersion, "apiextensions.k8s.io/") {
+			continue
+		}
+		for _, version := range c.Spec.Versions {
+			if version.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			raw, err := json.Marshal(version.Schema.OpenAPIV3Schema)
+			if err != nil {
+				return err
+			}
+			var s Schema
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return fmt.Errorf("schema of %s/%s: %w", c.Spec.Names.Kind, version.Name, err)
+			}
+			s.close(map[*Schema]bool{})
+			gvk := GroupVersionKind{Group: c.Spec.Group, Version: version.Name, Kind: c.Spec.Names.Kind}
+			v.register(gvk, &s)
+		}
+	}
+}
+
+// schemaFor returns the schema for the group version kind (nil if unknown).
+func (v *Validator) schemaFor(gvk GroupVersionKind) *Schema {
+	if s, ok := v.schemas[gvk.key()]; ok {
+		return s
+	}
+	return v.byFileName[gvk.fileKey()]
+}
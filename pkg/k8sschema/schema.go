@@ -0,0 +1,211 @@
+// Package k8sschema validates Kubernetes objects offline against OpenAPI / JSON
+// schemas. Schemas are looked up by kind and apiVersion and are either bundled
+// with this package, read from JSON schema files (in the format of the
+// kubernetes-json-schema project) or extracted from CustomResourceDefinitions.
+package k8sschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Schema is the subset of a JSON schema (respectively an OpenAPI v3 schema) that
+// is used for validating Kubernetes objects.
+type Schema struct {
+	Types                []string           `json:"-"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *Schema            `json:"items"`
+	AdditionalProperties *Schema            `json:"-"`
+	// NoAdditionalProperties is set for "additionalProperties: false"
+	NoAdditionalProperties bool               `json:"-"`
+	Enum                   []interface{}      `json:"enum"`
+	Pattern                string             `json:"pattern"`
+	Ref                    string             `json:"$ref"`
+	Definitions            map[string]*Schema `json:"definitions"`
+	AllOf                  []*Schema          `json:"allOf"`
+	AnyOf                  []*Schema          `json:"anyOf"`
+	OneOf                  []*Schema          `json:"oneOf"`
+	Nullable               bool               `json:"nullable"`
+	IntOrString            bool               `json:"x-kubernetes-int-or-string"`
+	PreserveUnknownFields  bool               `json:"x-kubernetes-preserve-unknown-fields"`
+	GroupVersionKinds      []GroupVersionKind `json:"x-kubernetes-group-version-kind"`
+
+	// closed schemas report unknown fields of objects even if additional
+	// properties are not forbidden explicitly (used for CRD schemas)
+	closed bool
+	// ref holds the resolved Ref
+	ref *Schema
+}
+
+// GroupVersionKind identifies the type of a Kubernetes object.
+type GroupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// ParseGroupVersionKind splits the apiVersion (e.g. "apps/v1" or "v1") of a
+// Kubernetes object.
+func ParseGroupVersionKind(apiVersion, kind string) GroupVersionKind {
+	group, version, found := strings.Cut(apiVersion, "/")
+	if !found {
+		return GroupVersionKind{Version: apiVersion, Kind: kind}
+	}
+	return GroupVersionKind{Group: group, Version: version, Kind: kind}
+}
+
+func (g GroupVersionKind) String() string {
+	if g.Group == "" {
+		return fmt.Sprintf("%s/%s", g.Version, g.Kind)
+	}
+	return fmt.Sprintf("%s/%s/%s", g.Group, g.Version, g.Kind)
+}
+
+// key identifies the schema of a GroupVersionKind.
+func (g GroupVersionKind) key() string {
+	if g.Group == "" {
+		return strings.ToLower(fmt.Sprintf("%s-%s", g.Kind, g.Version))
+	}
+	return strings.ToLower(fmt.Sprintf("%s-%s-%s", g.Kind, g.Group, g.Version))
+}
+
+// fileKey identifies JSON schema files without group version kinds. Their names
+// only hold the first label of the group (e.g. "ingress-networking-v1.json" for
+// networking.k8s.io/v1).
+func (g GroupVersionKind) fileKey() string {
+	group, _, _ := strings.Cut(g.Group, ".")
+	return GroupVersionKind{Group: group, Version: g.Version, Kind: g.Kind}.key()
+}
+
+// UnmarshalJSON handles the fields of a JSON schema that can have different
+// types: "type" (string or list of strings) and "additionalProperties" (bool or
+// schema).
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	type plain Schema
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	var raw struct {
+		Type                 json.RawMessage `json:"type"`
+		AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*s = Schema(p)
+
+	if len(raw.Type) > 0 {
+		var single string
+		if err := json.Unmarshal(raw.Type, &single); err == nil {
+			s.Types = []string{single}
+		} else if err := json.Unmarshal(raw.Type, &s.Types); err != nil {
+			return fmt.Errorf("invalid schema type %s", raw.Type)
+		}
+	}
+	if len(raw.AdditionalProperties) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(raw.AdditionalProperties, &allowed); err == nil {
+			s.NoAdditionalProperties = !allowed
+		} else {
+			s.AdditionalProperties = &Schema{}
+			if err := json.Unmarshal(raw.AdditionalProperties, s.AdditionalProperties); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolved follows the reference of the schema (if any).
+func (s *Schema) resolved() *Schema {
+	for s.ref != nil {
+		s = s.ref
+	}
+	return s
+}
+
+// link resolves all references of the schema tree. References to the own
+// document start with "#", references to other documents (e.g.
+// "_definitions.json#/definitions/...") are looked up in docs.
+func (s *Schema) link(root *Schema, docs map[string]*Schema, visited map[*Schema]bool) error {
+	if s == nil || visited[s] {
+		return nil
+	}
+	visited[s] = true
+	if s.Ref != "" {
+		target, targetRoot, err := lookupRef(s.Ref, root, docs)
+		if err != nil {
+			return err
+		}
+		s.ref = target
+		if err := target.link(targetRoot, docs, visited); err != nil {
+			return err
+		}
+	}
+	for _, c := range s.children() {
+		if err := c.link(root, docs, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) children() []*Schema {
+	res := make([]*Schema, 0, len(s.Properties)+len(s.Definitions)+2)
+	for _, p := range s.Properties {
+		res = append(res, p)
+	}
+	for _, d := range s.Definitions {
+		res = append(res, d)
+	}
+	res = append(res, s.Items, s.AdditionalProperties)
+	res = append(res, s.AllOf...)
+	res = append(res, s.AnyOf...)
+	return append(res, s.OneOf...)
+}
+
+// lookupRef returns the schema the reference points to and the root of the
+// document that holds it.
+func lookupRef(ref string, root *Schema, docs map[string]*Schema) (target, doc *Schema, err error) {
+	file, pointer, _ := strings.Cut(ref, "#")
+	doc = root
+	if file != "" {
+		d, ok := docs[file]
+		if !ok {
+			return nil, nil, fmt.Errorf("unresolved schema reference %q", ref)
+		}
+		doc = d
+	}
+	current := doc
+	for _, p := range strings.Split(strings.Trim(pointer, "/"), "/") {
+		if p == "" {
+			continue
+		}
+		switch p {
+		case "definitions":
+			continue
+		default:
+			next, ok := current.Definitions[strings.ReplaceAll(p, "~1", "/")]
+			if !ok {
+				return nil, nil, fmt.Errorf("unresolved schema reference %q", ref)
+			}
+			current = next
+		}
+	}
+	return current, doc, nil
+}
+
+// close marks the schema tree as closed (see Schema.closed).
+func (s *Schema) close(visited map[*Schema]bool) {
+	if s == nil || visited[s] {
+		return
+	}
+	visited[s] = true
+	s.closed = true
+	for _, c := range s.children() {
+		c.close(visited)
+	}
+}
@@ -0,0 +1,294 @@
+package k8sschema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rootFields are always allowed at the top level of an object, also for closed
+// schemas that do not list them.
+var rootFields = map[string]bool{"apiVersion": true, "kind": true, "metadata": true}
+
+// FieldError describes a field of a Kubernetes object that does not match the
+// schema. Path is the dot separated path of the field, e.g.
+// "spec.template.spec.containers[0].image".
+type FieldError struct {
+	Path string
+	Msg  string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+}
+
+// Result holds the validation result of a single Kubernetes object.
+type Result struct {
+	// Document is the index of the yaml document in the validated file
+	Document int
+	GroupVersionKind
+	// Name is the name of the object (from its metadata). It is empty for objects
+	// without a name, e.g. objects with a generateName.
+	Name string
+	// MissingSchema reports that no schema is known for the object
+	MissingSchema bool
+	Errors        []FieldError
+}
+
+// ValidateYaml validates all Kubernetes objects in the (multi-document) yaml
+// content. Documents that are no Kubernetes objects (i.e. without apiVersion or
+// kind) are skipped.
+func (v *Validator) ValidateYaml(content []byte) ([]Result, error) {
+	d := yaml.NewDecoder(bytes.NewReader(content))
+	res := []Result{}
+	for i := 0; ; i++ {
+		var doc interface{}
+		err := d.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		apiVersion, okVersion := obj["apiVersion"].(string)
+		kind, okKind := obj["kind"].(string)
+		if !okVersion || !okKind {
+			continue
+		}
+		r := Result{Document: i, GroupVersionKind: ParseGroupVersionKind(apiVersion, kind)}
+		if m, ok := obj["metadata"].(map[string]interface{}); ok {
+			r.Name, _ = m["name"].(string)
+		}
+		r.Errors, r.MissingSchema = v.Validate(obj)
+		res = append(res, r)
+	}
+}
+
+// Validate validates a single Kubernetes object and reports whether no schema is
+// known for its kind and apiVersion.
+func (v *Validator) Validate(obj map[string]interface{}) (errs []FieldError, missingSchema bool) {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	s := v.schemaFor(ParseGroupVersionKind(apiVersion, kind))
+	if s == nil {
+		return []FieldError{}, true
+	}
+	c := checker{errs: []FieldError{}}
+	c.check(s, obj, "", true)
+	return c.errs, false
+}
+
+type checker struct {
+	errs []FieldError
+}
+
+func (c *checker) add(path, format string, args ...interface{}) {
+	if path == "" {
+		path = "(root)"
+	}
+	c.errs = append(c.errs, FieldError{Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+// check validates the value against the schema. Null values are accepted for all
+// fields since the API server treats them as unset.
+func (c *checker) check(s *Schema, value interface{}, path string, root bool) {
+	if s == nil {
+		return
+	}
+	s = s.resolved()
+	if value == nil {
+		return
+	}
+	for _, sub := range s.AllOf {
+		c.check(sub, value, path, root)
+	}
+	if !c.matchesAny(s.AnyOf, value, path, root) || !c.matchesAny(s.OneOf, value, path, root) {
+		c.add(path, "does not match any of the allowed schemas")
+		return
+	}
+	if !checkType(s, value) {
+		c.add(path, "expected %s, got %s", typeNames(s), typeOf(value))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		c.add(path, "value %v is not one of %v", value, s.Enum)
+	}
+	if str, ok := value.(string); ok && s.Pattern != "" {
+		if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(str) {
+			c.add(path, "value %q does not match the pattern %q", str, s.Pattern)
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		c.checkObject(s, val, path, root)
+	case []interface{}:
+		for i, el := range val {
+			c.check(s.Items, el, fmt.Sprintf("%s[%d]", path, i), false)
+		}
+	}
+}
+
+func (c *checker) matchesAny(alternatives []*Schema, value interface{}, path string, root bool) bool {
+	if len(alternatives) == 0 {
+		return true
+	}
+	for _, a := range alternatives {
+		sub := checker{errs: []FieldError{}}
+		sub.check(a, value, path, root)
+		if len(sub.errs) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *checker) checkObject(s *Schema, obj map[string]interface{}, path string, root bool) {
+	for _, r := range s.Required {
+		if _, ok := obj[r]; !ok {
+			c.add(join(path, r), "required field is missing")
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if p, ok := s.Properties[k]; ok {
+			c.check(p, obj[k], join(path, k), false)
+			continue
+		}
+		switch {
+		case s.AdditionalProperties != nil:
+			c.check(s.AdditionalProperties, obj[k], join(path, k), false)
+		case root && rootFields[k]:
+		case s.NoAdditionalProperties,
+			s.closed && !s.PreserveUnknownFields && len(s.Properties) > 0:
+			c.add(join(path, k), "unknown field")
+		}
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return fmt.Sprintf("%s.%s", path, key)
+}
+
+func checkType(s *Schema, value interface{}) bool {
+	if s.IntOrString {
+		return isInteger(value) || isString(value)
+	}
+	if len(s.Types) == 0 {
+		return true
+	}
+	for _, t := range s.Types {
+		if matchesType(t, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		return isString(value)
+	case "integer":
+		return isInteger(value)
+	case "number":
+		return isInteger(value) || isFloat(value)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return true
+	}
+}
+
+func isString(value interface{}) bool {
+	switch value.(type) {
+	case string, time.Time:
+		return true
+	default:
+		return false
+	}
+}
+
+func isInteger(value interface{}) bool {
+	switch v := value.(type) {
+	case int, int64, uint64:
+		return true
+	case float64:
+		return v == math.Trunc(v)
+	default:
+		return false
+	}
+}
+
+func isFloat(value interface{}) bool {
+	_, ok := value.(float64)
+	return ok
+}
+
+func typeNames(s *Schema) string {
+	if s.IntOrString {
+		return "integer or string"
+	}
+	if len(s.Types) == 1 {
+		return s.Types[0]
+	}
+	return fmt.Sprint(s.Types)
+}
+
+func typeOf(value interface{}) string {
+	switch {
+	case isString(value):
+		return "string"
+	case isInteger(value):
+		return "integer"
+	case isFloat(value):
+		return "number"
+	}
+	switch value.(type) {
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return reflect.TypeOf(value).String()
+	}
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
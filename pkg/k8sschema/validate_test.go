@@ -0,0 +1,440 @@
+package k8sschema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+)
+
+const crdFile = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: tenants.example.com
+spec:
+  group: example.com
+  names:
+    kind: Tenant
+  versions:
+  - name: v1alpha1
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required: [owner]
+            properties:
+              owner:
+                type: string
+              replicas:
+                type: integer
+              port:
+                x-kubernetes-int-or-string: true
+              tier:
+                type: string
+                enum: [gold, silver]
+              extra:
+                type: object
+                x-kubernetes-preserve-unknown-fields: true
+`
+
+const deploymentSchema = `{
+  "type": "object",
+  "properties": {
+    "metadata": {"$ref": "_definitions.json#/definitions/meta"},
+    "spec": {
+      "type": "object",
+      "properties": {
+        "containers": {
+          "type": "array",
+          "items": {"$ref": "_definitions.json#/definitions/container"}
+        }
+      }
+    }
+  },
+  "x-kubernetes-group-version-kind": [{"group": "apps", "kind": "Deployment", "version": "v1"}]
+}`
+
+const definitions = `{
+  "definitions": {
+    "meta": {"type": "object", "properties": {"name": {"type": "string"}}},
+    "container": {
+      "type": "object",
+      "required": ["image"],
+      "properties": {"image": {"type": ["string", "null"]}},
+      "additionalProperties": false
+    }
+  }
+}`
+
+func TestValidateYaml(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"crds/tenant.yaml":               crdFile,
+		"json/deployment-apps-v1.json":   deploymentSchema,
+		"json/_definitions.json":         definitions,
+		"json/unrelated/not-schema.yaml": "kind: Foo\n",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, err := New(filepath.Join(dir, "crds"), filepath.Join(dir, "json"))
+	if err != nil {
+		t.Fatalf("unable to create validator: %v", err)
+	}
+
+	for _, s := range []struct {
+		title string
+		input string
+		want  []Result
+	}{
+		{
+			title: "valid bundled configmap",
+			input: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\ndata:\n  k: v\n",
+			want: []Result{{
+				GroupVersionKind: GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+				Name:             "a",
+				Errors:           []FieldError{},
+			}},
+		},
+		{
+			title: "invalid bundled configmap",
+			input: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n  labels:\n    " +
+				"team: [x]\ndata:\n  k: 1\nspec: {}\n",
+			want: []Result{{
+				GroupVersionKind: GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+				Name:             "a",
+				Errors: []FieldError{
+					{Path: "data.k", Msg: "expected string, got integer"},
+					{Path: "metadata.labels.team", Msg: "expected string, got array"},
+					{Path: "spec", Msg: "unknown field"},
+				},
+			}},
+		},
+		{
+			title: "object without a name",
+			input: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  generateName: a-\n",
+			want: []Result{{
+				GroupVersionKind: GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+				Errors:           []FieldError{},
+			}},
+		},
+		{
+			title: "bundled volume sources",
+			input: `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: j1
+spec:
+  template:
+    spec:
+      containers:
+      - name: main
+        image: busybox
+      volumes:
+      - name: config
+        configMap:
+          name: c1
+      - name: cache
+        emptyDir: {}
+      - name: typo
+        configmap:
+          name: c1
+`,
+			want: []Result{{
+				GroupVersionKind: GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"},
+				Name:             "j1",
+				Errors: []FieldError{
+					{Path: "spec.template.spec.volumes[2].configmap", Msg: "unknown field"},
+				},
+			}},
+		},
+		{
+			title: "custom resource",
+			input: `
+apiVersion: example.com/v1alpha1
+kind: Tenant
+metadata:
+  name: t1
+spec:
+  replicas: "2"
+  port: http
+  tier: bronze
+  unknown: true
+  extra:
+    anything: goes
+`,
+			want: []Result{{
+				GroupVersionKind: GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Tenant"},
+				Name:             "t1",
+				Errors: []FieldError{
+					{Path: "spec.owner", Msg: "required field is missing"},
+					{Path: "spec.replicas", Msg: "expected integer, got string"},
+					{Path: "spec.tier", Msg: "value bronze is not one of [gold silver]"},
+					{Path: "spec.unknown", Msg: "unknown field"},
+				},
+			}},
+		},
+		{
+			title: "json schema with references and several documents",
+			input: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d1
+spec:
+  containers:
+  - image: nginx
+  - name: sidecar
+---
+# only a comment
+---
+apiVersion: v1
+kind: Unknown
+metadata:
+  name: u1
+`,
+			want: []Result{
+				{
+					GroupVersionKind: GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+					Name:             "d1",
+					Errors: []FieldError{
+						{Path: "spec.containers[1].image", Msg: "required field is missing"},
+						{Path: "spec.containers[1].name", Msg: "unknown field"},
+					},
+				},
+				{
+					Document:         2,
+					GroupVersionKind: GroupVersionKind{Version: "v1", Kind: "Unknown"},
+					Name:             "u1",
+					MissingSchema:    true,
+					Errors:           []FieldError{},
+				},
+			},
+		},
+		{
+			title: "no kubernetes objects",
+			input: "just: values\nlist: [1, 2]\n",
+			want:  []Result{},
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		got, err := v.ValidateYaml([]byte(s.input))
+		testfuncs.CheckErrs(t, nil, err)
+		testfuncs.CheckEqualityInterface(t, s.want, got)
+	}
+}
+
+func TestNewUnresolvedReference(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(
+		filepath.Join(dir, "foo-v1.json"),
+		[]byte(`{"properties": {"spec": {"$ref": "_definitions.json#/definitions/missing"}}}`),
+		0o644,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = New(dir)
+	if err == nil {
+		t.Fatal("expected an error for an unresolved reference")
+	}
+}
+
+const ingressCRD = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: ingresses.networking.example.com
+spec:
+  group: networking.example.com
+  names:
+    kind: Ingress
+  versions:
+  - name: v1
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              gateway:
+                type: string
+`
+
+func TestBundledSchemas(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ingress.yaml"), []byte(ingressCRD), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v, err := New(dir)
+	if err != nil {
+		t.Fatalf("unable to create validator: %v", err)
+	}
+
+	for _, s := range []struct {
+		title string
+		input string
+		want  []FieldError
+	}{
+		{
+			title: "deployment",
+			input: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: "2"
+  selector:
+    matchLabels:
+      app: app
+  strategy:
+    rollingUpdate:
+      maxSurge: 25%
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      containers:
+      - name: app
+        image: nginx
+        imagePullPolicy: Sometimes
+        ports:
+        - containerPort: 80
+        resources:
+          limits:
+            cpu: 1
+            memory: 1Gi
+        volumeMounts:
+        - name: config
+      volumes:
+      - name: config
+        configMap:
+          name: app
+`,
+			want: []FieldError{
+				{Path: "spec.replicas", Msg: "expected integer, got string"},
+				{
+					Path: "spec.template.spec.containers[0].imagePullPolicy",
+					Msg:  "value Sometimes is not one of [Always IfNotPresent Never]",
+				},
+				{Path: "spec.template.spec.containers[0].volumeMounts[0].mountPath", Msg: "required field is missing"},
+			},
+		},
+		{
+			title: "service",
+			input: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  type: ClusterIP
+  selector:
+    app: app
+  ports:
+  - port: 80
+    targetPort: http
+  - targetPort: 8080
+`,
+			want: []FieldError{{Path: "spec.ports[1].port", Msg: "required field is missing"}},
+		},
+		{
+			title: "ingress",
+			input: `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: app
+spec:
+  rules:
+  - host: app.example.com
+    http:
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: app
+            port:
+              number: 80
+  gateway: public
+`,
+			want: []FieldError{{Path: "spec.gateway", Msg: "unknown field"}},
+		},
+		{
+			title: "custom resource of a group with the same first label",
+			input: `
+apiVersion: networking.example.com/v1
+kind: Ingress
+metadata:
+  name: app
+spec:
+  gateway: public
+`,
+			want: []FieldError{},
+		},
+		{
+			title: "cron job",
+			input: `
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: cleanup
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      backoffLimit: 2
+      template:
+        spec:
+          restartPolicy: OnFailure
+          containers:
+          - name: cleanup
+            image: busybox
+            command: [sh, -c, "true"]
+`,
+			want: []FieldError{},
+		},
+		{
+			title: "role binding",
+			input: `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: read
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: read
+subjects:
+- kind: ServiceAccount
+  name: app
+- kind: Robot
+  name: r2
+`,
+			want: []FieldError{{Path: "subjects[1].kind", Msg: "value Robot is not one of [ServiceAccount User Group]"}},
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		got, err := v.ValidateYaml([]byte(s.input))
+		testfuncs.CheckErrs(t, nil, err)
+		if len(got) != 1 || got[0].MissingSchema {
+			t.Errorf("want one object with a known schema, got %+v", got)
+			continue
+		}
+		testfuncs.CheckEqualityInterface(t, s.want, got[0].Errors)
+	}
+}
@@ -0,0 +1,231 @@
+package yamlfile
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+const mergeKeyTag = "!!merge"
+
+// resolveAliases returns a deep copy of n in which all aliases are replaced by
+// copies of the nodes they refer to and all merge keys ("<<") are expanded into
+// the keys of the referenced maps. The copy holds no anchors, so that it can be
+// added to any other yaml.Node without leaving dangling aliases behind.
+func resolveAliases(n *yaml.Node) *yaml.Node {
+	switch n.Kind {
+	case yaml.AliasNode:
+		if n.Alias == nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+		}
+		res := resolveAliases(n.Alias)
+		res.HeadComment = n.HeadComment
+		res.LineComment = n.LineComment
+		res.FootComment = n.FootComment
+		return res
+	case yaml.MappingNode:
+		res := copyNodeProperties(n)
+		res.Content = resolveMapContent(n.Content)
+		return res
+	default:
+		res := copyNodeProperties(n)
+		res.Content = make([]*yaml.Node, 0, len(n.Content))
+		for _, c := range n.Content {
+			res.Content = append(res.Content, resolveAliases(c))
+		}
+		return res
+	}
+}
+
+// resolveMapContent resolves the key-value pairs of a map. The pairs of maps
+// referenced by a merge key are inserted at the position of the merge key, unless
+// the key is set explicitly in the map or by an earlier merge.
+func resolveMapContent(content []*yaml.Node) []*yaml.Node {
+	explicit := map[string]bool{}
+	for i := 0; i+1 < len(content); i += 2 {
+		if !isMergeKey(content[i]) {
+			explicit[content[i].Value] = true
+		}
+	}
+	added := map[string]bool{}
+	res := make([]*yaml.Node, 0, len(content))
+	for i := 0; i+1 < len(content); i += 2 {
+		if !isMergeKey(content[i]) {
+			res = append(res, resolveAliases(content[i]), resolveAliases(content[i+1]))
+			continue
+		}
+		for _, m := range mergedMaps(resolveAliases(content[i+1])) {
+			for j := 0; j+1 < len(m.Content); j += 2 {
+				k := m.Content[j].Value
+				if explicit[k] || added[k] {
+					continue
+				}
+				added[k] = true
+				res = append(res, m.Content[j], m.Content[j+1])
+			}
+		}
+	}
+	return res
+}
+
+// mergedMaps returns the maps that are referenced by the value of a merge key
+// (either a single map or a sequence of maps).
+func mergedMaps(v *yaml.Node) []*yaml.Node {
+	switch v.Kind {
+	case yaml.MappingNode:
+		return []*yaml.Node{v}
+	case yaml.SequenceNode:
+		res := make([]*yaml.Node, 0, len(v.Content))
+		for _, c := range v.Content {
+			if c.Kind == yaml.MappingNode {
+				res = append(res, c)
+			}
+		}
+		return res
+	default:
+		return []*yaml.Node{}
+	}
+}
+
+func isMergeKey(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Value == "<<" && n.ShortTag() == mergeKeyTag
+}
+
+func copyNodeProperties(n *yaml.Node) *yaml.Node {
+	return &yaml.Node{
+		Kind:        n.Kind,
+		Style:       n.Style,
+		Tag:         n.Tag,
+		Value:       n.Value,
+		HeadComment: n.HeadComment,
+		LineComment: n.LineComment,
+		FootComment: n.FootComment,
+		Line:        n.Line,
+		Column:      n.Column,
+	}
+}
+
+// materialize replaces the alias node n by a resolved copy of the node it refers
+// to. The comments of n are kept.
+func materialize(n *yaml.Node) {
+	*n = *resolveAliases(n)
+}
+
+// aliasesByTarget returns the alias nodes below root by the nodes they refer to.
+func aliasesByTarget(root *yaml.Node) map[*yaml.Node][]*yaml.Node {
+	res := map[*yaml.Node][]*yaml.Node{}
+	var collect func(n *yaml.Node)
+	collect = func(n *yaml.Node) {
+		if n.Kind == yaml.AliasNode {
+			res[n.Alias] = append(res[n.Alias], n)
+			return
+		}
+		for _, c := range n.Content {
+			collect(c)
+		}
+	}
+	collect(root)
+	return res
+}
+
+// detachAliases materializes the aliases that refer to the anchored node n
+// before n is merged, so that they keep the former content of n. The anchor of
+// n is removed since no alias refers to it anymore.
+func (m *merger) detachAliases(n *yaml.Node) {
+	for _, a := range m.aliases[n] {
+		if a.Kind == yaml.AliasNode && a.Alias == n {
+			materialize(a)
+		}
+	}
+	delete(m.aliases, n)
+	n.Anchor = ""
+}
+
+// repairAliases makes sure that every alias below root refers to an anchor that
+// is defined before the alias (in document order), which is required for a valid
+// yaml encoding. Aliases whose anchor has been removed are materialized; aliases
+// that precede their anchor (e.g. after sorting) swap places with the anchor.
+func repairAliases(root *yaml.Node) {
+	r := aliasRepair{
+		anchors: map[*yaml.Node]bool{},
+		seen:    map[*yaml.Node]bool{},
+		aliases: []*yaml.Node{},
+	}
+	r.collect(root)
+	if len(r.aliases) == 0 {
+		return
+	}
+	r.walk(root)
+}
+
+type aliasRepair struct {
+	// anchors holds all nodes with an anchor that are part of the tree
+	anchors map[*yaml.Node]bool
+	// seen holds all anchored nodes that have been visited by the walk
+	seen map[*yaml.Node]bool
+	// aliases holds all alias nodes of the tree
+	aliases []*yaml.Node
+}
+
+func (r *aliasRepair) collect(n *yaml.Node) {
+	if n.Kind == yaml.AliasNode {
+		r.aliases = append(r.aliases, n)
+		return
+	}
+	if n.Anchor != "" {
+		r.anchors[n] = true
+	}
+	for _, c := range n.Content {
+		r.collect(c)
+	}
+}
+
+func (r *aliasRepair) walk(n *yaml.Node) {
+	if n.Kind == yaml.AliasNode {
+		t := n.Alias
+		switch {
+		case t == nil || t.Anchor == "" || !r.anchors[t]:
+			materialize(n)
+			return
+		case r.seen[t]:
+			n.Value = t.Anchor
+			return
+		default:
+			r.swapAnchor(n, t)
+		}
+	}
+	if n.Anchor != "" {
+		r.seen[n] = true
+	}
+	for _, c := range n.Content {
+		r.walk(c)
+	}
+}
+
+// swapAnchor moves the anchored content of anchor to the position of alias and
+// turns anchor into an alias. Comments stay at their positions.
+func (r *aliasRepair) swapAnchor(alias, anchor *yaml.Node) {
+	data := *anchor
+	alias.Kind = data.Kind
+	alias.Style = data.Style
+	alias.Tag = data.Tag
+	alias.Value = data.Value
+	alias.Anchor = data.Anchor
+	alias.Alias = nil
+	alias.Content = data.Content
+
+	anchor.Kind = yaml.AliasNode
+	anchor.Style = 0
+	anchor.Tag = ""
+	anchor.Value = data.Anchor
+	anchor.Anchor = ""
+	anchor.Alias = alias
+	anchor.Content = nil
+
+	delete(r.anchors, anchor)
+	r.anchors[alias] = true
+	for _, a := range r.aliases {
+		if a.Alias == anchor {
+			a.Alias = alias
+		}
+	}
+	r.aliases = append(r.aliases, anchor)
+}
@@ -0,0 +1,77 @@
+package yamlfile
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// markBlankLines preserves the blank lines of the input, which are not part of the
+// yaml.Node representation. Every map entry and sequence element (except the
+// first one in its parent) that is preceded by a blank line in the input gets a
+// blank line prepended to its head comment, which the yaml encoder turns into an
+// empty line again.
+func markBlankLines(n *yaml.Node, lines []string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			markBlankLines(c, lines)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 && !hasFootComment(n.Content[i-1]) && !hasFootComment(n.Content[i-2]) {
+				markBlankLine(n.Content[i], lines)
+			}
+			markBlankLines(n.Content[i+1], lines)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			if i > 0 && !hasFootComment(n.Content[i-1]) {
+				markBlankLine(c, lines)
+			}
+			markBlankLines(c, lines)
+		}
+	}
+}
+
+// markBlankLine prepends a blank line to the head comment of n if the line above
+// n (and above its head comment) is empty.
+func markBlankLine(n *yaml.Node, lines []string) {
+	first := n.Line
+	if n.HeadComment != "" {
+		first -= strings.Count(n.HeadComment, "\n") + 1
+	}
+	// Line numbers start at 1, hence the line above n is at index first-2.
+	above := first - 2
+	if above < 0 || above >= len(lines) {
+		return
+	}
+	if strings.TrimSpace(lines[above]) == "" {
+		n.HeadComment = "\n" + n.HeadComment
+	}
+}
+
+// hasFootComment reports whether n or its last descendant holds a foot comment.
+// The yaml encoder already separates foot comments by an empty line.
+func hasFootComment(n *yaml.Node) bool {
+	if n.FootComment != "" {
+		return true
+	}
+	if len(n.Content) == 0 {
+		return false
+	}
+	return hasFootComment(n.Content[len(n.Content)-1])
+}
+
+// trimBlankLines removes the indentation of otherwise empty lines that the yaml
+// encoder writes for blank lines in nested nodes.
+func trimBlankLines(content []byte) []byte {
+	lines := bytes.Split(content, []byte("\n"))
+	for i, l := range lines {
+		if len(bytes.TrimSpace(l)) == 0 {
+			lines[i] = []byte{}
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
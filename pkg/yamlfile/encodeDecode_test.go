@@ -34,6 +34,52 @@ array:
   - v1
   - v2 # human overwrite
   - v3
+`,
+		wantErr: nil,
+	},
+	{
+		title: "comments and blank lines",
+		input: []byte(strings.TrimSpace(`
+# head of file
+
+# head of a
+a: 1 # line a
+
+# section b
+b:
+  x: 1
+
+  y: 2
+  # foot of y
+
+c:
+  - v1
+
+  - v2
+# foot of file
+`)),
+		wantDecode: map[string]interface{}{
+			"a": 1,
+			"b": map[string]interface{}{"x": 1, "y": 2},
+			"c": []interface{}{"v1", "v2"},
+		},
+		wantEncode: `# head of file
+
+# head of a
+a: 1 # line a
+
+# section b
+b:
+  x: 1
+
+  y: 2
+  # foot of y
+
+c:
+  - v1
+
+  - v2
+# foot of file
 `,
 		wantErr: nil,
 	},
@@ -17,6 +17,9 @@ import (
 //     1) the from value overwrites the into value entirely.
 //   - scalars from overwrite scalars in into
 //   - all other combinations the object in into is overwritten with the object in from
+//   - aliases and merge keys ("<<") in from are resolved before merging, aliases
+//     in into are kept unless they are merged into (then they are replaced by a
+//     copy of their anchor)
 //
 // The resulting Yaml object is NOT sorted.
 func (y *Yaml) Merge(from Yaml) ([]Warning, error) {
@@ -44,12 +47,22 @@ func (y *Yaml) MergeSelective(from Yaml, selectFlag string) ([]Warning, error) {
 
 func (y *Yaml) mergeSelective(
 	from Yaml, selectFlag string, parentSelected bool,
+) ([]Warning, error) {
+	return y.mergeTracked(from, selectFlag, parentSelected, nil)
+}
+
+func (y *Yaml) mergeTracked(
+	from Yaml, selectFlag string, parentSelected bool, t *tracker,
 ) ([]Warning, error) {
 	// from Yaml is empty
 	if from.Node.Kind == 0 || len(from.Node.Content) == 0 {
 		return []Warning{}, nil
 	}
-	m := newMerger(selectFlag, y.settings.arrayMergePolicy)
+	from = Yaml{resolveAliases(from.Node), from.settings}
+	t.init(from.Node)
+	m := newMerger(selectFlag, y.settings.Copy())
+	m.track = t
+	m.aliases = aliasesByTarget(y.Node)
 	// into yaml is empty
 	if y.Node.Kind == 0 || len(y.Node.Content) == 0 {
 		y.Node.Kind = 1
@@ -59,24 +72,32 @@ func (y *Yaml) mergeSelective(
 		}
 		if !reflect.DeepEqual(*add, yaml.Node{}) {
 			y.Node.Content = append(y.Node.Content, add.Content...)
+			for _, c := range add.Content {
+				m.track.add(c)
+			}
 		}
 		return m.warnings, nil
 	}
 	err := m.merge(from.Node, y.Node, parentSelected, []string{})
+	repairAliases(y.Node)
 	return m.warnings, err
 }
 
-func newMerger(selectFlag string, arrayMergePolicy ArrayMergePolicy) merger {
-	return merger{selectFlag, arrayMergePolicy, []Warning{}}
+func newMerger(selectFlag string, s settings) merger {
+	return merger{selectFlag, s, []Warning{}, nil, nil}
 }
 
 // merger holds general information for the yaml merging procedure. It holds the
-// selectFlag which will be used for filtering the from Yaml and a slice to capture
-// all occurring warnings.
+// selectFlag which will be used for filtering the from Yaml, the settings of the
+// into Yaml, a slice to capture all occurring warnings, the tracker of the
+// origins (nil if they are not recorded) and the aliases of the into Yaml by the
+// anchored nodes they refer to.
 type merger struct {
-	selectFlag       string
-	arrayMergePolicy ArrayMergePolicy
-	warnings         []Warning
+	selectFlag string
+	settings   settings
+	warnings   []Warning
+	track      *tracker
+	aliases    map[*yaml.Node][]*yaml.Node
 }
 
 // Warning holds the ordered list of nested keys for which a warning occurred and
@@ -94,7 +115,8 @@ func (m merger) newContent(from *yaml.Node, parentSelected bool) (*yaml.Node, er
 	if parentSelected || m.selectNode(from) {
 		return from, nil
 	}
-	selectedNodes := Yaml{from, &settings{arrayMergePolicy: m.arrayMergePolicy}}
+	s := m.settings.Copy()
+	selectedNodes := Yaml{from, &s}
 	if err := selectedNodes.FilterBy(m.selectFlag); err != nil {
 		return nil, err
 	}
@@ -114,6 +136,12 @@ func (m *merger) selectNode(n *yaml.Node) bool {
 // merge checks the types of the top level from and into yaml Nodes and deligates
 // the merging to dedicated functions
 func (m *merger) merge(from, into *yaml.Node, parentSelected bool, parentKeys []string) error {
+	if into.Kind == yaml.AliasNode {
+		materialize(into)
+	}
+	if into.Anchor != "" {
+		m.detachAliases(into)
+	}
 	t, err := mergeCombination(from.Kind, into.Kind)
 	if err != nil {
 		return err
@@ -122,10 +150,10 @@ func (m *merger) merge(from, into *yaml.Node, parentSelected bool, parentKeys []
 	case scalar2scalar:
 		m.mergeScalar(from, into, parentSelected)
 	case map2map:
+		m.track.set(into, from, false)
 		err = m.mergeMaps(from, into, parentSelected, parentKeys)
 	case sequence2sequence:
-		into.LineComment = from.LineComment
-		into.Tag = from.Tag
+		m.mergeNodeProperties(from, into, parentSelected)
 		err = m.mergeSequences(from, into, parentSelected, parentKeys)
 	case document2document:
 		err = m.merge(from.Content[0], into.Content[0], parentSelected, []string{})
@@ -142,6 +170,33 @@ func (m merger) mergeScalar(from, into *yaml.Node, parentSelected bool) {
 		into.LineComment = from.LineComment
 		into.Tag = from.Tag
 		into.Value = from.Value
+		m.track.set(into, from, true)
+	}
+}
+
+// moveFootComments moves the foot comments of the last key-value pair of the map
+// content to the pair that is appended to it, so that the foot comments remain at
+// the end of the map.
+func moveFootComments(content, pair []*yaml.Node) {
+	if len(content) < 2 || len(pair) < 2 {
+		return
+	}
+	last := content[len(content)-2:]
+	for j := range last {
+		if last[j].FootComment == "" || pair[j].FootComment != "" {
+			continue
+		}
+		pair[j].FootComment, last[j].FootComment = last[j].FootComment, ""
+	}
+}
+
+// mergeNodeProperties overwrites the line comment and tag of the into node with
+// the ones of the from node if the from node is selected. Comments of nodes that
+// are not selected (e.g. the comments of a template) are kept.
+func (m merger) mergeNodeProperties(from, into *yaml.Node, parentSelected bool) {
+	if parentSelected || m.selectNode(from) {
+		into.LineComment = from.LineComment
+		into.Tag = from.Tag
 	}
 }
 
@@ -167,11 +222,17 @@ func (m *merger) mergeDefault(from, into *yaml.Node, parentSelected bool, parent
 	}
 	if !reflect.DeepEqual(*overwrite, yaml.Node{}) {
 		*into = *overwrite
+		m.track.set(into, from, true)
+		for _, c := range into.Content {
+			m.track.add(c)
+		}
 	}
 	return nil
 }
 
-// mergeSequences runs in 1 of 2 different modes: standard and strict
+// mergeSequences runs in 1 of 4 different modes: standard, strict, append and
+// by-key. The mode is taken from the array merge policy of the settings, unless
+// an exception is configured for the path (parentKeys) of the sequence.
 //
 // in strict mode, the following rules for merging sequences are applied
 //
@@ -199,12 +260,42 @@ func (m *merger) mergeDefault(from, into *yaml.Node, parentSelected bool, parent
 // will result in
 //
 //	res = [{k1: o1, k2:o2, k3: NN3, k4: NN4}, {k3: NN5, k4: NN4, k7: NN7}, d]
+//
+// in append mode, the elements of from are appended to the elements of into
+//
+//	from = [a,b]
+//	into = [d,e,f]
+//
+// will result in
+//
+//	res = [d,e,f,a,b]
+//
+// in by-key mode, map elements are matched by the value of the merge key (e.g.
+// "name") and deep-merged, scalar elements are matched by value. Elements of from
+// without a match are appended:
+//
+//	from = [{name: a, v: NN1}, {name: c, v: NN3}, x]
+//	into = [{name: a, v: o1, w: o1}, {name: b, v: o2}, x]
+//
+// will result in
+//
+//	res = [{name: a, v: NN1, w: o1}, {name: b, v: o2}, x, {name: c, v: NN3}]
 func (m *merger) mergeSequences(
 	from, into *yaml.Node, parentSelected bool, parentKeys []string,
 ) error {
-	if m.arrayMergePolicy == Strict {
+	policy, key := m.settings.policyFor(parentKeys)
+	m.track.set(into, from, policy == Strict)
+	switch policy {
+	case Strict:
 		into.Content = from.Content
+		for _, c := range into.Content {
+			m.track.add(c)
+		}
 		return nil
+	case Append:
+		return m.appendSequence(from.Content, into, parentSelected)
+	case ByKey:
+		return m.mergeSequencesByKey(from, into, parentSelected, parentKeys, key)
 	}
 	lenFrom := len(from.Content)
 	lenInto := len(into.Content)
@@ -230,6 +321,7 @@ func (m *merger) mergeSequences(
 			}
 			if !reflect.DeepEqual(*add, yaml.Node{}) {
 				into.Content = append(into.Content, add)
+				m.track.add(add)
 			}
 			continue
 		}
@@ -247,6 +339,85 @@ func (m *merger) mergeSequences(
 	return nil
 }
 
+// appendSequence appends the selected content of the elements to the into sequence.
+func (m *merger) appendSequence(elements []*yaml.Node, into *yaml.Node, parentSelected bool) error {
+	for _, el := range elements {
+		add, err := m.newContent(el, parentSelected)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(*add, yaml.Node{}) {
+			into.Content = append(into.Content, add)
+			m.track.add(add)
+		}
+	}
+	return nil
+}
+
+// mergeSequencesByKey implements the by-key mode of mergeSequences.
+func (m *merger) mergeSequencesByKey(
+	from, into *yaml.Node, parentSelected bool, parentKeys []string, key string,
+) error {
+	lenInto := len(into.Content)
+	for _, fromEl := range from.Content {
+		j := indexByKey(into.Content[:lenInto], fromEl, key)
+		if j < 0 {
+			if err := m.appendSequence([]*yaml.Node{fromEl}, into, parentSelected); err != nil {
+				return err
+			}
+			continue
+		}
+		err := m.merge(
+			fromEl,
+			into.Content[j],
+			parentSelected,
+			append(parentKeys, fmt.Sprintf("%v", j)),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexByKey returns the index of the element in elements that matches el. Maps
+// match if they hold the same scalar value for key, scalars match if their values
+// are equal. If no element matches, -1 is returned.
+func indexByKey(elements []*yaml.Node, el *yaml.Node, key string) int {
+	switch el.Kind {
+	case yaml.ScalarNode:
+		for i, e := range elements {
+			if e.Kind == yaml.ScalarNode && e.Value == el.Value {
+				return i
+			}
+		}
+	case yaml.MappingNode:
+		v, ok := mapValue(el, key)
+		if !ok {
+			return -1
+		}
+		for i, e := range elements {
+			if e.Kind != yaml.MappingNode {
+				continue
+			}
+			if ev, ok := mapValue(e, key); ok && ev == v {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// mapValue returns the value of key in the map n if it is a scalar.
+func mapValue(n *yaml.Node, key string) (string, bool) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key && n.Content[i+1].Kind == yaml.ScalarNode {
+			return n.Content[i+1].Value, true
+		}
+	}
+	return "", false
+}
+
 // mergeMaps finds matching keys in the from and into yaml.Node, merges comments
 // and tags and subsequently calls the merge method for its values.
 func (m *merger) mergeMaps(from, into *yaml.Node,
@@ -272,8 +443,7 @@ func (m *merger) mergeMaps(from, into *yaml.Node,
 				return err
 			}
 			if fromKey == intoKey {
-				into.LineComment = from.LineComment
-				into.Tag = from.Tag
+				m.mergeNodeProperties(from, into, parentSelected)
 				// keys match: call merge on the sub-objects of from and into
 				selected := parentSelected
 				if m.selectNode(from.Content[i+1]) {
@@ -293,25 +463,57 @@ func (m *merger) mergeMaps(from, into *yaml.Node,
 		}
 		if !keyExistsInTarget {
 			if parentSelected || m.selectNode(from.Content[i+1]) {
-				into.Content = append(into.Content, from.Content[i:i+2]...)
+				m.insertPair(from, into, i, from.Content[i:i+2])
 				continue
 			}
-			selectedNodes := PartialCopy(
-				Yaml{from, &settings{arrayMergePolicy: m.arrayMergePolicy}},
-				i, i+2,
-			)
+			s := m.settings.Copy()
+			selectedNodes := PartialCopy(Yaml{from, &s}, i, i+2)
 			if err := selectedNodes.FilterBy(m.selectFlag); err != nil {
 				return err
 			}
 			if len(selectedNodes.Node.Content) > 0 {
-				into.Content = append(into.Content, selectedNodes.Node.Content...)
+				m.insertPair(from, into, i, selectedNodes.Node.Content)
 			}
 		}
 	}
 	return nil
 }
 
+// insertPair adds the key-value pair (located at index i in the from map) to the
+// into map. Per default the pair is appended. In TemplateOrder sort mode the pair
+// is inserted after the closest preceding key of the from map that exists in the
+// into map (or at the start if there is none), so that it keeps its position.
+func (m *merger) insertPair(from, into *yaml.Node, i int, pair []*yaml.Node) {
+	for _, n := range pair {
+		m.track.add(n)
+	}
+	if m.settings.sortMode != TemplateOrder {
+		moveFootComments(into.Content, pair)
+		into.Content = append(into.Content, pair...)
+		return
+	}
+	pos := 0
+	for k := i - 2; k >= 0 && pos == 0; k -= 2 {
+		for j := 0; j < len(into.Content); j += 2 {
+			if into.Content[j].Value == from.Content[k].Value {
+				pos = j + 2
+				break
+			}
+		}
+	}
+	if pos == len(into.Content) {
+		moveFootComments(into.Content, pair)
+	}
+	content := make([]*yaml.Node, 0, len(into.Content)+len(pair))
+	content = append(content, into.Content[:pos]...)
+	content = append(content, pair...)
+	into.Content = append(content, into.Content[pos:]...)
+}
+
 func readKey(n *yaml.Node) (string, error) {
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
 	if n.Kind != yaml.ScalarNode {
 		return "", fmt.Errorf("merge for non-scalar map keys is not implemented")
 	}
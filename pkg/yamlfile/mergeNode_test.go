@@ -461,6 +461,292 @@ k1:
 k1:
   - k1n1
   - k1n2
+`,
+		wantErr: nil,
+	},
+	{
+		title:    "array merge append",
+		settings: []yamlfile.UpdateSettingsFunc{yamlfile.SetArrayMergePolicy(yamlfile.Append)},
+		from: []byte(strings.TrimSpace(`
+k1:
+- !HumanInput k1n1
+- k1n2
+`)),
+		selectiveFlag: "HumanInput",
+		into: []byte(strings.TrimSpace(`
+k1:
+- k1o1
+- k1o2
+`)),
+		want: `
+k1:
+  - k1o1
+  - k1o2
+  - !HumanInput k1n1
+  - k1n2
+`,
+		wantSelective: `
+k1:
+  - k1o1
+  - k1o2
+  - !HumanInput k1n1
+`,
+		wantErr: nil,
+	},
+	{
+		title:    "array merge by key",
+		settings: []yamlfile.UpdateSettingsFunc{yamlfile.SetArrayMergePolicy(yamlfile.ByKey)},
+		from: []byte(strings.TrimSpace(`
+k1:
+- name: a
+  v: !HumanInput n1
+- name: c
+  v: n3
+- x
+`)),
+		selectiveFlag: "HumanInput",
+		into: []byte(strings.TrimSpace(`
+k1:
+- name: a
+  v: o1
+  w: o1
+- name: b
+  v: o2
+- x
+`)),
+		want: `
+k1:
+  - name: a
+    v: !HumanInput n1
+    w: o1
+  - name: b
+    v: o2
+  - x
+  - name: c
+    v: n3
+`,
+		wantSelective: `
+k1:
+  - name: a
+    v: !HumanInput n1
+    w: o1
+  - name: b
+    v: o2
+  - x
+`,
+		wantErr: nil,
+	},
+	{
+		title: "array merge exceptions",
+		settings: []yamlfile.UpdateSettingsFunc{
+			yamlfile.SetArrayMergePolicy(yamlfile.Strict),
+			yamlfile.AddArrayMergeException(yamlfile.ArrayMergeException{
+				Path: "tenants.*.cidrs", Policy: yamlfile.Append,
+			}),
+			yamlfile.AddArrayMergeException(yamlfile.ArrayMergeException{
+				Path: "tenants", Policy: yamlfile.ByKey, Key: "id",
+			}),
+		},
+		from: []byte(strings.TrimSpace(`
+tenants:
+- id: t1
+  cidrs:
+  - 10.0.1.0/24
+containers:
+- n1
+`)),
+		into: []byte(strings.TrimSpace(`
+tenants:
+- id: t1
+  cidrs:
+  - 10.0.0.0/24
+containers:
+- o1
+- o2
+`)),
+		want: `
+tenants:
+  - id: t1
+    cidrs:
+      - 10.0.0.0/24
+      - 10.0.1.0/24
+containers:
+  - n1
+`,
+		wantSelective: `
+tenants:
+  - id: t1
+    cidrs:
+      - 10.0.0.0/24
+containers:
+  - n1
+`,
+		wantErr: nil,
+	},
+	{
+		title:    "template order keeps position of added keys",
+		settings: []yamlfile.UpdateSettingsFunc{yamlfile.SetSortMode(yamlfile.TemplateOrder)},
+		from: []byte(strings.TrimSpace(`
+first: !HumanInput o0
+kind: o1
+manual: !HumanInput o2
+spec:
+  a: o3
+  keep: !HumanInput o4
+  b: o5
+`)),
+		selectiveFlag: "HumanInput",
+		into: []byte(strings.TrimSpace(`
+kind: n1
+spec:
+  b: n5
+  a: n3
+`)),
+		want: `
+first: !HumanInput o0
+kind: o1
+manual: !HumanInput o2
+spec:
+  b: o5
+  a: o3
+  keep: !HumanInput o4
+`,
+		wantSelective: `
+first: !HumanInput o0
+kind: n1
+manual: !HumanInput o2
+spec:
+  b: n5
+  a: n3
+  keep: !HumanInput o4
+`,
+		wantErr: nil,
+	},
+	{
+		title: "comments of unselected nodes are kept",
+		from: []byte(strings.TrimSpace(`
+k1: # old comment
+  k11: o1
+k2: # old comment
+  - o2
+k3: {a: o3} # old comment
+`)),
+		selectiveFlag: "HumanInput",
+		into: []byte(strings.TrimSpace(`
+# head of k1
+k1: # template comment
+  k11: n1
+
+k2: # template comment
+  - n2
+k3: {a: n3} # template comment
+`)),
+		want: `
+# head of k1
+k1: # template comment
+  k11: o1
+
+k2: # template comment
+  - o2
+k3: {a: o3} # old comment
+`,
+		wantSelective: `
+# head of k1
+k1: # template comment
+  k11: n1
+
+k2: # template comment
+  - n2
+k3: {a: n3} # template comment
+`,
+		wantErr: nil,
+	},
+	{
+		title: "aliases and merge keys in from",
+		from: []byte(strings.TrimSpace(`
+defaults: &defaults
+  cpu: 1
+  mem: !HumanInput 2
+svc:
+  <<: *defaults
+  cpu: 3
+`)),
+		selectiveFlag: "HumanInput",
+		into: []byte(strings.TrimSpace(`
+svc:
+  cpu: 0
+`)),
+		want: `
+svc:
+  cpu: 3
+  mem: !HumanInput 2
+defaults:
+  cpu: 1
+  mem: !HumanInput 2
+`,
+		wantSelective: `
+svc:
+  cpu: 0
+  mem: !HumanInput 2
+defaults:
+  mem: !HumanInput 2
+`,
+		wantErr: nil,
+	},
+	{
+		title: "aliases in into",
+		from: []byte(strings.TrimSpace(`
+y:
+  b: !HumanInput 2
+`)),
+		selectiveFlag: "HumanInput",
+		into: []byte(strings.TrimSpace(`
+base: &base
+  a: 1
+x: *base
+y: *base
+`)),
+		want: `
+base: &base
+  a: 1
+x: *base
+y:
+  a: 1
+  b: !HumanInput 2
+`,
+		wantSelective: `
+base: &base
+  a: 1
+x: *base
+y:
+  a: 1
+  b: !HumanInput 2
+`,
+		wantErr: nil,
+	},
+	{
+		title: "merge into anchored node",
+		from: []byte(strings.TrimSpace(`
+a:
+  v: !HumanInput 2
+`)),
+		selectiveFlag: "HumanInput",
+		into: []byte(strings.TrimSpace(`
+a: &x
+  v: 1
+b: *x
+`)),
+		want: `
+a:
+  v: !HumanInput 2
+b:
+  v: 1
+`,
+		wantSelective: `
+a:
+  v: !HumanInput 2
+b:
+  v: 1
 `,
 		wantErr: nil,
 	},
@@ -0,0 +1,93 @@
+package yamlfile
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// Origin is a node of a merge input that set or contributed to a node of the
+// merged Yaml.
+type Origin struct {
+	// Source names the input (e.g. the path of the merged file)
+	Source string
+	// Key is the map key of Node in the input (nil for sequence elements)
+	Key *yaml.Node
+	// Node is an unmodified copy of the input node
+	Node *yaml.Node
+	// Replaced reports whether the input replaced the former value of the node
+	// (scalars, strictly merged sequences, new keys and changed types). Otherwise
+	// it is merged into the node (maps and other sequences).
+	Replaced bool
+}
+
+// Origins holds the origins of the nodes of a merged Yaml in merge order (see
+// MergeTracked).
+type Origins map[*yaml.Node][]Origin
+
+// MergeTracked merges from into the Yaml object like Merge and records source as
+// origin of all nodes that from sets or contributes to. Nothing is recorded if
+// origins is nil.
+func (y *Yaml) MergeTracked(from Yaml, source string, origins Origins) ([]Warning, error) {
+	if origins == nil {
+		return y.Merge(from)
+	}
+	return y.mergeTracked(from, "", true, &tracker{source: source, origins: origins})
+}
+
+// tracker records the origins of a single merge. Its methods are no-ops for a
+// nil tracker.
+type tracker struct {
+	source  string
+	origins Origins
+	// inputs maps the nodes of the merged input to their unmodified copies
+	inputs map[*yaml.Node]*yaml.Node
+	// keys maps the value nodes of the copies to their map keys
+	keys map[*yaml.Node]*yaml.Node
+}
+
+// init copies the input from, since its nodes become part of the merged Yaml and
+// are modified by later merges.
+func (t *tracker) init(from *yaml.Node) {
+	if t == nil {
+		return
+	}
+	c := newNodeCopier()
+	c.copy(from)
+	c.relink()
+	t.inputs = c.copies
+	t.keys = map[*yaml.Node]*yaml.Node{}
+	for _, n := range c.copies {
+		if n.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			t.keys[n.Content[i+1]] = n.Content[i]
+		}
+	}
+}
+
+// set records that the input node from set (replaced) or contributed to the
+// merged node into.
+func (t *tracker) set(into, from *yaml.Node, replaced bool) {
+	if t == nil {
+		return
+	}
+	input, ok := t.inputs[from]
+	if !ok {
+		input = from
+	}
+	t.origins[into] = append(t.origins[into], Origin{
+		Source: t.source, Key: t.keys[input], Node: input, Replaced: replaced,
+	})
+}
+
+// add records the input as origin of the node n and all its children, which have
+// been added to the merged Yaml as they are.
+func (t *tracker) add(n *yaml.Node) {
+	if t == nil {
+		return
+	}
+	t.set(n, n, true)
+	for _, c := range n.Content {
+		t.add(c)
+	}
+}
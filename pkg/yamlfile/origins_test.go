@@ -0,0 +1,103 @@
+package yamlfile_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/testfuncs"
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/yamlfile"
+	"gopkg.in/yaml.v3"
+)
+
+// trackedOrigin is the comparable part of a yamlfile.Origin.
+type trackedOrigin struct {
+	Source   string
+	Line     int
+	Value    string
+	Replaced bool
+}
+
+func TestMergeTracked(t *testing.T) {
+	inputs := []struct {
+		source  string
+		content string
+	}{
+		{"a", "map:\n  x: 1\n  y: 1\nlist: [1, 2]\nstrict: [1]\n"},
+		{"b", "map:\n  x: 2\nlist: [3]\nstrict: [2]\n"},
+		{"c", "map:\n  z: 3\nlist: [4, 5, 6]\n"},
+	}
+	merged, err := yamlfile.New([]byte{}, yamlfile.AddArrayMergeException(
+		yamlfile.ArrayMergeException{Path: "strict", Policy: yamlfile.Strict},
+	))
+	testfuncs.MustBeNil(t, err)
+	origins := yamlfile.Origins{}
+	for _, in := range inputs {
+		from, err := yamlfile.New([]byte(in.content))
+		testfuncs.MustBeNil(t, err)
+		_, err = merged.MergeTracked(from, in.source, origins)
+		testfuncs.MustBeNil(t, err)
+	}
+
+	for _, s := range []struct {
+		title string
+		path  []string
+		want  []trackedOrigin
+	}{
+		{
+			title: "overridden scalar",
+			path:  []string{"map", "x"},
+			want:  []trackedOrigin{{"a", 2, "1", true}, {"b", 2, "2", true}},
+		},
+		{
+			title: "merged map",
+			path:  []string{"map"},
+			want: []trackedOrigin{
+				{"a", 1, "", true}, {"b", 1, "", false}, {"c", 1, "", false},
+			},
+		},
+		{
+			title: "element of a merged list",
+			path:  []string{"list", "2"},
+			want:  []trackedOrigin{{"c", 3, "6", true}},
+		},
+		{
+			title: "strictly merged list",
+			path:  []string{"strict"},
+			want:  []trackedOrigin{{"a", 5, "", true}, {"b", 4, "", true}},
+		},
+		{
+			title: "element of a strictly merged list",
+			path:  []string{"strict", "0"},
+			want:  []trackedOrigin{{"b", 4, "2", true}},
+		},
+	} {
+		t.Logf("test scenario: %s\n", s.title)
+		n := merged.Node.Content[0]
+		for _, k := range s.path {
+			n = child(n, k)
+		}
+		got := []trackedOrigin{}
+		for _, o := range origins[n] {
+			line := o.Node.Line
+			if o.Key != nil {
+				line = o.Key.Line
+			}
+			got = append(got, trackedOrigin{o.Source, line, o.Node.Value, o.Replaced})
+		}
+		testfuncs.CheckEqualityInterface(t, s.want, got)
+	}
+}
+
+func child(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Kind == yaml.MappingNode && n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	for i, c := range n.Content {
+		if n.Kind == yaml.SequenceNode && strconv.Itoa(i) == key {
+			return c
+		}
+	}
+	return nil
+}
@@ -1,14 +1,30 @@
 package yamlfile
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
+)
+
+// DefaultArrayMergeKey is the map key that identifies sequence elements for the
+// ByKey array merge policy if no other key is configured.
+const DefaultArrayMergeKey = "name"
+
 type UpdateSettingsFunc func(*settings)
 
 type settings struct {
-	arrayMergePolicy ArrayMergePolicy
+	arrayMergePolicy     ArrayMergePolicy
+	arrayMergeKey        string
+	arrayMergeExceptions []ArrayMergeException
+	sortMode             SortMode
 }
 
 func newSettings(opts ...UpdateSettingsFunc) *settings {
 	s := settings{
 		arrayMergePolicy: Standard,
+		arrayMergeKey:    DefaultArrayMergeKey,
+		sortMode:         Alphabetical,
 	}
 	for i := range opts {
 		opts[i](&s)
@@ -17,8 +33,13 @@ func newSettings(opts ...UpdateSettingsFunc) *settings {
 }
 
 func (s settings) Copy() settings {
+	exceptions := make([]ArrayMergeException, len(s.arrayMergeExceptions))
+	copy(exceptions, s.arrayMergeExceptions)
 	return settings{
 		s.arrayMergePolicy,
+		s.arrayMergeKey,
+		exceptions,
+		s.sortMode,
 	}
 }
 
@@ -28,9 +49,107 @@ func SetArrayMergePolicy(policy ArrayMergePolicy) UpdateSettingsFunc {
 	}
 }
 
+// SetSortMode sets the key order that is applied by Sort. With the TemplateOrder
+// mode, keys that are added by a merge are in addition inserted at the position
+// they had in the merge source instead of being appended.
+func SetSortMode(mode SortMode) UpdateSettingsFunc {
+	return func(s *settings) {
+		s.sortMode = mode
+	}
+}
+
+// SetArrayMergeKey sets the map key that identifies sequence elements for the
+// ByKey array merge policy.
+func SetArrayMergeKey(key string) UpdateSettingsFunc {
+	return func(s *settings) {
+		s.arrayMergeKey = key
+	}
+}
+
+// AddArrayMergeException adds an exception to the array merge policy. The
+// sequences that match the path of the exception are merged with the policy (and
+// key) of the exception instead of the general policy. Later exceptions take
+// precedence over earlier ones.
+func AddArrayMergeException(e ArrayMergeException) UpdateSettingsFunc {
+	return func(s *settings) {
+		s.arrayMergeExceptions = append(s.arrayMergeExceptions, e)
+	}
+}
+
+// ArrayMergeException overwrites the array merge policy for all sequences that
+// match Path. Path is a dot-separated list of keys (sequence elements are
+// addressed by their index), where "*" matches any single key, e.g.
+// "spec.template.spec.containers" or "tenants.*.cidrs".
+type ArrayMergeException struct {
+	Path   string
+	Policy ArrayMergePolicy
+	// Key is only used for the ByKey policy. If empty, the general key is used.
+	Key string
+}
+
+func (e ArrayMergeException) matches(keys []string) bool {
+	segments := strings.Split(e.Path, ".")
+	if len(segments) != len(keys) {
+		return false
+	}
+	for i, s := range segments {
+		if s != "*" && s != keys[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// policyFor returns the array merge policy and key for the sequence located at
+// the nested keys.
+func (s settings) policyFor(keys []string) (ArrayMergePolicy, string) {
+	policy, key := s.arrayMergePolicy, s.arrayMergeKey
+	for _, e := range s.arrayMergeExceptions {
+		if !e.matches(keys) {
+			continue
+		}
+		policy = e.Policy
+		if e.Key != "" {
+			key = e.Key
+		}
+	}
+	return policy, key
+}
+
 type ArrayMergePolicy uint8
 
 const (
 	Standard ArrayMergePolicy = 1 << iota
 	Strict
+	Append
+	ByKey
 )
+
+var arrayMergePolicies = map[string]ArrayMergePolicy{
+	"standard": Standard,
+	"strict":   Strict,
+	"append":   Append,
+	"by-key":   ByKey,
+}
+
+// ParseArrayMergePolicy returns the ArrayMergePolicy for its name (standard,
+// strict, append or by-key).
+func ParseArrayMergePolicy(name string) (ArrayMergePolicy, error) {
+	p, ok := arrayMergePolicies[name]
+	if !ok {
+		return 0, fmt.Errorf(
+			"unsupported array merge policy: %q, available options: %+v",
+			name, maputils.KeysSorted(arrayMergePolicies),
+		)
+	}
+	return p, nil
+}
+
+func (p ArrayMergePolicy) String() string {
+	for name, policy := range arrayMergePolicies {
+		if policy == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("ArrayMergePolicy(%d)", uint8(p))
+}
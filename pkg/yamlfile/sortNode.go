@@ -4,32 +4,94 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/SAP/configuration-tools-for-gitops/v2/pkg/maputils"
 	"gopkg.in/yaml.v3"
 )
 
-// Sort deeply sorts the Yaml object. Sorting rules:
-//   - maps are sorted alphabetically by key
+// SortMode defines the key order that is applied by Sort.
+type SortMode uint8
+
+const (
+	// Alphabetical sorts all maps alphabetically by key.
+	Alphabetical SortMode = 1 << iota
+	// TemplateOrder keeps the key order of the Yaml object.
+	TemplateOrder
+	// Kubernetes puts well-known top-level keys of Kubernetes objects (apiVersion,
+	// kind, metadata, ...) first in their conventional order. All other keys are
+	// sorted alphabetically.
+	Kubernetes
+)
+
+var sortModes = map[string]SortMode{
+	"alphabetical": Alphabetical,
+	"template":     TemplateOrder,
+	"kubernetes":   Kubernetes,
+}
+
+// kubernetesTopLevelKeys holds the conventional order of the top-level keys of
+// Kubernetes objects.
+var kubernetesTopLevelKeys = []string{
+	"apiVersion", "kind", "metadata", "spec", "data", "stringData", "type", "status",
+}
+
+// ParseSortMode returns the SortMode for its name (alphabetical, template or
+// kubernetes).
+func ParseSortMode(name string) (SortMode, error) {
+	m, ok := sortModes[name]
+	if !ok {
+		return 0, fmt.Errorf(
+			"unsupported sort mode: %q, available options: %+v",
+			name, maputils.KeysSorted(sortModes),
+		)
+	}
+	return m, nil
+}
+
+// Sort deeply sorts the Yaml object according to the sort mode of its settings
+// (see SetSortMode). Sorting rules:
+//   - Alphabetical (default): maps are sorted alphabetically by key
+//   - TemplateOrder: maps keep their order
+//   - Kubernetes: well-known top-level keys first, all other keys alphabetically
 //   - arrays are not sorted
+//
+// Anchors that end up behind their aliases swap places with the first alias.
 func (y *Yaml) Sort() {
-	sorter{}.sort(y.Node)
+	mode := Alphabetical
+	if y.settings != nil {
+		mode = y.settings.sortMode
+	}
+	switch mode {
+	case TemplateOrder:
+		return
+	case Kubernetes:
+		sorter{topLevelKeys: kubernetesTopLevelKeys}.sort(y.Node, 0)
+	default:
+		sorter{}.sort(y.Node, 0)
+	}
+	repairAliases(y.Node)
 }
 
-type sorter struct{}
+// sorter sorts maps alphabetically. Keys in topLevelKeys are put first (in the
+// given order) in maps at the top level of the Yaml object.
+type sorter struct {
+	topLevelKeys []string
+}
 
 // sort sends a sorting request for n to the specific node-type implementation.
-func (s sorter) sort(n *yaml.Node) {
+// The depth counts the map levels above n.
+func (s sorter) sort(n *yaml.Node, depth int) {
 	switch n.Kind {
 	case yaml.ScalarNode:
 		return
 	case yaml.MappingNode:
-		s.sortMaps(n)
+		s.sortMaps(n, depth)
 	case yaml.SequenceNode:
 		for _, c := range n.Content {
-			s.sort(c)
+			s.sort(c, depth)
 		}
 	case yaml.DocumentNode:
 		for _, c := range n.Content {
-			s.sort(c)
+			s.sort(c, depth)
 		}
 	default:
 		return
@@ -38,23 +100,31 @@ func (s sorter) sort(n *yaml.Node) {
 
 // sortMaps first sorts the n.Content slice of the node (which is known to be of
 // map-type). Then the sort method is invoced for every value of the map.
-func (s sorter) sortMaps(n *yaml.Node) {
+func (s sorter) sortMaps(n *yaml.Node, depth int) {
 	if len(n.Content)%2 != 0 {
 		panic(fmt.Errorf(
 			"illegal content length %v found: content slice must be even for maps",
 			len(n.Content),
 		))
 	}
-	sortContent(n)
+	var first []string
+	if depth == 0 {
+		first = s.topLevelKeys
+	}
+	sortContent(n, first)
 	for i, c := range n.Content {
 		if i%2 == 0 {
 			continue
 		}
-		s.sort(c)
+		s.sort(c, depth+1)
 	}
 }
 
-func sortContent(n *yaml.Node) {
+func sortContent(n *yaml.Node, first []string) {
+	rank := make(map[string]int, len(first))
+	for i, k := range first {
+		rank[k] = i
+	}
 	mapKeys := make([]string, 0, len(n.Content)/2)
 	keyIndex := make(map[string]int, len(n.Content)/2)
 	for i := 0; i < len(n.Content); i += 2 {
@@ -62,7 +132,18 @@ func sortContent(n *yaml.Node) {
 		mapKeys = append(mapKeys, k)
 		keyIndex[k] = i
 	}
-	sort.Strings(mapKeys)
+	sort.Slice(mapKeys, func(i, j int) bool {
+		ri, iFirst := rank[mapKeys[i]]
+		rj, jFirst := rank[mapKeys[j]]
+		switch {
+		case iFirst && jFirst:
+			return ri < rj
+		case iFirst != jFirst:
+			return iFirst
+		default:
+			return mapKeys[i] < mapKeys[j]
+		}
+	})
 	sortedContent := make([]*yaml.Node, len(n.Content))
 	for i, k := range mapKeys {
 		sortedContent[2*i] = n.Content[keyIndex[k]]
@@ -54,6 +54,65 @@ z: v1
 - a:
     a: v4
     z: v3
+`,
+	},
+	{
+		title:    "template order",
+		settings: []yamlfile.UpdateSettingsFunc{yamlfile.SetSortMode(yamlfile.TemplateOrder)},
+		input: []byte(strings.TrimSpace(`
+z: v1
+a:
+  y: v2
+  b: v3
+`)),
+		want: `
+z: v1
+a:
+  y: v2
+  b: v3
+`,
+	},
+	{
+		title:    "kubernetes order",
+		settings: []yamlfile.UpdateSettingsFunc{yamlfile.SetSortMode(yamlfile.Kubernetes)},
+		input: []byte(strings.TrimSpace(`
+spec:
+  selector: s
+  replicas: 1
+data: d
+metadata:
+  name: n
+  labels: l
+kind: Deployment
+apiVersion: apps/v1
+additional: a
+`)),
+		want: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels: l
+  name: n
+spec:
+  replicas: 1
+  selector: s
+data: d
+additional: a
+`,
+	},
+	{
+		title: "anchor behind its alias after sorting",
+		input: []byte(`
+b: &x
+  k: v
+a: *x
+c: *x
+`),
+		want: `
+a: &x
+  k: v
+b: *x
+c: *x
 `,
 	},
 }
@@ -62,7 +121,7 @@ func TestSort(t *testing.T) {
 	for _, s := range scenariosSort {
 		t.Logf("test scenario: %s\n", s.title)
 
-		y, err := yamlfile.New(s.input)
+		y, err := yamlfile.New(s.input, s.settings...)
 		testfuncs.CheckErrs(t, nil, err)
 
 		y.Sort()
@@ -78,9 +137,10 @@ func TestSort(t *testing.T) {
 }
 
 type scenarioSort struct {
-	title string
-	input []byte
-	want  string
+	title    string
+	settings []yamlfile.UpdateSettingsFunc
+	input    []byte
+	want     string
 }
 
 func (s scenarioSort) CheckRes(t *testing.T, got string) bool {
@@ -96,3 +156,20 @@ func (s scenarioSort) CheckRes(t *testing.T, got string) bool {
 	}
 	return true
 }
+
+func TestParseSortMode(t *testing.T) {
+	for name, want := range map[string]yamlfile.SortMode{
+		"alphabetical": yamlfile.Alphabetical,
+		"template":     yamlfile.TemplateOrder,
+		"kubernetes":   yamlfile.Kubernetes,
+	} {
+		got, err := yamlfile.ParseSortMode(name)
+		testfuncs.CheckErrs(t, nil, err)
+		testfuncs.CheckEqualityInterface(t, want, got)
+	}
+	_, err := yamlfile.ParseSortMode("random")
+	testfuncs.CheckErrs(t, fmt.Errorf(
+		"unsupported sort mode: %q, available options: %+v",
+		"random", []string{"alphabetical", "kubernetes", "template"},
+	), err)
+}
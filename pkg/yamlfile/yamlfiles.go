@@ -1,8 +1,10 @@
 package yamlfile
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -20,11 +22,13 @@ type Yaml struct {
 }
 
 // New unmarshalls a yaml input into a yaml.Node representation and returns a Yaml type.
+// Blank lines between map entries or sequence elements are kept (see Encode).
 func New(input []byte, opts ...UpdateSettingsFunc) (Yaml, error) {
 	var node yaml.Node
 	if err := yaml.Unmarshal(input, &node); err != nil {
 		return Yaml{}, fmt.Errorf("unmarshalling failed %s", err)
 	}
+	markBlankLines(&node, strings.Split(string(input), "\n"))
 	return Yaml{&node, newSettings(opts...)}, nil
 }
 
@@ -39,24 +43,20 @@ func NewFromInterface(i interface{}, opts ...UpdateSettingsFunc) (Yaml, error) {
 // PartialCopy creates a copy of the input n but with only the subslice of the
 // content n.Content[start:end]
 func PartialCopy(n Yaml, start, end int) Yaml {
+	c := newNodeCopier()
 	newContent := make([]*yaml.Node, 0, len(n.Node.Content))
 	for i, el := range n.Node.Content {
 		if i >= start && i < end {
-			newContent = append(newContent, deepCopy(el))
+			newContent = append(newContent, c.copy(el))
 		}
 	}
-	var newAlias *yaml.Node
-	if n.Node.Alias != nil {
-		newAlias = &yaml.Node{}
-		*newAlias = *n.Node.Alias
-	}
 	newNode := yaml.Node{
 		Kind:        n.Node.Kind,
 		Style:       n.Node.Style,
 		Tag:         n.Node.Tag,
 		Value:       n.Node.Value,
 		Anchor:      n.Node.Anchor,
-		Alias:       newAlias,
+		Alias:       n.Node.Alias,
 		Content:     newContent,
 		HeadComment: n.Node.HeadComment,
 		LineComment: n.Node.LineComment,
@@ -64,30 +64,49 @@ func PartialCopy(n Yaml, start, end int) Yaml {
 		Line:        n.Node.Line,
 		Column:      n.Node.Column,
 	}
+	c.relink()
 	s := n.settings.Copy()
 	return Yaml{&newNode, &s}
 }
 
-// Copy creates a deep copy of the Yaml object.
+// Copy creates a deep copy of the Yaml object. Anchors and aliases are preserved.
 func (y Yaml) Copy() Yaml {
-	newSettings := settings{
-		arrayMergePolicy: y.settings.arrayMergePolicy,
-	}
+	newSettings := y.settings.Copy()
 	return Yaml{deepCopy(y.Node), &newSettings}
 }
 
+// deepCopy copies n and all its children. Aliases in the copy refer to the copied
+// anchors (or to the original anchors if they are not part of n).
 func deepCopy(n *yaml.Node) *yaml.Node {
+	c := newNodeCopier()
+	res := c.copy(n)
+	c.relink()
+	return res
+}
+
+// nodeCopier keeps track of the copied nodes so that aliases can be pointed to
+// the copies of their anchors.
+type nodeCopier struct {
+	copies  map[*yaml.Node]*yaml.Node
+	aliases []*yaml.Node
+}
+
+func newNodeCopier() nodeCopier {
+	return nodeCopier{copies: map[*yaml.Node]*yaml.Node{}, aliases: []*yaml.Node{}}
+}
+
+func (c *nodeCopier) copy(n *yaml.Node) *yaml.Node {
 	newContent := make([]*yaml.Node, 0, len(n.Content))
 	for _, el := range n.Content {
-		newContent = append(newContent, deepCopy(el))
+		newContent = append(newContent, c.copy(el))
 	}
-	return &yaml.Node{
+	res := &yaml.Node{
 		Kind:        n.Kind,
 		Style:       n.Style,
 		Tag:         n.Tag,
 		Value:       n.Value,
 		Anchor:      n.Anchor,
-		Alias:       nil,
+		Alias:       n.Alias,
 		Content:     newContent,
 		HeadComment: n.HeadComment,
 		LineComment: n.LineComment,
@@ -95,6 +114,19 @@ func deepCopy(n *yaml.Node) *yaml.Node {
 		Line:        n.Line,
 		Column:      n.Column,
 	}
+	c.copies[n] = res
+	if n.Alias != nil {
+		c.aliases = append(c.aliases, res)
+	}
+	return res
+}
+
+func (c *nodeCopier) relink() {
+	for _, a := range c.aliases {
+		if target, ok := c.copies[a.Alias]; ok {
+			a.Alias = target
+		}
+	}
 }
 
 // Decode unmarshals the Yaml into a provided interface v.
@@ -104,6 +136,7 @@ func (y *Yaml) Decode(v interface{}) error {
 
 // Encode marshalls a Yaml into the provided Writer w. The number of space
 // indentations in the output can be controlled via the indent parameter.
+// Comments as well as the blank lines of the original input are preserved.
 func (y *Yaml) Encode(w io.Writer, indent int) error {
 	if y.Node.Kind == 0 {
 		return nil
@@ -111,10 +144,17 @@ func (y *Yaml) Encode(w io.Writer, indent int) error {
 	if y.Node.Kind == yaml.DocumentNode && len(y.Node.Content) == 0 {
 		return nil
 	}
-	e := yaml.NewEncoder(w)
-	defer e.Close()
+	var b bytes.Buffer
+	e := yaml.NewEncoder(&b)
 	e.SetIndent(indent)
-	return e.Encode(y.Node)
+	if err := e.Encode(y.Node); err != nil {
+		return err
+	}
+	if err := e.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write(trimBlankLines(b.Bytes()))
+	return err
 }
 
 // FilterBy puts a positive filter on the Node in Yaml. Only elements
@@ -145,6 +185,7 @@ func (y *Yaml) FilterBy(filter string) error {
 	if remove {
 		y.Node = &yaml.Node{}
 	}
+	repairAliases(y.Node)
 	return err
 }
 
@@ -179,6 +220,7 @@ func (y *Yaml) FilterByKeys(keys []string) error {
 	if remove {
 		y.Node = &yaml.Node{}
 	}
+	repairAliases(y.Node)
 	return err
 }
 
@@ -237,8 +279,10 @@ func (s sieve) selected(n *yaml.Node) bool {
 	return false
 }
 
+// aliasNode filters aliases like scalars. Aliases whose anchor is removed are
+// materialized after the filtering (see repairAliases).
 func (s sieve) aliasNode(n *yaml.Node, parentSelected bool) (removed bool, err error) {
-	return false, fmt.Errorf("unmarshal yaml.AliasNode not implemented")
+	return s.scalarNode(n, parentSelected)
 }
 
 // mapNode implements the filtering logic for maps. The node method is called
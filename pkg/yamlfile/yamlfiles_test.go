@@ -205,15 +205,33 @@ nestedArray:
 		wantErr:              fmt.Errorf("unmarshalling failed %s", "yaml: line 1: did not find expected node content"),
 	},
 	{
-		title: "filtering fails",
+		title: "filter aliases",
 		input: []byte(strings.TrimSpace(`
 hello: &hello 'hello'
 greeting:
-  hello: *hello  #greeting.hello has the string value of 'hello'
+  hello: *hello # human overwrite
+  other: *hello
 `)),
 		filterByTagOrComment: "human overwrite",
-		want:                 "",
-		wantErr:              fmt.Errorf("unmarshal yaml.AliasNode not implemented"),
+		want: `greeting:
+  hello: 'hello' # human overwrite
+`,
+		wantErr: nil,
+	},
+	{
+		title: "filter aliases with kept anchor",
+		input: []byte(strings.TrimSpace(`
+hello: &hello 'hello' # human overwrite
+greeting:
+  hello: *hello # human overwrite
+  other: *hello
+`)),
+		filterByTagOrComment: "human overwrite",
+		want: `hello: &hello 'hello' # human overwrite
+greeting:
+  hello: *hello # human overwrite
+`,
+		wantErr: nil,
 	},
 	{
 		title: "filter by keys",
@@ -299,6 +317,28 @@ func TestReadPersistentParts(t *testing.T) {
 	}
 }
 
+func TestCopyKeepsAliases(t *testing.T) {
+	input := strings.TrimSpace(`
+base: &base
+  a: 1
+x: *base
+`) + "\n"
+	y, err := yamlfile.New([]byte(input))
+	testfuncs.CheckErrs(t, nil, err)
+
+	c := y.Copy()
+	content := c.Node.Content[0].Content
+	if content[3].Alias != content[1] {
+		t.Errorf("alias of the copy does not refer to the copied anchor")
+	}
+	y.Node.Content[0].Content[1].Content[1].Value = "changed"
+
+	var gotBytes bytes.Buffer
+	err = c.Encode(&gotBytes, 2)
+	testfuncs.CheckErrs(t, nil, err)
+	testfuncs.CheckEqualityInterface(t, input, gotBytes.String())
+}
+
 type scenarioPersistence struct {
 	title                string
 	input                []byte